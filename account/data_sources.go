@@ -5,14 +5,23 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
+	"github.com/FuZhouJohn/memrizr/account/mailer"
 	"github.com/go-redis/redis/v8"
 	"github.com/jmoiron/sqlx"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
 )
 
 type dataSources struct {
 	DB          *sqlx.DB
 	RedisClient *redis.Client
+	// StorageClient 仅在 IMAGE_STORAGE=s3 时创建，否则图片保存在本地目录
+	StorageClient *minio.Client
+	// MailSender 在后台投递邮件，关闭时会等待队列中的邮件发送完成
+	MailSender *mailer.AsyncSender
 }
 
 // InitDS establishes connections to fields in dataSources
@@ -56,14 +65,129 @@ func initDS() (*dataSources, error) {
 	}
 	log.Printf("连接成功...\n")
 
+	var storage *minio.Client
+	if os.Getenv("IMAGE_STORAGE") == "s3" {
+		storage, err = initStorage()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	mailSender, err := initMailSender()
+	if err != nil {
+		return nil, err
+	}
+
 	return &dataSources{
-		DB:          db,
-		RedisClient: rdb,
+		DB:            db,
+		RedisClient:   rdb,
+		StorageClient: storage,
+		MailSender:    mailSender,
 	}, nil
 }
 
+// initMailSender 按 MAIL_TRANSPORT 选择邮件的投递方式：smtp 发送至 SMTP 服务器（本地可使用 MailHog），
+// file 将邮件写入 MAIL_DIR，默认的 log 只在日志中记录收件人与主题，不记录包含令牌的正文
+func initMailSender() (*mailer.AsyncSender, error) {
+	var sender mailer.Sender
+
+	transport := os.Getenv("MAIL_TRANSPORT")
+	switch transport {
+	case "smtp":
+		smtpHost := os.Getenv("SMTP_HOST")
+		smtpPort := os.Getenv("SMTP_PORT")
+		if smtpHost == "" || smtpPort == "" {
+			return nil, fmt.Errorf("必须设置 SMTP_HOST 与 SMTP_PORT")
+		}
+
+		log.Printf("邮件将发送至 SMTP 服务器 %v:%v\n", smtpHost, smtpPort)
+		sender = mailer.NewSMTPSender(&mailer.SMTPConfig{
+			Host:     smtpHost,
+			Port:     smtpPort,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			Timeout:  10 * time.Second,
+		})
+	case "file":
+		mailDir := os.Getenv("MAIL_DIR")
+		if mailDir == "" {
+			return nil, fmt.Errorf("必须设置 MAIL_DIR")
+		}
+
+		if err := os.MkdirAll(mailDir, 0755); err != nil {
+			return nil, fmt.Errorf("无法创建邮件目录 %v：%w", mailDir, err)
+		}
+
+		log.Printf("邮件将写入目录 %v\n", mailDir)
+		sender = mailer.NewFileSender(mailDir)
+	case "", "log":
+		log.Printf("邮件不会投递，日志中只记录收件人与主题，需要查看邮件内容时请使用 smtp 或 file\n")
+		sender = mailer.NewLogSender()
+	default:
+		return nil, fmt.Errorf("无效的 MAIL_TRANSPORT：%v，可选值为 smtp、file 与 log", transport)
+	}
+
+	return mailer.NewAsyncSender(&mailer.AsyncConfig{
+		Sender:    sender,
+		Workers:   2,
+		QueueSize: 100,
+		Timeout:   30 * time.Second,
+	}), nil
+}
+
+// initStorage 连接 S3 兼容的对象存储，存储桶不存在时自动创建（便于使用本地 MinIO 开发与测试）
+func initStorage() (*minio.Client, error) {
+	endpoint := os.Getenv("S3_ENDPOINT")
+	accessKeyID := os.Getenv("S3_ACCESS_KEY_ID")
+	secretAccessKey := os.Getenv("S3_SECRET_ACCESS_KEY")
+	region := os.Getenv("S3_REGION")
+	bucketName := os.Getenv("S3_BUCKET")
+
+	useSSL, err := strconv.ParseBool(os.Getenv("S3_USE_SSL"))
+	if err != nil {
+		return nil, fmt.Errorf("无法将 S3_USE_SSL 转换为布尔值：%w", err)
+	}
+
+	log.Printf("开始连接对象存储 %v\n", endpoint)
+	storage, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKeyID, secretAccessKey, ""),
+		Secure: useSSL,
+		Region: region,
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("创建对象存储客户端失败：%w", err)
+	}
+
+	ctx := context.Background()
+	exists, err := storage.BucketExists(ctx, bucketName)
+	if err != nil {
+		return nil, fmt.Errorf("连接对象存储失败：%w", err)
+	}
+
+	if !exists {
+		log.Printf("存储桶 %v 不存在，开始创建\n", bucketName)
+		if err := storage.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{Region: region}); err != nil {
+			return nil, fmt.Errorf("创建存储桶 %v 失败：%w", bucketName, err)
+		}
+
+		// 头像需要能被匿名读取
+		policy := fmt.Sprintf(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":["*"]},"Action":["s3:GetObject"],"Resource":["arn:aws:s3:::%s/*"]}]}`, bucketName)
+		if err := storage.SetBucketPolicy(ctx, bucketName, policy); err != nil {
+			return nil, fmt.Errorf("设置存储桶 %v 的访问策略失败：%w", bucketName, err)
+		}
+	}
+	log.Printf("连接成功...\n")
+
+	return storage, nil
+}
+
 // close to be used in graceful server shutdown
 func (d *dataSources) close() error {
+	// 先发送完队列中的邮件
+	if err := d.MailSender.Close(); err != nil {
+		return fmt.Errorf("关闭邮件队列失败： %w", err)
+	}
 	if err := d.DB.Close(); err != nil {
 		return fmt.Errorf("关闭 Postgresql 失败： %w", err)
 	}
@@ -0,0 +1,71 @@
+// Package geoip 使用本地的 MaxMind 格式数据库（如 GeoLite2-City.mmdb）查询 IP 所在的位置，不依赖外部服务
+package geoip
+
+import (
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// cityRecord 为 City 与 Country 数据库中用到的字段，Country 数据库没有 city
+type cityRecord struct {
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+}
+
+// Locator 实现 model.GeoLocator，地名使用英文
+type Locator struct {
+	reader *maxminddb.Reader
+}
+
+// Open 打开 MaxMind 格式的数据库文件，不再使用时需要调用 Close
+func Open(path string) (*Locator, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开地理位置数据库 %v：%w", path, err)
+	}
+
+	return &Locator{reader: reader}, nil
+}
+
+// Locate 返回 IP 所在的城市与国家，如 Shanghai, China；只能确定国家时只返回国家，
+// 无效的 IP、保留地址或数据库中没有的 IP 返回空字符串
+func (l *Locator) Locate(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ""
+	}
+
+	var record cityRecord
+	if err := l.reader.Lookup(addr, &record); err != nil {
+		log.Printf("无法查询 IP %v 的地理位置：%v\n", ip, err)
+		return ""
+	}
+
+	country := record.Country.Names["en"]
+	if country == "" {
+		country = record.Country.ISOCode
+	}
+
+	city := record.City.Names["en"]
+
+	switch {
+	case city != "" && country != "":
+		return city + ", " + country
+	case country != "":
+		return country
+	default:
+		return city
+	}
+}
+
+func (l *Locator) Close() error {
+	return l.reader.Close()
+}
@@ -0,0 +1,178 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 以下按 MaxMind DB 格式（https://maxmind.github.io/MaxMind-DB/）生成只含 IPv4 网段的测试数据库
+
+func encodeString(s string) []byte {
+	return append([]byte{2<<5 | byte(len(s))}, s...)
+}
+
+func encodeUint16(v uint16) []byte {
+	b := []byte{5<<5 | 2, 0, 0}
+	binary.BigEndian.PutUint16(b[1:], v)
+	return b
+}
+
+func encodeUint32(v uint32) []byte {
+	b := []byte{6<<5 | 4, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(b[1:], v)
+	return b
+}
+
+func encodeMap(pairs ...[]byte) []byte {
+	b := []byte{7<<5 | byte(len(pairs)/2)}
+	for _, p := range pairs {
+		b = append(b, p...)
+	}
+	return b
+}
+
+// encodeArray 使用扩展类型，控制字节的类型为 0，下一个字节为 11 - 7
+func encodeArray(items ...[]byte) []byte {
+	b := []byte{byte(len(items)), 4}
+	for _, item := range items {
+		b = append(b, item...)
+	}
+	return b
+}
+
+type trieNode struct {
+	children [2]*trieNode
+	data     [2][]byte
+}
+
+// writeTestDatabase 生成记录长度为 24 位的 IPv4 数据库，records 的键为 CIDR 网段
+func writeTestDatabase(t *testing.T, records map[string][]byte) string {
+	root := &trieNode{}
+	for cidr, data := range records {
+		_, network, err := net.ParseCIDR(cidr)
+		assert.NoError(t, err)
+
+		ones, _ := network.Mask.Size()
+		ip := network.IP.To4()
+		node := root
+		for i := 0; i < ones; i++ {
+			bit := (ip[i/8] >> (7 - i%8)) & 1
+			if i == ones-1 {
+				node.data[bit] = data
+				break
+			}
+			if node.children[bit] == nil {
+				node.children[bit] = &trieNode{}
+			}
+			node = node.children[bit]
+		}
+	}
+
+	var nodes []*trieNode
+	var number func(n *trieNode)
+	number = func(n *trieNode) {
+		nodes = append(nodes, n)
+		for _, c := range n.children {
+			if c != nil {
+				number(c)
+			}
+		}
+	}
+	number(root)
+
+	index := make(map[*trieNode]int)
+	for i, n := range nodes {
+		index[n] = i
+	}
+
+	nodeCount := len(nodes)
+	var tree, dataSection bytes.Buffer
+	for _, n := range nodes {
+		for bit := 0; bit < 2; bit++ {
+			record := nodeCount
+			switch {
+			case n.children[bit] != nil:
+				record = index[n.children[bit]]
+			case n.data[bit] != nil:
+				record = nodeCount + 16 + dataSection.Len()
+				dataSection.Write(n.data[bit])
+			}
+			tree.Write([]byte{byte(record >> 16), byte(record >> 8), byte(record)})
+		}
+	}
+
+	var db bytes.Buffer
+	db.Write(tree.Bytes())
+	db.Write(make([]byte, 16))
+	db.Write(dataSection.Bytes())
+	db.WriteString("\xab\xcd\xefMaxMind.com")
+	db.Write(encodeMap(
+		encodeString("node_count"), encodeUint32(uint32(nodeCount)),
+		encodeString("record_size"), encodeUint16(24),
+		encodeString("ip_version"), encodeUint16(4),
+		encodeString("database_type"), encodeString("Test-City"),
+		encodeString("languages"), encodeArray(encodeString("en")),
+		encodeString("binary_format_major_version"), encodeUint16(2),
+		encodeString("binary_format_minor_version"), encodeUint16(0),
+	))
+
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	assert.NoError(t, ioutil.WriteFile(path, db.Bytes(), 0644))
+
+	return path
+}
+
+func cityData(city string, isoCode string, country string) []byte {
+	fields := [][]byte{
+		encodeString("country"), encodeMap(
+			encodeString("iso_code"), encodeString(isoCode),
+			encodeString("names"), encodeMap(encodeString("en"), encodeString(country)),
+		),
+	}
+	if city != "" {
+		fields = append(fields,
+			encodeString("city"), encodeMap(
+				encodeString("names"), encodeMap(encodeString("en"), encodeString(city)),
+			),
+		)
+	}
+
+	return encodeMap(fields...)
+}
+
+func TestLocator(t *testing.T) {
+	path := writeTestDatabase(t, map[string][]byte{
+		"203.0.113.0/24":  cityData("Shanghai", "CN", "China"),
+		"198.51.100.0/25": cityData("", "US", "United States"),
+	})
+
+	l, err := Open(path)
+	assert.NoError(t, err)
+	defer l.Close()
+
+	assert.Equal(t, "Shanghai, China", l.Locate("203.0.113.7"))
+	assert.Equal(t, "United States", l.Locate("198.51.100.1"))
+	// 同一个 /24 中不在 /25 内的地址
+	assert.Equal(t, "", l.Locate("198.51.100.200"))
+	assert.Equal(t, "", l.Locate("192.0.2.1"))
+	assert.Equal(t, "", l.Locate("not an ip"))
+	// IPv4 数据库中无法查询 IPv6 地址
+	assert.Equal(t, "", l.Locate("2001:db8::1"))
+}
+
+func TestOpenInvalidDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invalid.mmdb")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("not a database"), 0644))
+
+	_, err := Open(path)
+	assert.Error(t, err)
+
+	_, err = Open(filepath.Join(t.TempDir(), "missing.mmdb"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+// Activity 按时间倒序列出当前用户的认证事件。?limit= 为每页条数，
+// ?before= 为上一页最后一条事件的 id，用于获取更早的事件
+func (h *Handler) Activity(c *gin.Context) {
+	user, exists := c.Get("user")
+
+	if !exists {
+		log.Printf("由于未知原因，无法从请求环境中提取用户：%v\n", c)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	uid := user.(*model.User).UID
+
+	var beforeID int64
+	if v := c.Query("before"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			err := apperrors.NewBadRequest("before 必须为正整数")
+			c.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			return
+		}
+		beforeID = id
+	}
+
+	var limit int
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			err := apperrors.NewBadRequest("limit 必须为正整数")
+			c.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			return
+		}
+		limit = n
+	}
+
+	ctx := c.Request.Context()
+	events, err := h.AuditService.ListActivity(ctx, uid, beforeID, limit)
+
+	if err != nil {
+		log.Printf("无法获取用户 %v 的认证事件：%v\n", uid, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+	})
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestActivity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	newRouter := func(mockAuditService *mocks.MockAuditService) *gin.Engine {
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &model.User{
+				UID: uid,
+			})
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			AuditService: mockAuditService,
+		})
+
+		return router
+	}
+
+	t.Run("成功", func(t *testing.T) {
+		mockEvents := []*model.AuthEvent{
+			{
+				ID:        42,
+				UID:       uid,
+				Type:      model.AuthEventSigninSuccess,
+				Detail:    "password",
+				Email:     "hello@world.com",
+				IP:        "127.0.0.1",
+				UserAgent: "Mozilla/5.0",
+				CreatedAt: time.Unix(1626000000, 0),
+			},
+		}
+
+		mockAuditService := new(mocks.MockAuditService)
+		mockAuditService.On("ListActivity", mock.AnythingOfType("*context.emptyCtx"), uid, int64(43), 10).Return(mockEvents, nil)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/me/activity?before=43&limit=10", http.NoBody)
+
+		newRouter(mockAuditService).ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"events": mockEvents,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		// 不返回 uid 与邮箱
+		assert.NotContains(t, rr.Body.String(), "hello@world.com")
+		mockAuditService.AssertExpectations(t)
+	})
+
+	t.Run("未指定分页参数", func(t *testing.T) {
+		mockAuditService := new(mocks.MockAuditService)
+		mockAuditService.On("ListActivity", mock.AnythingOfType("*context.emptyCtx"), uid, int64(0), 0).Return([]*model.AuthEvent{}, nil)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/me/activity", http.NoBody)
+
+		newRouter(mockAuditService).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"events":[]}`, rr.Body.String())
+		mockAuditService.AssertExpectations(t)
+	})
+
+	t.Run("无效的分页参数", func(t *testing.T) {
+		for _, query := range []string{"before=abc", "before=-1", "limit=0", "limit=ten"} {
+			mockAuditService := new(mocks.MockAuditService)
+
+			rr := httptest.NewRecorder()
+			request, _ := http.NewRequest(http.MethodGet, "/me/activity?"+query, http.NoBody)
+
+			newRouter(mockAuditService).ServeHTTP(rr, request)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+			mockAuditService.AssertNotCalled(t, "ListActivity", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("AuditService 出错", func(t *testing.T) {
+		mockErr := apperrors.NewInternal()
+
+		mockAuditService := new(mocks.MockAuditService)
+		mockAuditService.On("ListActivity", mock.AnythingOfType("*context.emptyCtx"), uid, int64(0), 0).Return(nil, mockErr)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/me/activity", http.NoBody)
+
+		newRouter(mockAuditService).ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockErr,
+		})
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("上下文中没有 User", func(t *testing.T) {
+		mockAuditService := new(mocks.MockAuditService)
+
+		rr := httptest.NewRecorder()
+
+		router := gin.Default()
+		NewHandler(&Config{
+			R:            router,
+			AuditService: mockAuditService,
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/me/activity", http.NoBody)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockAuditService.AssertNotCalled(t, "ListActivity", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ClearLockout 由管理员解除某个用户的账号锁定
+func (h *Handler) ClearLockout(c *gin.Context) {
+	uid, err := uuid.Parse(c.Param("uid"))
+	if err != nil {
+		err := apperrors.NewBadRequest("无效的 uid")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if err := h.UserService.ClearLockout(ctx, uid); err != nil {
+		log.Printf("无法解除用户 %v 的账号锁定：%v\n", uid, err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "账号已解除锁定",
+	})
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestClearLockout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockUserService := new(mocks.MockUserService)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:           router,
+		UserService: mockUserService,
+		AdminToken:  "anAdminToken",
+	})
+
+	uid, _ := uuid.NewRandom()
+
+	t.Run("成功", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockUserService.
+			On("ClearLockout", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(nil)
+
+		request, _ := http.NewRequest(http.MethodDelete, "/admin/users/"+uid.String()+"/lockout", nil)
+		request.Header.Set("Authorization", "Bearer anAdminToken")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserService.AssertCalled(t, "ClearLockout", mock.AnythingOfType("*context.emptyCtx"), uid)
+	})
+
+	t.Run("管理令牌错误", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		request, _ := http.NewRequest(http.MethodDelete, "/admin/users/"+uid.String()+"/lockout", nil)
+		request.Header.Set("Authorization", "Bearer aWrongToken")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockUserService.AssertNumberOfCalls(t, "ClearLockout", 1)
+	})
+
+	t.Run("无效的 uid", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		request, _ := http.NewRequest(http.MethodDelete, "/admin/users/notauuid/lockout", nil)
+		request.Header.Set("Authorization", "Bearer anAdminToken")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNumberOfCalls(t, "ClearLockout", 1)
+	})
+
+	t.Run("用户不存在", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		missingUID, _ := uuid.NewRandom()
+		mockUserService.
+			On("ClearLockout", mock.AnythingOfType("*context.emptyCtx"), missingUID).
+			Return(apperrors.NewNotFound("uid", missingUID.String()))
+
+		request, _ := http.NewRequest(http.MethodDelete, "/admin/users/"+missingUID.String()+"/lockout", nil)
+		request.Header.Set("Authorization", "Bearer anAdminToken")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("未设置管理令牌时不注册管理接口", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		router := gin.Default()
+		NewHandler(&Config{
+			R:           router,
+			UserService: mockUserService,
+		})
+
+		request, _ := http.NewRequest(http.MethodDelete, "/admin/users/"+uid.String()+"/lockout", nil)
+		request.Header.Set("Authorization", "Bearer ")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"github.com/FuZhouJohn/memrizr/account/handler/middleware"
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/gin-gonic/gin"
+)
+
+// clientInfo 返回发起请求的客户端信息，用于记录会话所在的设备。优先使用 ClientInfo 中间件保存的信息，
+// 与认证事件中记录的一致；测试模式下未注册该中间件时从请求中提取
+func clientInfo(c *gin.Context) *model.ClientInfo {
+	if client := model.ClientInfoFromContext(c.Request.Context()); client != nil {
+		return client
+	}
+
+	return middleware.NewClientInfo(c)
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FuZhouJohn/memrizr/account/handler/middleware"
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientInfo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRequest := func() *http.Request {
+		request, _ := http.NewRequest(http.MethodPost, "/signin", http.NoBody)
+		request.Header.Set("User-Agent", "Mozilla/5.0")
+		request.RemoteAddr = "203.0.113.7:54321"
+		return request
+	}
+
+	t.Run("使用中间件保存的客户端信息", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(rr)
+
+		var fromMiddleware, client *model.ClientInfo
+
+		r.POST("/signin", middleware.ClientInfo(), func(c *gin.Context) {
+			fromMiddleware = model.ClientInfoFromContext(c.Request.Context())
+			client = clientInfo(c)
+		})
+
+		r.ServeHTTP(rr, newRequest())
+
+		assert.Same(t, fromMiddleware, client)
+	})
+
+	t.Run("未注册中间件时从请求中提取", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(rr)
+
+		var client *model.ClientInfo
+
+		r.POST("/signin", func(c *gin.Context) {
+			client = clientInfo(c)
+		})
+
+		r.ServeHTTP(rr, newRequest())
+
+		assert.Equal(t, &model.ClientInfo{
+			UserAgent: "Mozilla/5.0",
+			IP:        "203.0.113.7",
+		}, client)
+	})
+}
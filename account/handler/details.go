@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+type detailsReq struct {
+	Name    string `json:"name" binding:"omitempty,max=50"`
+	Email   string `json:"email" binding:"required,email"`
+	Website string `json:"website" binding:"omitempty,url"`
+}
+
+// Details 更新当前用户的资料，使用个人访问令牌时不能修改邮箱
+func (h *Handler) Details(c *gin.Context) {
+	authUser, exists := c.Get("user")
+
+	if !exists {
+		log.Printf("由于未知原因，无法从请求环境中提取用户：%v\n", c)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	var req detailsReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	// 修改邮箱后可以通过重置密码接管账户，个人访问令牌只能修改其他资料
+	if _, ok := c.Get("personalAccessToken"); ok && req.Email != authUser.(*model.User).Email {
+		err := apperrors.NewForbidden("个人访问令牌不能修改邮箱")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	u := &model.User{
+		UID:     authUser.(*model.User).UID,
+		Name:    req.Name,
+		Email:   req.Email,
+		Website: req.Website,
+	}
+
+	ctx := c.Request.Context()
+	err := h.UserService.UpdateDetails(ctx, u)
+
+	if err != nil {
+		log.Printf("更新用户详情失败：%v\n", err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	h.respondWithUser(c, u)
+}
+
+// respondWithUser 返回更新后的用户，由于 ID 令牌中包含用户资料，同时返回新的 ID 令牌。
+// 使用个人访问令牌时只返回用户，否则受限的令牌可以换取包含全部角色与权限的 ID 令牌
+func (h *Handler) respondWithUser(c *gin.Context, u *model.User) {
+	if _, ok := c.Get("personalAccessToken"); ok {
+		c.JSON(http.StatusOK, gin.H{
+			"user": u,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	idToken, err := h.TokenService.NewIDToken(ctx, u)
+
+	if err != nil {
+		log.Printf("为用户 %v 创建 ID 令牌失败：%v\n", u.UID, err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":    u,
+		"idToken": idToken,
+	})
+}
@@ -0,0 +1,275 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDetails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID: uid,
+	}
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", ctxUser)
+	})
+
+	mockUserService := new(mocks.MockUserService)
+	mockTokenService := new(mocks.MockTokenService)
+
+	NewHandler(&Config{
+		R:            router,
+		UserService:  mockUserService,
+		TokenService: mockTokenService,
+	})
+
+	t.Run("成功", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		newName := "Jacob"
+		newEmail := "jacob@jacob.com"
+		newWebsite := "https://jacobgoodwin.me"
+
+		reqBody, _ := json.Marshal(gin.H{
+			"name":    newName,
+			"email":   newEmail,
+			"website": newWebsite,
+		})
+
+		request, _ := http.NewRequest(http.MethodPut, "/details", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		userToUpdate := &model.User{
+			UID:     ctxUser.UID,
+			Name:    newName,
+			Email:   newEmail,
+			Website: newWebsite,
+		}
+
+		updateArgs := mock.Arguments{
+			mock.AnythingOfType("*context.emptyCtx"),
+			userToUpdate,
+		}
+
+		dbImageURL := "https://jacobgoodwin.me/static/696292a38f493a4283d1a308e4a11732/84d81/Profile.jpg"
+
+		mockUserService.
+			On("UpdateDetails", updateArgs...).
+			Run(func(args mock.Arguments) {
+				userArg := args.Get(1).(*model.User) // arg 0 is context, arg 1 is *User
+				userArg.ImageURL = dbImageURL
+			}).
+			Return(nil)
+
+		mockIDToken := "aNewIDToken"
+		mockTokenService.
+			On("NewIDToken", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.User")).
+			Return(mockIDToken, nil)
+
+		router.ServeHTTP(rr, request)
+
+		userToUpdate.ImageURL = dbImageURL
+		respBody, _ := json.Marshal(gin.H{
+			"user":    userToUpdate,
+			"idToken": mockIDToken,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertCalled(t, "UpdateDetails", updateArgs...)
+		mockTokenService.AssertCalled(t, "NewIDToken", mock.AnythingOfType("*context.emptyCtx"), userToUpdate)
+	})
+
+	t.Run("成功且无需 name 与 website", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		newEmail := "jacob2@jacob.com"
+
+		reqBody, _ := json.Marshal(gin.H{
+			"email": newEmail,
+		})
+
+		request, _ := http.NewRequest(http.MethodPut, "/details", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		userToUpdate := &model.User{
+			UID:   ctxUser.UID,
+			Email: newEmail,
+		}
+
+		updateArgs := mock.Arguments{
+			mock.AnythingOfType("*context.emptyCtx"),
+			userToUpdate,
+		}
+
+		mockUserService.
+			On("UpdateDetails", updateArgs...).
+			Return(nil)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserService.AssertCalled(t, "UpdateDetails", updateArgs...)
+	})
+
+	t.Run("无效的请求", func(t *testing.T) {
+		testCases := []gin.H{
+			{"name": "Jacob"},
+			{"name": "Jacob", "email": "notanemail"},
+			{"email": "bob@bob.com", "website": "notaurl"},
+		}
+
+		for _, tc := range testCases {
+			rr := httptest.NewRecorder()
+
+			reqBody, _ := json.Marshal(tc)
+
+			request, _ := http.NewRequest(http.MethodPut, "/details", bytes.NewBuffer(reqBody))
+			request.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+		}
+
+		mockUserService.AssertNumberOfCalls(t, "UpdateDetails", 2)
+	})
+
+	t.Run("邮箱已被使用", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		newEmail := "taken@jacob.com"
+
+		reqBody, _ := json.Marshal(gin.H{
+			"email": newEmail,
+		})
+
+		request, _ := http.NewRequest(http.MethodPut, "/details", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		userToUpdate := &model.User{
+			UID:   ctxUser.UID,
+			Email: newEmail,
+		}
+
+		updateArgs := mock.Arguments{
+			mock.AnythingOfType("*context.emptyCtx"),
+			userToUpdate,
+		}
+
+		mockError := apperrors.NewConflict("email", newEmail)
+
+		mockUserService.
+			On("UpdateDetails", updateArgs...).
+			Return(mockError)
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertNumberOfCalls(t, "NewIDToken", 2)
+	})
+
+	t.Run("个人访问令牌不能修改邮箱", func(t *testing.T) {
+		patUser := &model.User{
+			UID:   uid,
+			Email: "jacob@jacob.com",
+		}
+
+		patRouter := gin.Default()
+		patRouter.Use(func(c *gin.Context) {
+			c.Set("user", patUser)
+			c.Set("personalAccessToken", &model.PersonalAccessToken{UID: uid})
+		})
+
+		patUserService := new(mocks.MockUserService)
+		NewHandler(&Config{
+			R:            patRouter,
+			UserService:  patUserService,
+			TokenService: mockTokenService,
+		})
+
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(gin.H{
+			"email": "attacker@evil.com",
+		})
+
+		request, _ := http.NewRequest(http.MethodPut, "/details", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		patRouter.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		patUserService.AssertNotCalled(t, "UpdateDetails", mock.Anything, mock.Anything)
+	})
+	t.Run("个人访问令牌不返回 ID 令牌", func(t *testing.T) {
+		patUser := &model.User{
+			UID:   uid,
+			Email: "jacob@jacob.com",
+		}
+
+		patRouter := gin.Default()
+		patRouter.Use(func(c *gin.Context) {
+			c.Set("user", patUser)
+			c.Set("personalAccessToken", &model.PersonalAccessToken{UID: uid})
+		})
+
+		patUserService := new(mocks.MockUserService)
+		patTokenService := new(mocks.MockTokenService)
+		NewHandler(&Config{
+			R:            patRouter,
+			UserService:  patUserService,
+			TokenService: patTokenService,
+		})
+
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(gin.H{
+			"name":  "Jacob",
+			"email": patUser.Email,
+		})
+
+		request, _ := http.NewRequest(http.MethodPut, "/details", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		userToUpdate := &model.User{
+			UID:   uid,
+			Name:  "Jacob",
+			Email: patUser.Email,
+		}
+
+		patUserService.
+			On("UpdateDetails", mock.AnythingOfType("*context.emptyCtx"), userToUpdate).
+			Return(nil)
+
+		patRouter.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"user": userToUpdate,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		patTokenService.AssertNotCalled(t, "NewIDToken", mock.Anything, mock.Anything)
+	})
+}
@@ -1,78 +1,187 @@
 package handler
 
 import (
-	"net/http"
+	"net"
 	"time"
 
 	"github.com/FuZhouJohn/memrizr/account/handler/middleware"
 	"github.com/FuZhouJohn/memrizr/account/model"
 	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/passwordpolicy"
 	"github.com/gin-gonic/gin"
 )
 
+// UnverifiedEmailPolicy 决定邮箱未验证的用户可以做什么
+type UnverifiedEmailPolicy string
+
+const (
+	// UnverifiedEmailAllow 不作限制
+	UnverifiedEmailAllow UnverifiedEmailPolicy = "allow"
+	// UnverifiedEmailRestrict 可以登录，但 ID 令牌中 email_verified 为 false，且无法上传头像
+	UnverifiedEmailRestrict UnverifiedEmailPolicy = "restrict"
+	// UnverifiedEmailDeny 验证邮箱前无法获取令牌
+	UnverifiedEmailDeny UnverifiedEmailPolicy = "deny"
+)
+
 type Handler struct {
-	UserService  model.UserService
-	TokenService model.TokenService
+	UserService                model.UserService
+	TokenService               model.TokenService
+	WebAuthnService            model.WebAuthnService
+	PasswordPolicy             model.PasswordPolicy
+	AuditService               model.AuditService
+	LoginAlertService          model.LoginAlertService
+	PersonalAccessTokenService model.PersonalAccessTokenService
+	RoleService                model.RoleService
+	RateLimitRepository        model.RateLimitRepository
+	MaxBodyBytes               int64
+	UnverifiedEmailPolicy      UnverifiedEmailPolicy
 }
 
 type Config struct {
 	R               *gin.Engine
 	UserService     model.UserService
 	TokenService    model.TokenService
-	BaseURL         string
-	TimeoutDuration time.Duration
+	WebAuthnService model.WebAuthnService
+	// PasswordPolicy 为 nil 时只检查密码长度
+	PasswordPolicy    model.PasswordPolicy
+	AuditService      model.AuditService
+	LoginAlertService model.LoginAlertService
+	// PersonalAccessTokenService 为 nil 时只接受 ID 令牌
+	PersonalAccessTokenService model.PersonalAccessTokenService
+	RoleService                model.RoleService
+	BaseURL                    string
+	TimeoutDuration            time.Duration
+	MaxBodyBytes               int64
+	// ImageDir 不为空时，以 /images 对外提供本地保存的图片
+	ImageDir              string
+	UnverifiedEmailPolicy UnverifiedEmailPolicy
+	// TrustedProxies 为可信的反向代理，只有来自这些地址的请求才会读取 X-Forwarded-For 中的客户端 IP
+	TrustedProxies []*net.IPNet
+	// RateLimitRepository 不为空时，按 RateLimits 限制各个路由的请求次数
+	RateLimitRepository model.RateLimitRepository
+	RateLimits          middleware.RateLimits
+	// AdminToken 不为空时注册 /admin 下的管理接口，请求需以 Bearer 方式携带该令牌，
+	// 可用于分配第一个管理员角色。其他管理接口要求 ID 令牌中具有相应的权限
+	AdminToken string
 }
 
 func NewHandler(c *Config) {
+	passwordPolicy := c.PasswordPolicy
+	if passwordPolicy == nil {
+		passwordPolicy, _ = passwordpolicy.New(&passwordpolicy.Config{})
+	}
 
 	h := &Handler{
-		UserService:  c.UserService,
-		TokenService: c.TokenService,
+		UserService:                c.UserService,
+		TokenService:               c.TokenService,
+		WebAuthnService:            c.WebAuthnService,
+		PasswordPolicy:             passwordPolicy,
+		AuditService:               c.AuditService,
+		LoginAlertService:          c.LoginAlertService,
+		PersonalAccessTokenService: c.PersonalAccessTokenService,
+		RoleService:                c.RoleService,
+		RateLimitRepository:        c.RateLimitRepository,
+		MaxBodyBytes:               c.MaxBodyBytes,
+		UnverifiedEmailPolicy:      c.UnverifiedEmailPolicy,
 	}
+
+	// UnverifiedEmailRestrict 策略下，需要已验证邮箱的接口
+	verifiedEmail := middleware.VerifiedEmail(c.UnverifiedEmailPolicy == UnverifiedEmailRestrict)
+
+	// authUser 验证 ID 令牌，scopes 不为空时也接受具有这些权限的个人访问令牌
+	authUser := func(scopes ...string) gin.HandlerFunc {
+		return middleware.AuthUser(h.TokenService, h.PersonalAccessTokenService, scopes...)
+	}
+
+	// 客户端 IP 由 RealIP 解析，不使用 gin 按最左侧的 X-Forwarded-For 取得的 IP
+	c.R.ForwardedByClientIP = false
+
 	g := c.R.Group(c.BaseURL)
+	g.Use(middleware.RealIP(c.TrustedProxies))
+	g.Use(middleware.Language())
+	if c.RateLimitRepository != nil {
+		g.Use(middleware.RateLimit(c.RateLimitRepository, c.BaseURL, c.RateLimits))
+	}
 	if gin.Mode() != gin.TestMode {
 		g.Use(middleware.Timeout(c.TimeoutDuration, apperrors.NewServiceUnavailable()))
-		g.GET("/me", middleware.AuthUser(h.TokenService), h.Me)
+		g.Use(middleware.ClientInfo())
+		g.GET("/me", authUser(model.ScopeProfileRead), h.Me)
+		g.GET("/me/activity", authUser(model.ScopeProfileRead), h.Activity)
+		g.POST("/signout", authUser(), h.Signout)
+		g.GET("/sessions", authUser(model.ScopeSessionsRead), h.Sessions)
+		g.DELETE("/sessions/:id", authUser(model.ScopeSessionsWrite), h.DeleteSession)
+		g.PUT("/details", authUser(model.ScopeProfileWrite), h.Details)
+		g.POST("/image", authUser(model.ScopeProfileWrite), verifiedEmail, h.Image)
+		g.DELETE("/image", authUser(model.ScopeProfileWrite), h.DeleteImage)
+		g.POST("/verify-email/resend", authUser(), h.ResendVerificationEmail)
+		g.PUT("/password", authUser(), h.Password)
+		g.POST("/mfa/totp", authUser(), h.EnrollTOTP)
+		g.POST("/mfa/totp/confirm", authUser(), h.ConfirmTOTP)
+		g.DELETE("/mfa/totp", authUser(), h.DisableTOTP)
+		g.GET("/mfa/recovery-codes", authUser(), h.RecoveryCodes)
+		g.POST("/mfa/recovery-codes", authUser(), h.RegenerateRecoveryCodes)
+		g.POST("/webauthn/register/begin", authUser(), h.BeginWebAuthnRegistration)
+		g.POST("/webauthn/register/finish", authUser(), h.FinishWebAuthnRegistration)
+		g.GET("/webauthn/credentials", authUser(), h.WebAuthnCredentials)
+		g.DELETE("/webauthn/credentials/:id", authUser(), h.DeleteWebAuthnCredential)
+		g.POST("/personal-access-tokens", authUser(), h.CreatePersonalAccessToken)
+		g.GET("/personal-access-tokens", authUser(), h.PersonalAccessTokens)
+		g.DELETE("/personal-access-tokens/:id", authUser(), h.RevokePersonalAccessToken)
+		g.GET("/users/:uid/roles", authUser(), middleware.Require(model.PermissionRolesRead), h.UserRoles)
+		g.PUT("/users/:uid/roles/:role", authUser(), middleware.Require(model.PermissionRolesManage), h.AssignRole)
+		g.DELETE("/users/:uid/roles/:role", authUser(), middleware.Require(model.PermissionRolesManage), h.UnassignRole)
+		g.DELETE("/users/:uid/lockout", authUser(), middleware.Require(model.PermissionUsersUnlock), h.ClearLockout)
 	} else {
 		g.GET("/me", h.Me)
+		g.GET("/me/activity", h.Activity)
+		g.POST("/signout", h.Signout)
+		g.GET("/sessions", h.Sessions)
+		g.DELETE("/sessions/:id", h.DeleteSession)
+		g.PUT("/details", h.Details)
+		g.POST("/image", verifiedEmail, h.Image)
+		g.DELETE("/image", h.DeleteImage)
+		g.POST("/verify-email/resend", h.ResendVerificationEmail)
+		g.PUT("/password", h.Password)
+		g.POST("/mfa/totp", h.EnrollTOTP)
+		g.POST("/mfa/totp/confirm", h.ConfirmTOTP)
+		g.DELETE("/mfa/totp", h.DisableTOTP)
+		g.GET("/mfa/recovery-codes", h.RecoveryCodes)
+		g.POST("/mfa/recovery-codes", h.RegenerateRecoveryCodes)
+		g.POST("/webauthn/register/begin", h.BeginWebAuthnRegistration)
+		g.POST("/webauthn/register/finish", h.FinishWebAuthnRegistration)
+		g.GET("/webauthn/credentials", h.WebAuthnCredentials)
+		g.DELETE("/webauthn/credentials/:id", h.DeleteWebAuthnCredential)
+		g.POST("/personal-access-tokens", h.CreatePersonalAccessToken)
+		g.GET("/personal-access-tokens", h.PersonalAccessTokens)
+		g.DELETE("/personal-access-tokens/:id", h.RevokePersonalAccessToken)
+		g.GET("/users/:uid/roles", middleware.Require(model.PermissionRolesRead), h.UserRoles)
+		g.PUT("/users/:uid/roles/:role", middleware.Require(model.PermissionRolesManage), h.AssignRole)
+		g.DELETE("/users/:uid/roles/:role", middleware.Require(model.PermissionRolesManage), h.UnassignRole)
+		g.DELETE("/users/:uid/lockout", middleware.Require(model.PermissionUsersUnlock), h.ClearLockout)
 	}
 
+	g.GET("/.well-known/jwks.json", h.JWKS)
+	g.GET("/.well-known/openid-configuration", h.OpenIDConfiguration)
 	g.POST("/signup", h.Signup)
 	g.POST("/signin", h.Signin)
-	g.POST("/signout", h.Signout)
+	g.POST("/signin/mfa", h.SigninMFA)
+	g.POST("/webauthn/login/begin", h.BeginWebAuthnLogin)
+	g.POST("/webauthn/login/finish", h.FinishWebAuthnLogin)
 	g.POST("/tokens", h.Tokens)
-	g.POST("/image", h.Image)
-	g.DELETE("/image", h.DeleteImage)
-	g.PUT("/details", h.Details)
+	g.POST("/verify-email", h.VerifyEmail)
+	g.POST("/password/forgot", h.ForgotPassword)
+	g.POST("/password/reset", h.ResetPassword)
+	g.POST("/unlock", h.UnlockAccount)
+	g.POST("/sessions/revoke", h.RevokeSessionFromAlert)
 
-}
-
-func (h *Handler) Signout(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"hello": "it's signout",
-	})
-}
-
-func (h *Handler) Tokens(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"hello": "it's tokens",
-	})
-}
-
-func (h *Handler) Image(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"hello": "it's image",
-	})
-}
-
-func (h *Handler) DeleteImage(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"hello": "it's delete image",
-	})
-}
+	if c.AdminToken != "" {
+		admin := g.Group("/admin", middleware.AdminToken(c.AdminToken))
+		admin.DELETE("/users/:uid/lockout", h.ClearLockout)
+		admin.PUT("/users/:uid/roles/:role", h.AssignRole)
+		admin.DELETE("/users/:uid/roles/:role", h.UnassignRole)
+	}
 
-func (h *Handler) Details(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"hello": "it's details",
-	})
+	if c.ImageDir != "" {
+		g.Static("/images", c.ImageDir)
+	}
 }
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+// Image 上传当前用户的头像，文件位于 multipart 表单的 imageFile 字段
+func (h *Handler) Image(c *gin.Context) {
+	authUser, exists := c.Get("user")
+
+	if !exists {
+		log.Printf("由于未知原因，无法从请求环境中提取用户：%v\n", c)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	// 请求体超过限制时，读取 multipart 表单会返回错误
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.MaxBodyBytes)
+
+	imageFileHeader, err := c.FormFile("imageFile")
+
+	if err != nil {
+		log.Printf("无法解析 multipart/form-data：%+v\n", err)
+
+		if err.Error() == "http: request body too large" {
+			e := apperrors.NewPayloadTooLarge(h.MaxBodyBytes, c.Request.ContentLength)
+			c.JSON(e.Status(), gin.H{
+				"error": e,
+			})
+			return
+		}
+
+		e := apperrors.NewBadRequest("无法解析 multipart/form-data，需要包含 imageFile 字段")
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	uid := authUser.(*model.User).UID
+
+	ctx := c.Request.Context()
+	u, err := h.UserService.SetProfileImage(ctx, uid, imageFileHeader)
+
+	if err != nil {
+		log.Printf("无法更新用户 %v 的头像：%v\n", uid, err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	h.respondWithUser(c, u)
+}
+
+// DeleteImage 删除当前用户的头像
+func (h *Handler) DeleteImage(c *gin.Context) {
+	authUser, exists := c.Get("user")
+
+	if !exists {
+		log.Printf("由于未知原因，无法从请求环境中提取用户：%v\n", c)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	uid := authUser.(*model.User).UID
+
+	ctx := c.Request.Context()
+	u, err := h.UserService.ClearProfileImage(ctx, uid)
+
+	if err != nil {
+		log.Printf("无法删除用户 %v 的头像：%v\n", uid, err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	h.respondWithUser(c, u)
+}
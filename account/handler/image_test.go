@@ -0,0 +1,290 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// multipartImageRequest 构造包含单个文件字段的 multipart/form-data 请求
+func multipartImageRequest(t *testing.T, method string, fieldName string, content []byte) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	part, err := writer.CreateFormFile(fieldName, "avatar.png")
+	assert.NoError(t, err)
+	_, err = part.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	request, _ := http.NewRequest(method, "/image", body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return request
+}
+
+func TestImage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID: uid,
+	}
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", ctxUser)
+	})
+
+	mockUserService := new(mocks.MockUserService)
+	mockTokenService := new(mocks.MockTokenService)
+
+	var maxBodyBytes int64 = 4 * 1024
+
+	NewHandler(&Config{
+		R:            router,
+		UserService:  mockUserService,
+		TokenService: mockTokenService,
+		MaxBodyBytes: maxBodyBytes,
+	})
+
+	t.Run("成功", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		request := multipartImageRequest(t, http.MethodPost, "imageFile", []byte("\x89PNG\r\n\x1a\nnotreallyapng"))
+
+		updatedUser := &model.User{
+			UID:      uid,
+			Email:    "bob@bob.com",
+			ImageURL: "http://malcorp.test/images/abc.png",
+		}
+
+		setArgs := mock.Arguments{
+			mock.AnythingOfType("*context.emptyCtx"),
+			uid,
+			mock.AnythingOfType("*multipart.FileHeader"),
+		}
+
+		mockUserService.On("SetProfileImage", setArgs...).Return(updatedUser, nil).Once()
+		mockTokenService.On("NewIDToken", mock.AnythingOfType("*context.emptyCtx"), updatedUser).Return("aNewIDToken", nil)
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"user":    updatedUser,
+			"idToken": "aNewIDToken",
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertCalled(t, "SetProfileImage", setArgs...)
+	})
+
+	t.Run("请求体过大", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		request := multipartImageRequest(t, http.MethodPost, "imageFile", make([]byte, maxBodyBytes+1))
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		mockUserService.AssertNumberOfCalls(t, "SetProfileImage", 1)
+	})
+
+	t.Run("缺少 imageFile 字段", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		request := multipartImageRequest(t, http.MethodPost, "notImageFile", []byte("content"))
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNumberOfCalls(t, "SetProfileImage", 1)
+	})
+
+	t.Run("不支持的图片类型", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		request := multipartImageRequest(t, http.MethodPost, "imageFile", []byte("just some text"))
+
+		mockError := apperrors.NewUnsupportedMediaType("不支持的图片类型：text/plain; charset=utf-8")
+
+		mockUserService.
+			On("SetProfileImage", mock.AnythingOfType("*context.emptyCtx"), uid, mock.AnythingOfType("*multipart.FileHeader")).
+			Return(nil, mockError).Once()
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertNumberOfCalls(t, "NewIDToken", 1)
+	})
+
+	t.Run("个人访问令牌不返回 ID 令牌", func(t *testing.T) {
+		patRouter := gin.Default()
+		patRouter.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+			c.Set("personalAccessToken", &model.PersonalAccessToken{UID: uid})
+		})
+
+		patUserService := new(mocks.MockUserService)
+		patTokenService := new(mocks.MockTokenService)
+		NewHandler(&Config{
+			R:            patRouter,
+			UserService:  patUserService,
+			TokenService: patTokenService,
+			MaxBodyBytes: maxBodyBytes,
+		})
+
+		rr := httptest.NewRecorder()
+
+		request := multipartImageRequest(t, http.MethodPost, "imageFile", []byte("\x89PNG\r\n\x1a\nnotreallyapng"))
+
+		updatedUser := &model.User{
+			UID:      uid,
+			ImageURL: "http://malcorp.test/images/abc.png",
+		}
+
+		patUserService.
+			On("SetProfileImage", mock.AnythingOfType("*context.emptyCtx"), uid, mock.AnythingOfType("*multipart.FileHeader")).
+			Return(updatedUser, nil)
+
+		patRouter.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"user": updatedUser,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		patTokenService.AssertNotCalled(t, "NewIDToken", mock.Anything, mock.Anything)
+	})
+}
+
+func TestDeleteImage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID: uid,
+	}
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", ctxUser)
+	})
+
+	mockUserService := new(mocks.MockUserService)
+	mockTokenService := new(mocks.MockTokenService)
+
+	NewHandler(&Config{
+		R:            router,
+		UserService:  mockUserService,
+		TokenService: mockTokenService,
+	})
+
+	t.Run("成功", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		request, _ := http.NewRequest(http.MethodDelete, "/image", nil)
+
+		updatedUser := &model.User{
+			UID:   uid,
+			Email: "bob@bob.com",
+		}
+
+		clearArgs := mock.Arguments{
+			mock.AnythingOfType("*context.emptyCtx"),
+			uid,
+		}
+
+		mockUserService.On("ClearProfileImage", clearArgs...).Return(updatedUser, nil).Once()
+		mockTokenService.On("NewIDToken", mock.AnythingOfType("*context.emptyCtx"), updatedUser).Return("aNewIDToken", nil)
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"user":    updatedUser,
+			"idToken": "aNewIDToken",
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertCalled(t, "ClearProfileImage", clearArgs...)
+	})
+
+	t.Run("失败", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		request, _ := http.NewRequest(http.MethodDelete, "/image", nil)
+
+		mockError := apperrors.NewInternal()
+
+		mockUserService.
+			On("ClearProfileImage", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(nil, mockError).Once()
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertNumberOfCalls(t, "NewIDToken", 1)
+	})
+
+	t.Run("个人访问令牌不返回 ID 令牌", func(t *testing.T) {
+		patRouter := gin.Default()
+		patRouter.Use(func(c *gin.Context) {
+			c.Set("user", ctxUser)
+			c.Set("personalAccessToken", &model.PersonalAccessToken{UID: uid})
+		})
+
+		patUserService := new(mocks.MockUserService)
+		patTokenService := new(mocks.MockTokenService)
+		NewHandler(&Config{
+			R:            patRouter,
+			UserService:  patUserService,
+			TokenService: patTokenService,
+		})
+
+		rr := httptest.NewRecorder()
+
+		request, _ := http.NewRequest(http.MethodDelete, "/image", nil)
+
+		updatedUser := &model.User{
+			UID: uid,
+		}
+
+		patUserService.
+			On("ClearProfileImage", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(updatedUser, nil)
+
+		patRouter.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"user": updatedUser,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		patTokenService.AssertNotCalled(t, "NewIDToken", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminToken 要求请求带有 `Authorization: Bearer {token}`，用于保护管理接口。token 为空时拒绝所有请求
+func AdminToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+
+		if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			err := apperrors.NewAuthorization("提供的管理令牌是无效的")
+			c.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
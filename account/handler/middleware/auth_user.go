@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/FuZhouJohn/memrizr/account/model"
@@ -20,7 +21,9 @@ type invalidArgument struct {
 	Param string `json:"param"`
 }
 
-func AuthUser(s model.TokenService) gin.HandlerFunc {
+// AuthUser 验证 Authorization 头中的 ID 令牌，并将用户添加到上下文中。scopes 不为空且 p 不为 nil 时，
+// 也接受具有 scopes 中所有权限的个人访问令牌，此时令牌信息以 personalAccessToken 添加到上下文中
+func AuthUser(s model.TokenService, p model.PersonalAccessTokenService, scopes ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		h := authHeader{}
 
@@ -67,6 +70,11 @@ func AuthUser(s model.TokenService) gin.HandlerFunc {
 			return
 		}
 
+		if strings.HasPrefix(idTokenHeader[1], model.PersonalAccessTokenPrefix) {
+			authPersonalAccessToken(c, p, idTokenHeader[1], scopes)
+			return
+		}
+
 		user, err := s.ValidateIDToken(idTokenHeader[1])
 
 		if err != nil {
@@ -83,3 +91,38 @@ func AuthUser(s model.TokenService) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+func authPersonalAccessToken(c *gin.Context, p model.PersonalAccessTokenService, token string, scopes []string) {
+	if p == nil || len(scopes) == 0 {
+		err := apperrors.NewAuthorization("该接口不接受个人访问令牌")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		c.Abort()
+		return
+	}
+
+	user, pat, err := p.Validate(c.Request.Context(), token)
+
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		c.Abort()
+		return
+	}
+
+	if !pat.HasScopes(scopes...) {
+		err := apperrors.NewForbidden(fmt.Sprintf("个人访问令牌缺少权限：%v", strings.Join(scopes, " ")))
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		c.Abort()
+		return
+	}
+
+	c.Set("user", user)
+	c.Set("personalAccessToken", pat)
+
+	c.Next()
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestAuthUser(t *testing.T) {
@@ -39,7 +40,7 @@ func TestAuthUser(t *testing.T) {
 
 		var contextUser *model.User
 
-		r.GET("/me", AuthUser(mockTokenService), func(c *gin.Context) {
+		r.GET("/me", AuthUser(mockTokenService, nil), func(c *gin.Context) {
 			contextKeyVal, _ := c.Get("user")
 			contextUser = contextKeyVal.(*model.User)
 		})
@@ -60,7 +61,7 @@ func TestAuthUser(t *testing.T) {
 
 		_, r := gin.CreateTestContext(rr)
 
-		r.GET("/me", AuthUser(mockTokenService))
+		r.GET("/me", AuthUser(mockTokenService, nil))
 
 		request, _ := http.NewRequest(http.MethodGet, "/me", http.NoBody)
 
@@ -76,7 +77,7 @@ func TestAuthUser(t *testing.T) {
 
 		_, r := gin.CreateTestContext(rr)
 
-		r.GET("/me", AuthUser(mockTokenService))
+		r.GET("/me", AuthUser(mockTokenService, nil))
 
 		request, _ := http.NewRequest(http.MethodGet, "/me", http.NoBody)
 
@@ -86,3 +87,94 @@ func TestAuthUser(t *testing.T) {
 		mockTokenService.AssertNotCalled(t, "ValidateIDToken")
 	})
 }
+
+func TestAuthUserPersonalAccessToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockTokenService := new(mocks.MockTokenService)
+	mockPATService := new(mocks.MockPersonalAccessTokenService)
+
+	uid, _ := uuid.NewRandom()
+	u := &model.User{
+		UID:   uid,
+		Email: "zhuangjinan@test.com",
+	}
+
+	validToken := model.PersonalAccessTokenPrefix + "validToken"
+	invalidToken := model.PersonalAccessTokenPrefix + "invalidToken"
+	pat := &model.PersonalAccessToken{
+		UID:    uid,
+		Scopes: []string{model.ScopeProfileRead},
+	}
+	invalidTokenErr := apperrors.NewAuthorization("无效的个人访问令牌")
+
+	mockPATService.On("Validate", mock.Anything, validToken).Return(u, pat, nil)
+	mockPATService.On("Validate", mock.Anything, invalidToken).Return(nil, nil, invalidTokenErr)
+
+	t.Run("具有所需权限", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		_, r := gin.CreateTestContext(rr)
+
+		var contextUser, contextPAT interface{}
+
+		r.GET("/me", AuthUser(mockTokenService, mockPATService, model.ScopeProfileRead), func(c *gin.Context) {
+			contextUser, _ = c.Get("user")
+			contextPAT, _ = c.Get("personalAccessToken")
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/me", http.NoBody)
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", validToken))
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, u, contextUser)
+		assert.Equal(t, pat, contextPAT)
+		mockTokenService.AssertNotCalled(t, "ValidateIDToken", mock.Anything)
+	})
+
+	t.Run("缺少权限", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		_, r := gin.CreateTestContext(rr)
+
+		r.PUT("/details", AuthUser(mockTokenService, mockPATService, model.ScopeProfileWrite))
+
+		request, _ := http.NewRequest(http.MethodPut, "/details", http.NoBody)
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", validToken))
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("接口不接受个人访问令牌", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		_, r := gin.CreateTestContext(rr)
+
+		unusedPATService := new(mocks.MockPersonalAccessTokenService)
+		r.PUT("/password", AuthUser(mockTokenService, unusedPATService))
+
+		request, _ := http.NewRequest(http.MethodPut, "/password", http.NoBody)
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", validToken))
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		unusedPATService.AssertNotCalled(t, "Validate", mock.Anything, mock.Anything)
+	})
+
+	t.Run("无效令牌", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		_, r := gin.CreateTestContext(rr)
+
+		r.GET("/me", AuthUser(mockTokenService, mockPATService, model.ScopeProfileRead))
+
+		request, _ := http.NewRequest(http.MethodGet, "/me", http.NoBody)
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", invalidToken))
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockPATService.AssertCalled(t, "Validate", mock.Anything, invalidToken)
+	})
+}
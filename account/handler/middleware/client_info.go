@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/gin-gonic/gin"
+)
+
+// ClientInfo 将发起请求的客户端信息保存到 request context 中，服务记录认证事件时据此获取 IP 与 User-Agent
+func ClientInfo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(model.ContextWithClientInfo(c.Request.Context(), NewClientInfo(c)))
+
+		c.Next()
+	}
+}
+
+// NewClientInfo 从请求中提取客户端信息，IP 为 RealIP 解析后的客户端地址
+func NewClientInfo(c *gin.Context) *model.ClientInfo {
+	return &model.ClientInfo{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+}
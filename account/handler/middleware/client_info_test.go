@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientInfo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	rr := httptest.NewRecorder()
+
+	_, r := gin.CreateTestContext(rr)
+
+	var client *model.ClientInfo
+
+	r.POST("/signin", ClientInfo(), func(c *gin.Context) {
+		client = model.ClientInfoFromContext(c.Request.Context())
+	})
+
+	request, _ := http.NewRequest(http.MethodPost, "/signin", http.NoBody)
+	request.Header.Set("User-Agent", "Mozilla/5.0")
+	request.RemoteAddr = "203.0.113.7:54321"
+	r.ServeHTTP(rr, request)
+
+	assert.Equal(t, &model.ClientInfo{
+		UserAgent: "Mozilla/5.0",
+		IP:        "203.0.113.7",
+	}, client)
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/gin-gonic/gin"
+)
+
+// Language 将 Accept-Language 保存到 request context 中，邮件等内容据此选择语言。
+// 请求没有该请求头时不替换 context
+func Language() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if lang := c.GetHeader("Accept-Language"); lang != "" {
+			c.Request = c.Request.WithContext(model.ContextWithLanguage(c.Request.Context(), lang))
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLanguage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("保存 Accept-Language", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		_, r := gin.CreateTestContext(rr)
+
+		var lang string
+
+		r.GET("/me", Language(), func(c *gin.Context) {
+			lang = model.LanguageFromContext(c.Request.Context())
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/me", http.NoBody)
+		request.Header.Set("Accept-Language", "en-US,en;q=0.9")
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, "en-US,en;q=0.9", lang)
+	})
+
+	t.Run("没有 Accept-Language 时不替换 context", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		_, r := gin.CreateTestContext(rr)
+
+		var ctx context.Context
+
+		r.GET("/me", Language(), func(c *gin.Context) {
+			ctx = c.Request.Context()
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/me", http.NoBody)
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, request.Context(), ctx)
+		assert.Equal(t, "", model.LanguageFromContext(ctx))
+	})
+}
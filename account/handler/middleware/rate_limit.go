@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitByIP 表示按客户端 IP 计数，其他值表示按 JSON 请求体中的同名字段计数
+const RateLimitByIP = "ip"
+
+// 读取请求体中的字段时最多读取的字节数，超出时该请求不按字段计数
+const rateLimitMaxBodyBytes = 64 << 10
+
+// RateLimitRule 为某个路由在 Window 内最多允许 Max 次请求
+type RateLimitRule struct {
+	By     string
+	Max    int
+	Window time.Duration
+}
+
+// RateLimits 以路由（不含 BaseURL，如 /signin）为键
+type RateLimits map[string][]RateLimitRule
+
+// ParseRateLimits 解析形如 `/signin=ip:20/1m,email:5/15m;/password/forgot=email:3/1h` 的配置，
+// 同一路由的多个限制以逗号分隔，不同路由以分号分隔
+func ParseRateLimits(s string) (RateLimits, error) {
+	limits := RateLimits{}
+
+	for _, route := range strings.Split(s, ";") {
+		route = strings.TrimSpace(route)
+		if route == "" {
+			continue
+		}
+
+		parts := strings.SplitN(route, "=", 2)
+		path := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("无效的限流配置：%v", route)
+		}
+
+		for _, rule := range strings.Split(parts[1], ",") {
+			limit, err := parseRateLimit(strings.TrimSpace(rule))
+			if err != nil {
+				return nil, fmt.Errorf("路由 %v 的限流配置无效：%w", path, err)
+			}
+
+			limits[path] = append(limits[path], limit)
+		}
+	}
+
+	return limits, nil
+}
+
+// parseRateLimit 解析形如 `email:5/15m` 的单个限制
+func parseRateLimit(rule string) (RateLimitRule, error) {
+	colon := strings.Index(rule, ":")
+	slash := strings.LastIndex(rule, "/")
+	if colon <= 0 || slash < colon {
+		return RateLimitRule{}, fmt.Errorf("%q 应为 {ip|字段}:{次数}/{窗口}", rule)
+	}
+
+	max, err := strconv.Atoi(rule[colon+1 : slash])
+	if err != nil || max <= 0 {
+		return RateLimitRule{}, fmt.Errorf("%q 中的次数应为正整数", rule)
+	}
+
+	window, err := time.ParseDuration(rule[slash+1:])
+	if err != nil || window < time.Second {
+		return RateLimitRule{}, fmt.Errorf("%q 中的窗口应为不小于 1s 的时长", rule)
+	}
+
+	return RateLimitRule{
+		By:     rule[:colon],
+		Max:    max,
+		Window: window,
+	}, nil
+}
+
+// RateLimit 按路由的配置依次计数，超出任一限制时返回 429 与 Retry-After，并且不再累加之后的限制，
+// 否则被 IP 限制拦下的请求仍会耗尽邮箱等字段的次数，导致其他 IP 的正常用户无法登录。
+// 计数失败时记录日志并放行，避免 Redis 故障导致无法登录
+func RateLimit(r model.RateLimitRepository, baseURL string, limits RateLimits) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := strings.TrimPrefix(c.FullPath(), baseURL)
+		routeLimits := limits[route]
+		if len(routeLimits) == 0 {
+			c.Next()
+			return
+		}
+
+		var fields map[string]interface{}
+		var wait time.Duration
+
+		for _, limit := range routeLimits {
+			var value string
+			if limit.By == RateLimitByIP {
+				value = c.ClientIP()
+			} else {
+				if fields == nil {
+					fields = peekJSONFields(c)
+				}
+
+				// 字段不存在时由 handler 返回参数错误
+				s, ok := fields[limit.By].(string)
+				if !ok || s == "" {
+					continue
+				}
+				value = strings.ToLower(strings.TrimSpace(s))
+			}
+
+			key := fmt.Sprintf("%s:%s:%s", route, limit.By, value)
+			w, err := r.Hit(c.Request.Context(), key, limit.Max, limit.Window)
+			if err != nil {
+				log.Printf("限流计数失败，放行请求：%v\n", err)
+				continue
+			}
+
+			if w > 0 {
+				wait = w
+				break
+			}
+		}
+
+		if wait > 0 {
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			err := apperrors.NewTooManyRequests(retryAfter)
+
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// peekJSONFields 读取 JSON 请求体的顶层字段，并还原请求体以便 handler 再次读取
+func peekJSONFields(c *gin.Context) map[string]interface{} {
+	fields := map[string]interface{}{}
+
+	if c.Request.Body == nil || c.ContentType() != "application/json" {
+		return fields
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, rateLimitMaxBodyBytes+1))
+	c.Request.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	if err != nil || len(body) > rateLimitMaxBodyBytes {
+		return fields
+	}
+
+	json.Unmarshal(body, &fields)
+
+	return fields
+}
@@ -0,0 +1,224 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limits := RateLimits{
+		"/signin": {
+			{By: RateLimitByIP, Max: 20, Window: time.Minute},
+			{By: "email", Max: 5, Window: 15 * time.Minute},
+		},
+	}
+
+	newRouter := func(r *mocks.MockRateLimitRepository, handled *[]byte) *gin.Engine {
+		_, router := gin.CreateTestContext(httptest.NewRecorder())
+
+		g := router.Group("/api/account")
+		g.Use(RealIP(nil))
+		g.Use(RateLimit(r, "/api/account", limits))
+		g.POST("/signin", func(c *gin.Context) {
+			*handled, _ = ioutil.ReadAll(c.Request.Body)
+			c.Status(http.StatusOK)
+		})
+		g.POST("/signup", func(c *gin.Context) {
+			c.Status(http.StatusCreated)
+		})
+
+		return router
+	}
+
+	signinRequest := func(email string) *http.Request {
+		reqBody, _ := json.Marshal(gin.H{
+			"email":    email,
+			"password": "avalidpassword",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/api/account/signin", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		request.RemoteAddr = "10.0.0.1:1234"
+
+		return request
+	}
+
+	t.Run("未超出限制", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockRateLimitRepository := new(mocks.MockRateLimitRepository)
+		mockRateLimitRepository.
+			On("Hit", mock.Anything, "/signin:ip:10.0.0.1", 20, time.Minute).
+			Return(time.Duration(0), nil)
+		mockRateLimitRepository.
+			On("Hit", mock.Anything, "/signin:email:bob@bob.com", 5, 15*time.Minute).
+			Return(time.Duration(0), nil)
+
+		var handled []byte
+		request := signinRequest(" Bob@bob.com")
+		reqBody, _ := ioutil.ReadAll(request.Body)
+		request.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+
+		newRouter(mockRateLimitRepository, &handled).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		// handler 仍能读取完整的请求体
+		assert.Equal(t, reqBody, handled)
+		mockRateLimitRepository.AssertExpectations(t)
+	})
+
+	t.Run("超出限制", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockRateLimitRepository := new(mocks.MockRateLimitRepository)
+		mockRateLimitRepository.
+			On("Hit", mock.Anything, "/signin:ip:10.0.0.1", 20, time.Minute).
+			Return(time.Duration(0), nil)
+		mockRateLimitRepository.
+			On("Hit", mock.Anything, "/signin:email:bob@bob.com", 5, 15*time.Minute).
+			Return(90*time.Second+300*time.Millisecond, nil)
+
+		var handled []byte
+		newRouter(mockRateLimitRepository, &handled).ServeHTTP(rr, signinRequest("bob@bob.com"))
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": apperrors.NewTooManyRequests(91),
+		})
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "91", rr.Header().Get("Retry-After"))
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		assert.Nil(t, handled)
+	})
+
+	t.Run("超出 IP 的限制后不再累加邮箱的计数", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockRateLimitRepository := new(mocks.MockRateLimitRepository)
+		mockRateLimitRepository.
+			On("Hit", mock.Anything, "/signin:ip:10.0.0.1", 20, time.Minute).
+			Return(30*time.Second, nil)
+
+		var handled []byte
+		newRouter(mockRateLimitRepository, &handled).ServeHTTP(rr, signinRequest("bob@bob.com"))
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "30", rr.Header().Get("Retry-After"))
+		assert.Nil(t, handled)
+		mockRateLimitRepository.AssertNotCalled(t, "Hit", mock.Anything, "/signin:email:bob@bob.com", mock.Anything, mock.Anything)
+	})
+
+	t.Run("伪造 X-Forwarded-For 不会重置 IP 的计数", func(t *testing.T) {
+		mockRateLimitRepository := new(mocks.MockRateLimitRepository)
+		mockRateLimitRepository.
+			On("Hit", mock.Anything, "/signin:ip:10.0.0.1", 20, time.Minute).
+			Return(time.Duration(0), nil)
+		mockRateLimitRepository.
+			On("Hit", mock.Anything, "/signin:email:bob@bob.com", 5, 15*time.Minute).
+			Return(time.Duration(0), nil)
+
+		var handled []byte
+		router := newRouter(mockRateLimitRepository, &handled)
+		router.ForwardedByClientIP = false
+
+		for _, spoofed := range []string{"198.51.100.1", "198.51.100.2"} {
+			request := signinRequest("bob@bob.com")
+			request.Header.Set("X-Forwarded-For", spoofed)
+			request.Header.Set("X-Real-IP", spoofed)
+
+			router.ServeHTTP(httptest.NewRecorder(), request)
+		}
+
+		mockRateLimitRepository.AssertNumberOfCalls(t, "Hit", 4)
+		mockRateLimitRepository.AssertCalled(t, "Hit", mock.Anything, "/signin:ip:10.0.0.1", 20, time.Minute)
+	})
+
+	t.Run("计数失败时放行", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockRateLimitRepository := new(mocks.MockRateLimitRepository)
+		mockRateLimitRepository.
+			On("Hit", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("int"), mock.AnythingOfType("time.Duration")).
+			Return(time.Duration(0), apperrors.NewInternal())
+
+		var handled []byte
+		newRouter(mockRateLimitRepository, &handled).ServeHTTP(rr, signinRequest("bob@bob.com"))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("请求体中没有字段时只按 IP 计数", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockRateLimitRepository := new(mocks.MockRateLimitRepository)
+		mockRateLimitRepository.
+			On("Hit", mock.Anything, "/signin:ip:10.0.0.1", 20, time.Minute).
+			Return(time.Duration(0), nil)
+
+		var handled []byte
+		newRouter(mockRateLimitRepository, &handled).ServeHTTP(rr, signinRequest(""))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockRateLimitRepository.AssertNumberOfCalls(t, "Hit", 1)
+	})
+
+	t.Run("未配置限制的路由", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockRateLimitRepository := new(mocks.MockRateLimitRepository)
+
+		var handled []byte
+		request, _ := http.NewRequest(http.MethodPost, "/api/account/signup", nil)
+		newRouter(mockRateLimitRepository, &handled).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		mockRateLimitRepository.AssertNotCalled(t, "Hit", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestParseRateLimits(t *testing.T) {
+	t.Run("解析多个路由", func(t *testing.T) {
+		limits, err := ParseRateLimits(" /signin=ip:20/1m, email:5/15m ; /password/forgot=email:3/1h;")
+
+		assert.NoError(t, err)
+		assert.Equal(t, RateLimits{
+			"/signin": {
+				{By: "ip", Max: 20, Window: time.Minute},
+				{By: "email", Max: 5, Window: 15 * time.Minute},
+			},
+			"/password/forgot": {
+				{By: "email", Max: 3, Window: time.Hour},
+			},
+		}, limits)
+	})
+
+	for _, config := range []string{
+		"signin=ip:20/1m",
+		"/signin",
+		"/signin=ip20/1m",
+		"/signin=ip:0/1m",
+		"/signin=ip:20/1",
+		"/signin=ip:20/1ms",
+		"/signin=:20/1m",
+	} {
+		config := config
+		t.Run(fmt.Sprintf("无效的配置 %v", config), func(t *testing.T) {
+			_, err := ParseRateLimits(config)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ParseTrustedProxies 解析以逗号分隔的 IP 或 CIDR，如 `10.0.0.0/8,192.168.1.10`
+func ParseTrustedProxies(s string) ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+
+	for _, proxy := range strings.Split(s, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("无效的代理地址：%v", proxy)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("无效的代理地址：%v", proxy)
+		}
+		proxies = append(proxies, network)
+	}
+
+	return proxies, nil
+}
+
+// RealIP 将 Request.RemoteAddr 替换为客户端的 IP，此后 c.ClientIP() 返回该 IP，限流、会话与审计日志使用同一个值。
+// 只有直接连接的对端属于 trustedProxies 时才读取 X-Forwarded-For，从右向左跳过可信代理，
+// 取第一个不可信的地址：最左侧的地址由客户端提供，可以任意伪造。
+// gin.Engine 的 ForwardedByClientIP 需要设为 false，否则 c.ClientIP() 仍会读取请求头
+func RealIP(trustedProxies []*net.IPNet) gin.HandlerFunc {
+	trusted := func(ip net.IP) bool {
+		for _, network := range trustedProxies {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return func(c *gin.Context) {
+		host, _, err := net.SplitHostPort(strings.TrimSpace(c.Request.RemoteAddr))
+		remoteIP := net.ParseIP(host)
+
+		if err != nil || remoteIP == nil || !trusted(remoteIP) {
+			c.Next()
+			return
+		}
+
+		clientIP := remoteIP
+		hops := strings.Split(c.GetHeader("X-Forwarded-For"), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				break
+			}
+
+			clientIP = ip
+			if !trusted(ip) {
+				break
+			}
+		}
+
+		c.Request.RemoteAddr = net.JoinHostPort(clientIP.String(), "0")
+
+		c.Next()
+	}
+}
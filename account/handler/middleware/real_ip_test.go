@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRealIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	trustedProxies, err := ParseTrustedProxies("10.0.0.0/8, 192.168.1.10")
+	assert.NoError(t, err)
+
+	clientIP := func(remoteAddr string, forwardedFor string) string {
+		_, router := gin.CreateTestContext(httptest.NewRecorder())
+		router.ForwardedByClientIP = false
+
+		var ip string
+		router.Use(RealIP(trustedProxies))
+		router.GET("/", func(c *gin.Context) {
+			ip = c.ClientIP()
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/", http.NoBody)
+		request.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			request.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), request)
+
+		return ip
+	}
+
+	t.Run("对端不是可信代理时忽略请求头", func(t *testing.T) {
+		assert.Equal(t, "203.0.113.7", clientIP("203.0.113.7:1234", "198.51.100.1"))
+	})
+
+	t.Run("取最右侧不可信的地址", func(t *testing.T) {
+		// 客户端伪造的 198.51.100.1 位于最左侧
+		assert.Equal(t, "203.0.113.7", clientIP("10.0.0.1:1234", "198.51.100.1, 203.0.113.7"))
+		assert.Equal(t, "203.0.113.7", clientIP("192.168.1.10:1234", "198.51.100.1, 203.0.113.7, 10.0.0.2"))
+	})
+
+	t.Run("没有请求头或请求头无效", func(t *testing.T) {
+		assert.Equal(t, "10.0.0.1", clientIP("10.0.0.1:1234", ""))
+		assert.Equal(t, "203.0.113.7", clientIP("10.0.0.1:1234", "unknown, 203.0.113.7"))
+	})
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies("")
+	assert.NoError(t, err)
+	assert.Empty(t, proxies)
+
+	proxies, err = ParseTrustedProxies("10.0.0.0/8,::1")
+	assert.NoError(t, err)
+	assert.Len(t, proxies, 2)
+
+	_, err = ParseTrustedProxies("10.0.0.0/33")
+	assert.Error(t, err)
+
+	_, err = ParseTrustedProxies("proxy.local")
+	assert.Error(t, err)
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+// Require 要求用户具有 perms 中的所有权限，需要在 AuthUser 之后使用。
+// 权限来自 ID 令牌的 permissions claim，个人访问令牌不具有任何权限
+func Require(perms ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+
+		if !exists {
+			err := apperrors.NewAuthorization("需要登录")
+			c.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			c.Abort()
+			return
+		}
+
+		if !user.(*model.User).HasPermissions(perms...) {
+			err := apperrors.NewForbidden(fmt.Sprintf("缺少权限：%v", strings.Join(perms, " ")))
+			c.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequire(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(user *model.User, perms ...string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		_, r := gin.CreateTestContext(rr)
+		if user != nil {
+			r.Use(func(c *gin.Context) {
+				c.Set("user", user)
+			})
+		}
+
+		r.GET("/admin", Require(perms...), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/admin", http.NoBody)
+		r.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	admin := &model.User{
+		Roles:       []string{"admin"},
+		Permissions: []string{model.PermissionRolesManage, model.PermissionUsersUnlock},
+	}
+
+	t.Run("具有所有权限", func(t *testing.T) {
+		rr := serve(admin, model.PermissionRolesManage, model.PermissionUsersUnlock)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("缺少其中一个权限", func(t *testing.T) {
+		rr := serve(admin, model.PermissionRolesManage, model.PermissionRolesRead)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("没有任何角色", func(t *testing.T) {
+		rr := serve(&model.User{}, model.PermissionUsersUnlock)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("上下文中没有 User", func(t *testing.T) {
+		rr := serve(nil, model.PermissionUsersUnlock)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+}
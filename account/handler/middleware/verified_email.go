@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+// VerifiedEmail 拒绝邮箱未验证的用户访问，需要在 AuthUser 之后使用。required 为 false 时不作限制
+func VerifiedEmail(required bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !required {
+			c.Next()
+			return
+		}
+
+		user, exists := c.Get("user")
+
+		if !exists || !user.(*model.User).EmailVerified {
+			err := apperrors.NewForbidden("请先验证邮箱")
+			c.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
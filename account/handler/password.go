@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+// changePasswordReq 中的新密码与注册时一样需要符合 PasswordPolicy
+type changePasswordReq struct {
+	CurrentPassword      string `json:"currentPassword" binding:"required,lte=1024"`
+	NewPassword          string `json:"newPassword" binding:"required,lte=1024"`
+	SignoutOtherSessions bool   `json:"signoutOtherSessions"`
+}
+
+// Password 修改当前用户的密码。signoutOtherSessions 为 true 时撤销所有会话与个人访问令牌，并为当前客户端签发新的令牌对
+func (h *Handler) Password(c *gin.Context) {
+	authUser, exists := c.Get("user")
+
+	if !exists {
+		log.Printf("由于未知原因，无法从请求环境中提取用户：%v\n", c)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	var req changePasswordReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	u := authUser.(*model.User)
+
+	if ok := h.checkPassword(c, "NewPassword", req.NewPassword, u.Email); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	err := h.UserService.ChangePassword(ctx, u.UID, req.CurrentPassword, req.NewPassword)
+
+	if err != nil {
+		log.Printf("用户 %v 修改密码失败：%v\n", u.UID, err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if !req.SignoutOtherSessions {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "密码已修改",
+		})
+		return
+	}
+
+	if err := h.TokenService.Signout(ctx, u.UID, ""); err != nil {
+		log.Printf("修改密码后无法撤销用户 %v 的会话：%v\n", u.UID, err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if ok := h.revokePersonalAccessTokens(c, u.UID); !ok {
+		return
+	}
+
+	tokens, err := h.TokenService.NewPairFromUser(ctx, u, "", clientInfo(c))
+
+	if err != nil {
+		log.Printf("创建用户令牌失败：%v\n", err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "密码已修改，其他设备已退出登录",
+		"tokens":  tokens,
+	})
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+// checkPassword 按 PasswordPolicy 检查新密码，不符合时以与参数校验相同的格式返回 invalidArgs。
+// field 为请求结构体中的字段名，invalidArgs 中不回显密码
+func (h *Handler) checkPassword(c *gin.Context, field string, password string, userInputs ...string) bool {
+	violations := h.PasswordPolicy.Check(password, userInputs...)
+	if len(violations) == 0 {
+		return true
+	}
+
+	invalidArgs := make([]invalidArgument, len(violations))
+	for i, v := range violations {
+		invalidArgs[i] = invalidArgument{
+			Field: field,
+			Tag:   v.Tag,
+			Param: v.Param,
+		}
+	}
+
+	err := apperrors.NewBadRequest("无效的请求参数，详情见 invalidArgs")
+
+	c.JSON(err.Status(), gin.H{
+		"error":       err,
+		"invalidArgs": invalidArgs,
+	})
+
+	return false
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+type forgotPasswordReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ForgotPassword 发送重置密码邮件。无论邮箱是否已注册、邮件是否发送成功都返回 200，避免被用于探测邮箱
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var req forgotPasswordReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.UserService.RequestPasswordReset(ctx, req.Email); err != nil {
+		log.Printf("无法处理 %v 的重置密码请求：%v\n", req.Email, err.Error())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "如果该邮箱已注册，你将收到一封重置密码的邮件",
+	})
+}
+
+type resetPasswordReq struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,lte=1024"`
+}
+
+// ResetPassword 使用重置密码邮件中的令牌设置新密码，并撤销该用户的所有会话与个人访问令牌
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var req resetPasswordReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	// 与注册、修改密码相同，新密码不能包含邮箱，因此先查询令牌所属的用户
+	u, err := h.UserService.FindByPasswordResetToken(ctx, req.Token)
+	if err != nil {
+		log.Printf("重置密码失败：%v\n", err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if ok := h.checkPassword(c, "Password", req.Password, u.Email); !ok {
+		return
+	}
+
+	uid, err := h.UserService.ResetPassword(ctx, req.Token, req.Password)
+
+	if err != nil {
+		log.Printf("重置密码失败：%v\n", err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	// 密码可能已泄露，已登录的设备需要使用新密码重新登录
+	if err := h.TokenService.Signout(ctx, uid, ""); err != nil {
+		log.Printf("重置密码后无法撤销用户 %v 的会话：%v\n", uid, err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if ok := h.revokePersonalAccessTokens(c, uid); !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "密码已重置，请使用新密码登录",
+	})
+}
@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestForgotPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockUserService := new(mocks.MockUserService)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:           router,
+		UserService: mockUserService,
+	})
+
+	t.Run("无论处理结果都返回 200", func(t *testing.T) {
+		mockUserService.
+			On("RequestPasswordReset", mock.AnythingOfType("*context.emptyCtx"), "bob@bob.com").
+			Return(nil)
+		mockUserService.
+			On("RequestPasswordReset", mock.AnythingOfType("*context.emptyCtx"), "broken@bob.com").
+			Return(apperrors.NewInternal())
+
+		var bodies [][]byte
+
+		for _, email := range []string{"bob@bob.com", "broken@bob.com"} {
+			rr := httptest.NewRecorder()
+
+			reqBody, _ := json.Marshal(gin.H{
+				"email": email,
+			})
+
+			request, _ := http.NewRequest(http.MethodPost, "/password/forgot", bytes.NewBuffer(reqBody))
+			request.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			bodies = append(bodies, rr.Body.Bytes())
+		}
+
+		assert.Equal(t, bodies[0], bodies[1])
+		mockUserService.AssertNumberOfCalls(t, "RequestPasswordReset", 2)
+	})
+
+	t.Run("邮箱格式错误", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(gin.H{
+			"email": "notanemail",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/password/forgot", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNumberOfCalls(t, "RequestPasswordReset", 2)
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockUserService := new(mocks.MockUserService)
+	mockTokenService := new(mocks.MockTokenService)
+	mockPersonalAccessTokenService := new(mocks.MockPersonalAccessTokenService)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:                          router,
+		UserService:                mockUserService,
+		TokenService:               mockTokenService,
+		PersonalAccessTokenService: mockPersonalAccessTokenService,
+	})
+
+	mockUserService.
+		On("FindByPasswordResetToken", mock.AnythingOfType("*context.emptyCtx"), "aValidToken").
+		Return(&model.User{Email: "bob@bob.com"}, nil)
+
+	t.Run("成功并撤销所有会话", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		uid, _ := uuid.NewRandom()
+
+		mockUserService.
+			On("ResetPassword", mock.AnythingOfType("*context.emptyCtx"), "aValidToken", "anewpassword").
+			Return(uid, nil)
+		mockTokenService.
+			On("Signout", mock.AnythingOfType("*context.emptyCtx"), uid, "").
+			Return(nil)
+		mockPersonalAccessTokenService.
+			On("RevokeAll", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(nil)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"token":    "aValidToken",
+			"password": "anewpassword",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/password/reset", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockTokenService.AssertCalled(t, "Signout", mock.AnythingOfType("*context.emptyCtx"), uid, "")
+		// 个人访问令牌随会话一起撤销
+		mockPersonalAccessTokenService.AssertCalled(t, "RevokeAll", mock.AnythingOfType("*context.emptyCtx"), uid)
+	})
+
+	t.Run("密码太短", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(gin.H{
+			"token":    "aValidToken",
+			"password": "short",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/password/reset", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNumberOfCalls(t, "ResetPassword", 1)
+	})
+
+	t.Run("按令牌所属用户的邮箱检查密码策略", func(t *testing.T) {
+		mockPasswordPolicy := new(mocks.MockPasswordPolicy)
+		mockPasswordPolicy.
+			On("Check", "bob@bob.com-password", []string{"bob@bob.com"}).
+			Return([]model.PasswordViolation{
+				{Tag: "strength", Param: "2"},
+			})
+
+		rr := httptest.NewRecorder()
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:              router,
+			UserService:    mockUserService,
+			PasswordPolicy: mockPasswordPolicy,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"token":    "aValidToken",
+			"password": "bob@bob.com-password",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/password/reset", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockPasswordPolicy.AssertExpectations(t)
+		mockUserService.AssertNumberOfCalls(t, "ResetPassword", 1)
+	})
+
+	t.Run("令牌无效", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockError := apperrors.NewAuthorization("令牌无效、已过期或已被使用")
+		mockUserService.
+			On("FindByPasswordResetToken", mock.AnythingOfType("*context.emptyCtx"), "anInvalidToken").
+			Return(nil, mockError)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"token":    "anInvalidToken",
+			"password": "anewpassword",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/password/reset", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertNumberOfCalls(t, "ResetPassword", 1)
+		mockTokenService.AssertNumberOfCalls(t, "Signout", 1)
+	})
+}
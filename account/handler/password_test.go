@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID:   uid,
+		Email: "bob@bob.com",
+	}
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", ctxUser)
+	})
+
+	mockUserService := new(mocks.MockUserService)
+	mockTokenService := new(mocks.MockTokenService)
+	mockPersonalAccessTokenService := new(mocks.MockPersonalAccessTokenService)
+
+	NewHandler(&Config{
+		R:                          router,
+		UserService:                mockUserService,
+		TokenService:               mockTokenService,
+		PersonalAccessTokenService: mockPersonalAccessTokenService,
+	})
+
+	changeArgs := mock.Arguments{
+		mock.AnythingOfType("*context.emptyCtx"),
+		uid,
+		"currentpassword",
+		"anewpassword",
+	}
+
+	t.Run("成功", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockUserService.On("ChangePassword", changeArgs...).Return(nil).Once()
+
+		reqBody, _ := json.Marshal(gin.H{
+			"currentPassword": "currentpassword",
+			"newPassword":     "anewpassword",
+		})
+
+		request, _ := http.NewRequest(http.MethodPut, "/password", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserService.AssertCalled(t, "ChangePassword", changeArgs...)
+		mockTokenService.AssertNotCalled(t, "Signout", mock.Anything, mock.Anything, mock.Anything)
+		mockPersonalAccessTokenService.AssertNotCalled(t, "RevokeAll", mock.Anything, mock.Anything)
+	})
+
+	t.Run("成功并退出其他设备", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		tokens := &model.TokenPair{
+			IDToken:      "idToken",
+			RefreshToken: "refreshToken",
+		}
+
+		mockUserService.On("ChangePassword", changeArgs...).Return(nil).Once()
+		mockTokenService.
+			On("Signout", mock.AnythingOfType("*context.emptyCtx"), uid, "").
+			Return(nil)
+		mockTokenService.
+			On("NewPairFromUser", mock.AnythingOfType("*context.emptyCtx"), ctxUser, "", mock.AnythingOfType("*model.ClientInfo")).
+			Return(tokens, nil)
+		mockPersonalAccessTokenService.
+			On("RevokeAll", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(nil)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"currentPassword":      "currentpassword",
+			"newPassword":          "anewpassword",
+			"signoutOtherSessions": true,
+		})
+
+		request, _ := http.NewRequest(http.MethodPut, "/password", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"message": "密码已修改，其他设备已退出登录",
+			"tokens":  tokens,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertCalled(t, "Signout", mock.AnythingOfType("*context.emptyCtx"), uid, "")
+		mockPersonalAccessTokenService.AssertCalled(t, "RevokeAll", mock.AnythingOfType("*context.emptyCtx"), uid)
+	})
+
+	t.Run("新密码不符合长度要求", func(t *testing.T) {
+		for _, newPassword := range []string{"short", strings.Repeat("a", 129)} {
+			rr := httptest.NewRecorder()
+
+			reqBody, _ := json.Marshal(gin.H{
+				"currentPassword": "currentpassword",
+				"newPassword":     newPassword,
+			})
+
+			request, _ := http.NewRequest(http.MethodPut, "/password", bytes.NewBuffer(reqBody))
+			request.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+		}
+
+		mockUserService.AssertNumberOfCalls(t, "ChangePassword", 2)
+	})
+
+	t.Run("当前密码错误", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockError := apperrors.NewAuthorization("当前密码错误")
+		mockUserService.
+			On("ChangePassword", mock.AnythingOfType("*context.emptyCtx"), uid, "wrongpassword", "anewpassword").
+			Return(mockError)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"currentPassword": "wrongpassword",
+			"newPassword":     "anewpassword",
+		})
+
+		request, _ := http.NewRequest(http.MethodPut, "/password", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+}
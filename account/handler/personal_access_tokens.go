@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// createPersonalAccessTokenReq 中 scopes 与 expiresInDays 由 PersonalAccessTokenService 校验
+type createPersonalAccessTokenReq struct {
+	Name          string   `json:"name" binding:"required,lte=64"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expiresInDays"`
+}
+
+// CreatePersonalAccessToken 为当前用户创建个人访问令牌，令牌只在响应中返回这一次
+func (h *Handler) CreatePersonalAccessToken(c *gin.Context) {
+	authUser, exists := c.Get("user")
+
+	if !exists {
+		log.Printf("由于未知原因，无法从请求环境中提取用户：%v\n", c)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	var req createPersonalAccessTokenReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	uid := authUser.(*model.User).UID
+	expiresIn := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+
+	ctx := c.Request.Context()
+	pat, token, err := h.PersonalAccessTokenService.Create(ctx, uid, req.Name, req.Scopes, expiresIn)
+
+	if err != nil {
+		log.Printf("无法为用户 %v 创建个人访问令牌：%v\n", uid, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":               token,
+		"personalAccessToken": pat,
+	})
+}
+
+// PersonalAccessTokens 列出当前用户的个人访问令牌，不包括令牌本身
+func (h *Handler) PersonalAccessTokens(c *gin.Context) {
+	authUser, exists := c.Get("user")
+
+	if !exists {
+		log.Printf("由于未知原因，无法从请求环境中提取用户：%v\n", c)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	uid := authUser.(*model.User).UID
+
+	ctx := c.Request.Context()
+	tokens, err := h.PersonalAccessTokenService.List(ctx, uid)
+
+	if err != nil {
+		log.Printf("无法获取用户 %v 的个人访问令牌：%v\n", uid, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"personalAccessTokens": tokens,
+	})
+}
+
+// RevokePersonalAccessToken 撤销当前用户的某个个人访问令牌
+func (h *Handler) RevokePersonalAccessToken(c *gin.Context) {
+	authUser, exists := c.Get("user")
+
+	if !exists {
+		log.Printf("由于未知原因，无法从请求环境中提取用户：%v\n", c)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		err := apperrors.NewBadRequest("无效的令牌 id")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	uid := authUser.(*model.User).UID
+
+	ctx := c.Request.Context()
+
+	if err := h.PersonalAccessTokenService.Revoke(ctx, uid, id); err != nil {
+		log.Printf("无法撤销用户 %v 的个人访问令牌 %v：%v\n", uid, id, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "个人访问令牌已撤销",
+	})
+}
+
+// revokePersonalAccessTokens 在撤销所有会话时一并撤销用户的个人访问令牌，
+// 否则泄露的令牌在修改密码后仍然有效。失败时写入响应并返回 false
+func (h *Handler) revokePersonalAccessTokens(c *gin.Context, uid uuid.UUID) bool {
+	if h.PersonalAccessTokenService == nil {
+		return true
+	}
+
+	if err := h.PersonalAccessTokenService.RevokeAll(c.Request.Context(), uid); err != nil {
+		log.Printf("无法撤销用户 %v 的个人访问令牌：%v\n", uid, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPersonalAccessTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	tokenID, _ := uuid.NewRandom()
+
+	mockPAT := &model.PersonalAccessToken{
+		ID:        tokenID,
+		UID:       uid,
+		Name:      "部署脚本",
+		TokenHash: "aTokenHash",
+		Scopes:    []string{model.ScopeProfileRead},
+		ExpiresAt: time.Unix(1628600000, 0),
+		CreatedAt: time.Unix(1626000000, 0),
+	}
+
+	mockPATService := new(mocks.MockPersonalAccessTokenService)
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", &model.User{
+			UID: uid,
+		})
+	})
+
+	NewHandler(&Config{
+		R:                          router,
+		PersonalAccessTokenService: mockPATService,
+	})
+
+	t.Run("创建令牌", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockPATService.
+			On("Create", mock.AnythingOfType("*context.emptyCtx"), uid, "部署脚本", []string{model.ScopeProfileRead}, 30*24*time.Hour).
+			Return(mockPAT, "mpat_aSecret", nil)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"name":          "部署脚本",
+			"scopes":        []string{model.ScopeProfileRead},
+			"expiresInDays": 30,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/personal-access-tokens", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"token":               "mpat_aSecret",
+			"personalAccessToken": mockPAT,
+		})
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		// 不返回令牌的摘要
+		assert.NotContains(t, rr.Body.String(), "aTokenHash")
+	})
+
+	t.Run("缺少名称", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(gin.H{
+			"scopes":        []string{model.ScopeProfileRead},
+			"expiresInDays": 30,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/personal-access-tokens", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockPATService.AssertNumberOfCalls(t, "Create", 1)
+	})
+
+	t.Run("无效的权限范围", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockErr := apperrors.NewBadRequest("无效的权限范围：admin")
+		mockPATService.
+			On("Create", mock.AnythingOfType("*context.emptyCtx"), uid, "部署脚本", []string{"admin"}, 24*time.Hour).
+			Return(nil, "", mockErr)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"name":          "部署脚本",
+			"scopes":        []string{"admin"},
+			"expiresInDays": 1,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/personal-access-tokens", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockErr,
+		})
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("列出令牌", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockPATService.
+			On("List", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return([]*model.PersonalAccessToken{mockPAT}, nil)
+
+		request, _ := http.NewRequest(http.MethodGet, "/personal-access-tokens", http.NoBody)
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"personalAccessTokens": []*model.PersonalAccessToken{mockPAT},
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("撤销令牌", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockPATService.On("Revoke", mock.AnythingOfType("*context.emptyCtx"), uid, tokenID).Return(nil)
+
+		request, _ := http.NewRequest(http.MethodDelete, "/personal-access-tokens/"+tokenID.String(), http.NoBody)
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"message": "个人访问令牌已撤销",
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("撤销不存在的令牌", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		otherID, _ := uuid.NewRandom()
+		mockErr := apperrors.NewNotFound("personal access token", otherID.String())
+		mockPATService.On("Revoke", mock.AnythingOfType("*context.emptyCtx"), uid, otherID).Return(mockErr)
+
+		request, _ := http.NewRequest(http.MethodDelete, "/personal-access-tokens/"+otherID.String(), http.NoBody)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("无效的令牌 id", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		request, _ := http.NewRequest(http.MethodDelete, "/personal-access-tokens/not-a-uuid", http.NoBody)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockPATService.AssertNumberOfCalls(t, "Revoke", 2)
+	})
+}
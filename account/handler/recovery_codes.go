@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+// RecoveryCodes 返回当前用户剩余的恢复码数量
+func (h *Handler) RecoveryCodes(c *gin.Context) {
+	authUser, exists := c.Get("user")
+
+	if !exists {
+		log.Printf("由于未知原因，无法从请求环境中提取用户：%v\n", c)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	uid := authUser.(*model.User).UID
+
+	ctx := c.Request.Context()
+	remaining, err := h.UserService.CountRecoveryCodes(ctx, uid)
+
+	if err != nil {
+		log.Printf("无法统计用户 %v 的恢复码：%v\n", uid, err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"remaining": remaining,
+	})
+}
+
+// RegenerateRecoveryCodes 校验 TOTP 验证码或恢复码后生成新的恢复码，已有的恢复码全部失效
+func (h *Handler) RegenerateRecoveryCodes(c *gin.Context) {
+	authUser, exists := c.Get("user")
+
+	if !exists {
+		log.Printf("由于未知原因，无法从请求环境中提取用户：%v\n", c)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	var req totpCodeReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	uid := authUser.(*model.User).UID
+
+	ctx := c.Request.Context()
+	recoveryCodes, err := h.UserService.RegenerateRecoveryCodes(ctx, uid, req.Code)
+
+	if err != nil {
+		log.Printf("无法为用户 %v 重新生成恢复码：%v\n", uid, err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recoveryCodes": recoveryCodes,
+	})
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRecoveryCodes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID:   uid,
+		Email: "bob@bob.com",
+	}
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", ctxUser)
+	})
+
+	mockUserService := new(mocks.MockUserService)
+	mockTokenService := new(mocks.MockTokenService)
+
+	NewHandler(&Config{
+		R:            router,
+		UserService:  mockUserService,
+		TokenService: mockTokenService,
+	})
+
+	t.Run("剩余数量", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockUserService.
+			On("CountRecoveryCodes", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(8, nil)
+
+		request, _ := http.NewRequest(http.MethodGet, "/mfa/recovery-codes", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"remaining": 8,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("重新生成", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		recoveryCodes := []string{"abcde-fgh23", "ijklm-nop45"}
+		mockUserService.
+			On("RegenerateRecoveryCodes", mock.AnythingOfType("*context.emptyCtx"), uid, "123456").
+			Return(recoveryCodes, nil)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"code": "123456",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/mfa/recovery-codes", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"recoveryCodes": recoveryCodes,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("验证码错误", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockErr := apperrors.NewAuthorization("验证码错误")
+		mockUserService.
+			On("RegenerateRecoveryCodes", mock.AnythingOfType("*context.emptyCtx"), uid, "000000").
+			Return(nil, mockErr)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"code": "000000",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/mfa/recovery-codes", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+type revokeSessionReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// RevokeSessionFromAlert 使用新设备登录提醒邮件中的令牌撤销该次登录的会话，无需登录
+func (h *Handler) RevokeSessionFromAlert(c *gin.Context) {
+	var req revokeSessionReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if err := h.LoginAlertService.RevokeSession(ctx, req.Token); err != nil {
+		log.Printf("撤销会话失败：%v\n", err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "该设备已退出登录，请尽快修改密码",
+	})
+}
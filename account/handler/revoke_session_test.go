@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRevokeSessionFromAlert(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockLoginAlertService := new(mocks.MockLoginAlertService)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:                 router,
+		LoginAlertService: mockLoginAlertService,
+	})
+
+	t.Run("成功", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockLoginAlertService.
+			On("RevokeSession", mock.AnythingOfType("*context.emptyCtx"), "aValidToken").
+			Return(nil)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"token": "aValidToken",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/sessions/revoke", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"message": "该设备已退出登录，请尽快修改密码",
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("令牌无效", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockErr := apperrors.NewAuthorization("令牌无效、已过期或已被使用")
+		mockLoginAlertService.
+			On("RevokeSession", mock.AnythingOfType("*context.emptyCtx"), "anInvalidToken").
+			Return(mockErr)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"token": "anInvalidToken",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/sessions/revoke", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockErr,
+		})
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("缺少令牌", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		request, _ := http.NewRequest(http.MethodPost, "/sessions/revoke", bytes.NewBufferString("{}"))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockLoginAlertService.AssertNumberOfCalls(t, "RevokeSession", 2)
+	})
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// UserRoles 列出某个用户的角色及其权限
+func (h *Handler) UserRoles(c *gin.Context) {
+	uid, err := uuid.Parse(c.Param("uid"))
+	if err != nil {
+		err := apperrors.NewBadRequest("无效的 uid")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	roles, err := h.RoleService.ListUserRoles(ctx, uid)
+
+	if err != nil {
+		log.Printf("无法获取用户 %v 的角色：%v\n", uid, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"roles": roles,
+	})
+}
+
+// AssignRole 为某个用户分配角色，用户下一次获取 ID 令牌时生效
+func (h *Handler) AssignRole(c *gin.Context) {
+	uid, err := uuid.Parse(c.Param("uid"))
+	if err != nil {
+		err := apperrors.NewBadRequest("无效的 uid")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	role := c.Param("role")
+
+	ctx := c.Request.Context()
+
+	if err := h.RoleService.AssignRole(ctx, actorUID(c), uid, role); err != nil {
+		log.Printf("无法为用户 %v 分配角色 %v：%v\n", uid, role, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "角色已分配",
+	})
+}
+
+// UnassignRole 移除某个用户的角色，用户已签发的 ID 令牌在过期前仍包含该角色
+func (h *Handler) UnassignRole(c *gin.Context) {
+	uid, err := uuid.Parse(c.Param("uid"))
+	if err != nil {
+		err := apperrors.NewBadRequest("无效的 uid")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	role := c.Param("role")
+
+	ctx := c.Request.Context()
+
+	if err := h.RoleService.UnassignRole(ctx, actorUID(c), uid, role); err != nil {
+		log.Printf("无法移除用户 %v 的角色 %v：%v\n", uid, role, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "角色已移除",
+	})
+}
+
+// actorUID 返回执行管理操作的用户，使用管理令牌调用时没有用户，返回 uuid.Nil
+func actorUID(c *gin.Context) uuid.UUID {
+	if u, ok := c.Get("user"); ok {
+		return u.(*model.User).UID
+	}
+
+	return uuid.Nil
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	adminUID, _ := uuid.NewRandom()
+
+	admin := &model.User{
+		UID:         adminUID,
+		Roles:       []string{"admin"},
+		Permissions: []string{model.PermissionRolesRead, model.PermissionRolesManage, model.PermissionUsersUnlock},
+	}
+	support := &model.User{
+		Roles:       []string{"support"},
+		Permissions: []string{model.PermissionRolesRead},
+	}
+
+	newRouter := func(user *model.User, roleService model.RoleService, userService model.UserService) *gin.Engine {
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", user)
+		})
+
+		NewHandler(&Config{
+			R:           router,
+			RoleService: roleService,
+			UserService: userService,
+			AdminToken:  "anAdminToken",
+		})
+
+		return router
+	}
+
+	t.Run("列出用户的角色", func(t *testing.T) {
+		mockRoles := []*model.Role{
+			{Name: "support", Description: "客服", Permissions: []string{model.PermissionRolesRead}},
+		}
+
+		mockRoleService := new(mocks.MockRoleService)
+		mockRoleService.On("ListUserRoles", mock.AnythingOfType("*context.emptyCtx"), uid).Return(mockRoles, nil)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/users/"+uid.String()+"/roles", http.NoBody)
+
+		newRouter(support, mockRoleService, nil).ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"roles": mockRoles,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("分配角色", func(t *testing.T) {
+		mockRoleService := new(mocks.MockRoleService)
+		mockRoleService.On("AssignRole", mock.AnythingOfType("*context.emptyCtx"), adminUID, uid, "support").Return(nil)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPut, "/users/"+uid.String()+"/roles/support", http.NoBody)
+
+		newRouter(admin, mockRoleService, nil).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockRoleService.AssertExpectations(t)
+	})
+
+	t.Run("没有 roles:manage 权限", func(t *testing.T) {
+		mockRoleService := new(mocks.MockRoleService)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPut, "/users/"+uid.String()+"/roles/admin", http.NoBody)
+
+		newRouter(support, mockRoleService, nil).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		mockRoleService.AssertNotCalled(t, "AssignRole", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("角色不存在", func(t *testing.T) {
+		mockErr := apperrors.NewNotFound("role", "root")
+		mockRoleService := new(mocks.MockRoleService)
+		mockRoleService.On("AssignRole", mock.AnythingOfType("*context.emptyCtx"), adminUID, uid, "root").Return(mockErr)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPut, "/users/"+uid.String()+"/roles/root", http.NoBody)
+
+		newRouter(admin, mockRoleService, nil).ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockErr,
+		})
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("移除角色", func(t *testing.T) {
+		mockRoleService := new(mocks.MockRoleService)
+		mockRoleService.On("UnassignRole", mock.AnythingOfType("*context.emptyCtx"), adminUID, uid, "support").Return(nil)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodDelete, "/users/"+uid.String()+"/roles/support", http.NoBody)
+
+		newRouter(admin, mockRoleService, nil).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockRoleService.AssertExpectations(t)
+	})
+
+	t.Run("使用管理令牌分配角色", func(t *testing.T) {
+		mockRoleService := new(mocks.MockRoleService)
+		// 使用管理令牌时没有执行操作的用户
+		mockRoleService.On("AssignRole", mock.AnythingOfType("*context.emptyCtx"), uuid.Nil, uid, "admin").Return(nil)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPut, "/admin/users/"+uid.String()+"/roles/admin", http.NoBody)
+		request.Header.Set("Authorization", "Bearer anAdminToken")
+
+		newRouter(&model.User{}, mockRoleService, nil).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockRoleService.AssertExpectations(t)
+	})
+
+	t.Run("按权限解除账号锁定", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("ClearLockout", mock.AnythingOfType("*context.emptyCtx"), uid).Return(nil)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodDelete, "/users/"+uid.String()+"/lockout", http.NoBody)
+
+		newRouter(admin, nil, mockUserService).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		rr = httptest.NewRecorder()
+		newRouter(support, nil, mockUserService).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		mockUserService.AssertNumberOfCalls(t, "ClearLockout", 1)
+	})
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+// Sessions 列出当前用户所有已登录的设备
+func (h *Handler) Sessions(c *gin.Context) {
+	user, exists := c.Get("user")
+
+	if !exists {
+		log.Printf("由于未知原因，无法从请求环境中提取用户：%v\n", c)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	uid := user.(*model.User).UID
+
+	ctx := c.Request.Context()
+	sessions, err := h.TokenService.ListSessions(ctx, uid)
+
+	if err != nil {
+		log.Printf("无法获取用户 %v 的会话：%v\n", uid, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+	})
+}
+
+// DeleteSession 撤销当前用户的某个会话，使对应设备退出登录
+func (h *Handler) DeleteSession(c *gin.Context) {
+	user, exists := c.Get("user")
+
+	if !exists {
+		log.Printf("由于未知原因，无法从请求环境中提取用户：%v\n", c)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	uid := user.(*model.User).UID
+	sessionID := c.Param("id")
+
+	ctx := c.Request.Context()
+
+	if err := h.TokenService.RevokeSession(ctx, uid, sessionID); err != nil {
+		log.Printf("无法撤销用户 %v 的会话 %v：%v\n", uid, sessionID, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "会话已撤销",
+	})
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("成功", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockSessions := []*model.TokenFamily{
+			{
+				ID:              "a_familyID",
+				UserAgent:       "Mozilla/5.0",
+				IP:              "127.0.0.1",
+				CreatedAt:       time.Unix(1626000000, 0),
+				LastRefreshedAt: time.Unix(1626100000, 0),
+			},
+		}
+
+		mockTokenService := new(mocks.MockTokenService)
+		mockTokenService.On("ListSessions", mock.AnythingOfType("*context.emptyCtx"), uid).Return(mockSessions, nil)
+
+		rr := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &model.User{
+				UID: uid,
+			})
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			TokenService: mockTokenService,
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/sessions", http.NoBody)
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"sessions": mockSessions,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("上下文中没有 User", func(t *testing.T) {
+		mockTokenService := new(mocks.MockTokenService)
+
+		rr := httptest.NewRecorder()
+
+		router := gin.Default()
+		NewHandler(&Config{
+			R:            router,
+			TokenService: mockTokenService,
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/sessions", http.NoBody)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockTokenService.AssertNotCalled(t, "ListSessions", mock.Anything, mock.Anything)
+	})
+}
+
+func TestDeleteSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("成功", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		sessionID := "a_familyID"
+
+		mockTokenService := new(mocks.MockTokenService)
+		mockTokenService.On("RevokeSession", mock.AnythingOfType("*context.emptyCtx"), uid, sessionID).Return(nil)
+
+		rr := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &model.User{
+				UID: uid,
+			})
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			TokenService: mockTokenService,
+		})
+
+		request, _ := http.NewRequest(http.MethodDelete, "/sessions/"+sessionID, http.NoBody)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("会话不存在", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		sessionID := "an_unknown_familyID"
+		mockError := apperrors.NewNotFound("session", sessionID)
+
+		mockTokenService := new(mocks.MockTokenService)
+		mockTokenService.On("RevokeSession", mock.AnythingOfType("*context.emptyCtx"), uid, sessionID).Return(mockError)
+
+		rr := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &model.User{
+				UID: uid,
+			})
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			TokenService: mockTokenService,
+		})
+
+		request, _ := http.NewRequest(http.MethodDelete, "/sessions/"+sessionID, http.NoBody)
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertExpectations(t)
+	})
+}
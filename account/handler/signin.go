@@ -11,7 +11,7 @@ import (
 
 type signinReq struct {
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,gte=6,lte=30"`
+	Password string `json:"password" binding:"required,lte=1024"`
 }
 
 func (h *Handler) Signin(c *gin.Context) {
@@ -36,7 +36,36 @@ func (h *Handler) Signin(c *gin.Context) {
 		return
 	}
 
-	tokens, err := h.TokenService.NewPairFromUser(ctx, u, "")
+	if h.denyUnverifiedEmail(c, u) {
+		// 此时无法调用需要登录的 /verify-email/resend，密码正确即重新发送验证邮件
+		if h.allowSigninVerificationEmail(c, u) {
+			if err := h.UserService.SendEmailVerification(ctx, u); err != nil {
+				log.Printf("无法向用户 %v 发送验证邮件：%v\n", u.UID, err.Error())
+			}
+		}
+		return
+	}
+
+	// 启用了 TOTP 时先返回 MFA 令牌，提交验证码至 /signin/mfa 后才签发令牌
+	if u.TOTPEnabled {
+		mfaToken, err := h.UserService.NewMFAChallenge(ctx, u)
+		if err != nil {
+			log.Printf("无法为用户 %v 创建 MFA 令牌：%v\n", u.UID, err.Error())
+
+			c.JSON(apperrors.Status(err), gin.H{
+				"error": err,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"mfaRequired": true,
+			"mfaToken":    mfaToken,
+		})
+		return
+	}
+
+	tokens, err := h.TokenService.NewPairFromUser(ctx, u, "", clientInfo(c))
 	if err != nil {
 		log.Printf("创建用户令牌失败：%v\n", err.Error())
 
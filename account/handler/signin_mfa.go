@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+type signinMFAReq struct {
+	MFAToken string `json:"mfaToken" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// SigninMFA 使用 /signin 返回的 MFA 令牌与验证码（或恢复码）完成登录
+func (h *Handler) SigninMFA(c *gin.Context) {
+	var req signinMFAReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	u, err := h.UserService.VerifyMFAChallenge(ctx, req.MFAToken, req.Code)
+
+	if err != nil {
+		log.Printf("MFA 验证失败：%v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	tokens, err := h.TokenService.NewPairFromUser(ctx, u, "", clientInfo(c))
+	if err != nil {
+		log.Printf("创建用户令牌失败：%v\n", err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+	})
+}
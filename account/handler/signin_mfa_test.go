@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSigninMFA(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockUserService := new(mocks.MockUserService)
+	mockTokenService := new(mocks.MockTokenService)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:            router,
+		UserService:  mockUserService,
+		TokenService: mockTokenService,
+	})
+
+	uid, _ := uuid.NewRandom()
+	mockUser := &model.User{
+		UID:         uid,
+		Email:       "bob@bob.com",
+		TOTPEnabled: true,
+	}
+
+	t.Run("签发令牌", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockTokenPair := &model.TokenPair{
+			IDToken:      "idToken",
+			RefreshToken: "refreshToken",
+		}
+
+		mockUserService.
+			On("VerifyMFAChallenge", mock.AnythingOfType("*context.emptyCtx"), "aMFAToken", "123456").
+			Return(mockUser, nil)
+		mockTokenService.
+			On("NewPairFromUser", mock.AnythingOfType("*context.emptyCtx"), mockUser, "", mock.AnythingOfType("*model.ClientInfo")).
+			Return(mockTokenPair, nil)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"mfaToken": "aMFAToken",
+			"code":     "123456",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/signin/mfa", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"tokens": mockTokenPair,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("验证码错误", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockErr := apperrors.NewAuthorization("验证码错误")
+		mockUserService.
+			On("VerifyMFAChallenge", mock.AnythingOfType("*context.emptyCtx"), "aMFAToken", "000000").
+			Return(nil, mockErr)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"mfaToken": "aMFAToken",
+			"code":     "000000",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/signin/mfa", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockTokenService.AssertNumberOfCalls(t, "NewPairFromUser", 1)
+	})
+}
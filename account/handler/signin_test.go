@@ -101,6 +101,7 @@ func TestSignin(t *testing.T) {
 				Password: password,
 			},
 			"",
+			mock.AnythingOfType("*model.ClientInfo"),
 		}
 		mockTokenPair := &model.TokenPair{
 			IDToken:      "idToken",
@@ -153,6 +154,7 @@ func TestSignin(t *testing.T) {
 				Password: password,
 			},
 			"",
+			mock.AnythingOfType("*model.ClientInfo"),
 		}
 		mockError := apperrors.NewInternal()
 
@@ -183,4 +185,49 @@ func TestSignin(t *testing.T) {
 		mockUserService.AssertCalled(t, "Signin", mockUSArgs...)
 		mockTokenService.AssertCalled(t, "NewPairFromUser", mockTSArgs...)
 	})
+
+	t.Run("启用 TOTP 时返回 MFA 令牌", func(t *testing.T) {
+		email := "totp@test.com"
+		password := "testpassword"
+
+		mockUSArgs := mock.Arguments{
+			mock.AnythingOfType("*context.emptyCtx"),
+			&model.User{Email: email, Password: password},
+		}
+
+		mockUserService.
+			On("Signin", mockUSArgs...).
+			Run(func(args mock.Arguments) {
+				u := args.Get(1).(*model.User)
+				u.TOTPEnabled = true
+			}).
+			Return(nil)
+		mockUserService.
+			On("NewMFAChallenge", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.User")).
+			Return("aMFAToken", nil)
+
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"email":    email,
+			"password": password,
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"mfaRequired": true,
+			"mfaToken":    "aMFAToken",
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser", mock.AnythingOfType("*context.emptyCtx"), &model.User{Email: email, Password: password, TOTPEnabled: true}, "", mock.AnythingOfType("*model.ClientInfo"))
+	})
 }
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+type signoutReq struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// Signout 撤销当前会话的刷新令牌，?all=true 时撤销该用户的所有会话
+func (h *Handler) Signout(c *gin.Context) {
+	user, exists := c.Get("user")
+
+	if !exists {
+		log.Printf("由于未知原因，无法从请求环境中提取用户：%v\n", c)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	uid := user.(*model.User).UID
+	ctx := c.Request.Context()
+
+	tokenID := ""
+
+	if c.Query("all") != "true" {
+		var req signoutReq
+
+		if ok := bindData(c, &req); !ok {
+			return
+		}
+
+		refreshToken, err := h.TokenService.ValidateRefreshToken(req.RefreshToken)
+
+		if err != nil {
+			c.JSON(apperrors.Status(err), gin.H{
+				"error": err,
+			})
+			return
+		}
+
+		if refreshToken.UID != uid {
+			log.Printf("刷新令牌不属于当前用户，uid：%v，令牌 uid：%v\n", uid, refreshToken.UID)
+			err := apperrors.NewAuthorization("无效的刷新令牌")
+			c.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			return
+		}
+
+		tokenID = refreshToken.ID.String()
+	}
+
+	if err := h.TokenService.Signout(ctx, uid, tokenID); err != nil {
+		log.Printf("用户 %v 退出登录失败：%v\n", uid, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "已成功退出登录",
+	})
+}
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSignout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	t.Run("退出当前会话", func(t *testing.T) {
+		refreshTokenString := "validRefreshToken"
+		mockRefreshToken := &model.RefreshToken{
+			SS:  refreshTokenString,
+			ID:  uuid.New(),
+			UID: uid,
+		}
+
+		mockTokenService := new(mocks.MockTokenService)
+		mockTokenService.On("ValidateRefreshToken", refreshTokenString).Return(mockRefreshToken, nil)
+		mockTokenService.On("Signout", mock.AnythingOfType("*context.emptyCtx"), uid, mockRefreshToken.ID.String()).Return(nil)
+
+		rr := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &model.User{
+				UID: uid,
+			})
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			TokenService: mockTokenService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"refreshToken": refreshTokenString,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/signout", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("退出所有会话", func(t *testing.T) {
+		mockTokenService := new(mocks.MockTokenService)
+		mockTokenService.On("Signout", mock.AnythingOfType("*context.emptyCtx"), uid, "").Return(nil)
+
+		rr := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &model.User{
+				UID: uid,
+			})
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			TokenService: mockTokenService,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/signout?all=true", http.NoBody)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockTokenService.AssertNotCalled(t, "ValidateRefreshToken", mock.Anything)
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("缺少刷新令牌", func(t *testing.T) {
+		mockTokenService := new(mocks.MockTokenService)
+
+		rr := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &model.User{
+				UID: uid,
+			})
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			TokenService: mockTokenService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{})
+
+		request, _ := http.NewRequest(http.MethodPost, "/signout", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockTokenService.AssertNotCalled(t, "Signout", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("刷新令牌属于其他用户", func(t *testing.T) {
+		refreshTokenString := "otherUsersRefreshToken"
+		mockRefreshToken := &model.RefreshToken{
+			SS:  refreshTokenString,
+			ID:  uuid.New(),
+			UID: uuid.New(),
+		}
+
+		mockTokenService := new(mocks.MockTokenService)
+		mockTokenService.On("ValidateRefreshToken", refreshTokenString).Return(mockRefreshToken, nil)
+
+		rr := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &model.User{
+				UID: uid,
+			})
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			TokenService: mockTokenService,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"refreshToken": refreshTokenString,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/signout", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockTokenService.AssertNotCalled(t, "Signout", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("TokenService.Signout 执行失败", func(t *testing.T) {
+		mockError := apperrors.NewInternal()
+
+		mockTokenService := new(mocks.MockTokenService)
+		mockTokenService.On("Signout", mock.AnythingOfType("*context.emptyCtx"), uid, "").Return(mockError)
+
+		rr := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &model.User{
+				UID: uid,
+			})
+		})
+
+		NewHandler(&Config{
+			R:            router,
+			TokenService: mockTokenService,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/signout?all=true", http.NoBody)
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, mockError.Status(), rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertExpectations(t)
+	})
+}
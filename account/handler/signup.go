@@ -11,7 +11,7 @@ import (
 
 type signupReq struct {
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,gte=6,lte=30"`
+	Password string `json:"password" binding:"required,lte=1024"`
 }
 
 func (h *Handler) Signup(c *gin.Context) {
@@ -21,6 +21,10 @@ func (h *Handler) Signup(c *gin.Context) {
 		return
 	}
 
+	if ok := h.checkPassword(c, "Password", req.Password, req.Email); !ok {
+		return
+	}
+
 	u := &model.User{
 		Email:    req.Email,
 		Password: req.Password,
@@ -36,7 +40,15 @@ func (h *Handler) Signup(c *gin.Context) {
 		return
 	}
 
-	tokens, err := h.TokenService.NewPairFromUser(ctx, u, "")
+	// 验证邮箱前不签发令牌，用户需在验证后登录
+	if h.UnverifiedEmailPolicy == UnverifiedEmailDeny && !u.EmailVerified {
+		c.JSON(http.StatusCreated, gin.H{
+			"user": u,
+		})
+		return
+	}
+
+	tokens, err := h.TokenService.NewPairFromUser(ctx, u, "", clientInfo(c))
 
 	if err != nil {
 		log.Printf("创建用户令牌失败：%v\n", err.Error())
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/FuZhouJohn/memrizr/account/model"
@@ -122,7 +123,7 @@ func TestSignup(t *testing.T) {
 
 		reqBody, err := json.Marshal(gin.H{
 			"email":    "hello@world.com",
-			"password": "testpasswordtestpasswordtestpas",
+			"password": strings.Repeat("testpassword", 11),
 		})
 		assert.NoError(t, err)
 
@@ -137,6 +138,50 @@ func TestSignup(t *testing.T) {
 		mockUserService.AssertNotCalled(t, "Signup")
 	})
 
+	t.Run("不符合密码策略", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockPasswordPolicy := new(mocks.MockPasswordPolicy)
+		mockPasswordPolicy.
+			On("Check", "hello12345", []string{"hello@world.com"}).
+			Return([]model.PasswordViolation{
+				{Tag: "strength", Param: "2"},
+				{Tag: "breached"},
+			})
+
+		rr := httptest.NewRecorder()
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			R:              router,
+			UserService:    mockUserService,
+			PasswordPolicy: mockPasswordPolicy,
+		})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"email":    "hello@world.com",
+			"password": "hello12345",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/signup", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		// invalidArgs 中不回显密码
+		respBody, _ := json.Marshal(gin.H{
+			"error": apperrors.NewBadRequest("无效的请求参数，详情见 invalidArgs"),
+			"invalidArgs": []invalidArgument{
+				{Field: "Password", Tag: "strength", Param: "2"},
+				{Field: "Password", Tag: "breached"},
+			},
+		})
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertNotCalled(t, "Signup")
+	})
+
 	t.Run("调用 UserService 出错", func(t *testing.T) {
 		u := &model.User{
 			Email:    "hello@world.com",
@@ -187,7 +232,7 @@ func TestSignup(t *testing.T) {
 		mockTokenService := new(mocks.MockTokenService)
 
 		mockUserService.On("Signup", mock.AnythingOfType("*context.emptyCtx"), u).Return(nil)
-		mockTokenService.On("NewPairFromUser", mock.AnythingOfType("*context.emptyCtx"), u, "").Return(mockTokenResp, nil)
+		mockTokenService.On("NewPairFromUser", mock.AnythingOfType("*context.emptyCtx"), u, "", mock.AnythingOfType("*model.ClientInfo")).Return(mockTokenResp, nil)
 
 		rr := httptest.NewRecorder()
 
@@ -235,7 +280,7 @@ func TestSignup(t *testing.T) {
 		mockTokenService := new(mocks.MockTokenService)
 
 		mockUserService.On("Signup", mock.AnythingOfType("*context.emptyCtx"), u).Return(nil)
-		mockTokenService.On("NewPairFromUser", mock.AnythingOfType("*context.emptyCtx"), u, "").Return(nil, mockErrorResponse)
+		mockTokenService.On("NewPairFromUser", mock.AnythingOfType("*context.emptyCtx"), u, "", mock.AnythingOfType("*model.ClientInfo")).Return(nil, mockErrorResponse)
 
 		rr := httptest.NewRecorder()
 
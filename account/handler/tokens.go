@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+type tokensReq struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+func (h *Handler) Tokens(c *gin.Context) {
+	var req tokensReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	refreshToken, err := h.TokenService.ValidateRefreshToken(req.RefreshToken)
+
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	u, err := h.UserService.Get(ctx, refreshToken.UID)
+
+	if err != nil {
+		log.Printf("无法找到刷新令牌对应的用户：%v\n%v", refreshToken.UID, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if h.denyUnverifiedEmail(c, u) {
+		return
+	}
+
+	tokens, err := h.TokenService.NewPairFromUser(ctx, u, refreshToken.ID.String(), clientInfo(c))
+
+	if err != nil {
+		log.Printf("为用户 %v 创建令牌失败：%v\n", u.UID, err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+	})
+}
@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockTokenService := new(mocks.MockTokenService)
+	mockUserService := new(mocks.MockUserService)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:            router,
+		TokenService: mockTokenService,
+		UserService:  mockUserService,
+	})
+
+	t.Run("无效的请求", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(gin.H{
+			"notRefreshToken": "this key is not valid for this handler!",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/tokens", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockTokenService.AssertNotCalled(t, "ValidateRefreshToken")
+		mockUserService.AssertNotCalled(t, "Get")
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+
+	t.Run("无效的刷新令牌", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		invalidTokenString := "invalid"
+		mockErrorMessage := "authProbs"
+		mockError := apperrors.NewAuthorization(mockErrorMessage)
+
+		mockTokenService.
+			On("ValidateRefreshToken", invalidTokenString).
+			Return(nil, mockError)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"refreshToken": invalidTokenString,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/tokens", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, mockError.Status(), rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertCalled(t, "ValidateRefreshToken", invalidTokenString)
+		mockUserService.AssertNotCalled(t, "Get")
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+
+	t.Run("获取用户失败", func(t *testing.T) {
+		validTokenString := "valid"
+		mockTokenResp := &model.RefreshToken{
+			SS:  validTokenString,
+			ID:  uuid.New(),
+			UID: uuid.New(),
+		}
+
+		mockTokenService.
+			On("ValidateRefreshToken", validTokenString).
+			Return(mockTokenResp, nil)
+
+		mockError := apperrors.NewNotFound("uid", mockTokenResp.UID.String())
+		mockUserService.
+			On("Get", mock.AnythingOfType("*context.emptyCtx"), mockTokenResp.UID).
+			Return(nil, mockError)
+
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(gin.H{
+			"refreshToken": validTokenString,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/tokens", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, mockError.Status(), rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertCalled(t, "ValidateRefreshToken", validTokenString)
+		mockUserService.AssertCalled(t, "Get", mock.AnythingOfType("*context.emptyCtx"), mockTokenResp.UID)
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+
+	t.Run("创建新的令牌对", func(t *testing.T) {
+		validTokenString := "anotherValid"
+		mockTokenResp := &model.RefreshToken{
+			SS:  validTokenString,
+			ID:  uuid.New(),
+			UID: uuid.New(),
+		}
+
+		mockTokenService.
+			On("ValidateRefreshToken", validTokenString).
+			Return(mockTokenResp, nil)
+
+		mockUserResp := &model.User{
+			UID: mockTokenResp.UID,
+		}
+		getArgs := mock.Arguments{
+			mock.AnythingOfType("*context.emptyCtx"),
+			mockTokenResp.UID,
+		}
+
+		mockUserService.
+			On("Get", getArgs...).
+			Return(mockUserResp, nil)
+
+		mockNewTokenPair := &model.TokenPair{
+			IDToken:      "aNewIDToken",
+			RefreshToken: "aNewRefreshToken",
+		}
+
+		newPairArgs := mock.Arguments{
+			mock.AnythingOfType("*context.emptyCtx"),
+			mockUserResp,
+			mockTokenResp.ID.String(),
+			mock.AnythingOfType("*model.ClientInfo"),
+		}
+
+		mockTokenService.
+			On("NewPairFromUser", newPairArgs...).
+			Return(mockNewTokenPair, nil)
+
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(gin.H{
+			"refreshToken": validTokenString,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/tokens", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"tokens": mockNewTokenPair,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertCalled(t, "ValidateRefreshToken", validTokenString)
+		mockUserService.AssertCalled(t, "Get", getArgs...)
+		mockTokenService.AssertCalled(t, "NewPairFromUser", newPairArgs...)
+	})
+
+	t.Run("刷新令牌已被使用", func(t *testing.T) {
+		validTokenString := "usedValid"
+		mockTokenResp := &model.RefreshToken{
+			SS:  validTokenString,
+			ID:  uuid.New(),
+			UID: uuid.New(),
+		}
+
+		mockTokenService.
+			On("ValidateRefreshToken", validTokenString).
+			Return(mockTokenResp, nil)
+
+		mockUserResp := &model.User{
+			UID: mockTokenResp.UID,
+		}
+
+		mockUserService.
+			On("Get", mock.AnythingOfType("*context.emptyCtx"), mockTokenResp.UID).
+			Return(mockUserResp, nil)
+
+		mockError := apperrors.NewAuthorization("无效的刷新令牌")
+		newPairArgs := mock.Arguments{
+			mock.AnythingOfType("*context.emptyCtx"),
+			mockUserResp,
+			mockTokenResp.ID.String(),
+			mock.AnythingOfType("*model.ClientInfo"),
+		}
+
+		mockTokenService.
+			On("NewPairFromUser", newPairArgs...).
+			Return(nil, mockError)
+
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(gin.H{
+			"refreshToken": validTokenString,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/tokens", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertCalled(t, "NewPairFromUser", newPairArgs...)
+	})
+}
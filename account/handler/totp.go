@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+type totpCodeReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// EnrollTOTP 生成新的 TOTP 密钥，返回密钥与 otpauth:// 地址
+func (h *Handler) EnrollTOTP(c *gin.Context) {
+	authUser, exists := c.Get("user")
+
+	if !exists {
+		log.Printf("由于未知原因，无法从请求环境中提取用户：%v\n", c)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	uid := authUser.(*model.User).UID
+
+	ctx := c.Request.Context()
+	enrollment, err := h.UserService.EnrollTOTP(ctx, uid)
+
+	if err != nil {
+		log.Printf("无法为用户 %v 生成 TOTP 密钥：%v\n", uid, err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmTOTP 使用验证器中的验证码确认绑定 TOTP，同时返回恢复码
+func (h *Handler) ConfirmTOTP(c *gin.Context) {
+	authUser, exists := c.Get("user")
+
+	if !exists {
+		log.Printf("由于未知原因，无法从请求环境中提取用户：%v\n", c)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	var req totpCodeReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	uid := authUser.(*model.User).UID
+
+	ctx := c.Request.Context()
+	u, recoveryCodes, err := h.UserService.ConfirmTOTP(ctx, uid, req.Code)
+
+	if err != nil {
+		log.Printf("用户 %v 确认 TOTP 失败：%v\n", uid, err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	idToken, err := h.TokenService.NewIDToken(ctx, u)
+
+	if err != nil {
+		log.Printf("为用户 %v 创建 ID 令牌失败：%v\n", u.UID, err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	// 恢复码只在此时返回一次，需要由用户自行保存
+	c.JSON(http.StatusOK, gin.H{
+		"user":          u,
+		"idToken":       idToken,
+		"recoveryCodes": recoveryCodes,
+	})
+}
+
+// DisableTOTP 校验验证码或恢复码后停用 TOTP
+func (h *Handler) DisableTOTP(c *gin.Context) {
+	authUser, exists := c.Get("user")
+
+	if !exists {
+		log.Printf("由于未知原因，无法从请求环境中提取用户：%v\n", c)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	var req totpCodeReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	uid := authUser.(*model.User).UID
+
+	ctx := c.Request.Context()
+	u, err := h.UserService.DisableTOTP(ctx, uid, req.Code)
+
+	if err != nil {
+		log.Printf("用户 %v 停用 TOTP 失败：%v\n", uid, err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	h.respondWithUser(c, u)
+}
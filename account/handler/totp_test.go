@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestTOTP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID:   uid,
+		Email: "bob@bob.com",
+	}
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", ctxUser)
+	})
+
+	mockUserService := new(mocks.MockUserService)
+	mockTokenService := new(mocks.MockTokenService)
+
+	NewHandler(&Config{
+		R:            router,
+		UserService:  mockUserService,
+		TokenService: mockTokenService,
+	})
+
+	t.Run("绑定 TOTP", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		enrollment := &model.TOTPEnrollment{
+			Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			URI:    "otpauth://totp/Memrizr:bob@bob.com?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		}
+
+		mockUserService.
+			On("EnrollTOTP", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(enrollment, nil)
+
+		request, _ := http.NewRequest(http.MethodPost, "/mfa/totp", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(enrollment)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("确认绑定", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		enabledUser := &model.User{
+			UID:         uid,
+			Email:       "bob@bob.com",
+			TOTPEnabled: true,
+		}
+
+		recoveryCodes := []string{"abcde-fgh23", "ijklm-nop45"}
+
+		mockUserService.
+			On("ConfirmTOTP", mock.AnythingOfType("*context.emptyCtx"), uid, "123456").
+			Return(enabledUser, recoveryCodes, nil)
+		mockTokenService.
+			On("NewIDToken", mock.AnythingOfType("*context.emptyCtx"), enabledUser).
+			Return("aNewIDToken", nil)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"code": "123456",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/mfa/totp/confirm", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"user":          enabledUser,
+			"idToken":       "aNewIDToken",
+			"recoveryCodes": recoveryCodes,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("缺少验证码", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(gin.H{})
+
+		request, _ := http.NewRequest(http.MethodPost, "/mfa/totp/confirm", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("停用时验证码错误", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockErr := apperrors.NewAuthorization("验证码错误")
+		mockUserService.
+			On("DisableTOTP", mock.AnythingOfType("*context.emptyCtx"), uid, "000000").
+			Return(nil, mockErr)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"code": "000000",
+		})
+
+		request, _ := http.NewRequest(http.MethodDelete, "/mfa/totp", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockErr,
+		})
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+}
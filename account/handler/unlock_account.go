@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+type unlockAccountReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// UnlockAccount 使用解除锁定邮件中的令牌解除账号锁定
+func (h *Handler) UnlockAccount(c *gin.Context) {
+	var req unlockAccountReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if err := h.UserService.UnlockAccount(ctx, req.Token); err != nil {
+		log.Printf("解除账号锁定失败：%v\n", err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "账号已解除锁定，请重新登录",
+	})
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+// signinVerificationEmailInterval 内 deny 策略下的登录最多重新发送一封验证邮件
+const signinVerificationEmailInterval = 10 * time.Minute
+
+type verifyEmailReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// VerifyEmail 使用验证邮件中的令牌验证邮箱。该接口无需登录，因此不返回用户资料与 ID 令牌，
+// 客户端需要通过 /tokens 刷新令牌以获取新的 email_verified
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	var req verifyEmailReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.UserService.VerifyEmail(ctx, req.Token); err != nil {
+		log.Printf("验证邮箱失败：%v\n", err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "邮箱已验证",
+	})
+}
+
+// ResendVerificationEmail 重新向当前用户发送验证邮件
+func (h *Handler) ResendVerificationEmail(c *gin.Context) {
+	authUser, exists := c.Get("user")
+
+	if !exists {
+		log.Printf("由于未知原因，无法从请求环境中提取用户：%v\n", c)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	uid := authUser.(*model.User).UID
+
+	ctx := c.Request.Context()
+	u, err := h.UserService.Get(ctx, uid)
+
+	if err != nil {
+		log.Printf("无法找到用户:%v\n%v", uid, err)
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if err := h.UserService.SendEmailVerification(ctx, u); err != nil {
+		log.Printf("无法向用户 %v 发送验证邮件：%v\n", uid, err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "验证邮件已发送",
+	})
+}
+
+// denyUnverifiedEmail 在 UnverifiedEmailDeny 策略下拒绝为邮箱未验证的用户签发令牌，返回是否已拒绝
+func (h *Handler) denyUnverifiedEmail(c *gin.Context, u *model.User) bool {
+	if h.UnverifiedEmailPolicy != UnverifiedEmailDeny || u.EmailVerified {
+		return false
+	}
+
+	err := apperrors.NewForbidden("请先验证邮箱")
+	c.JSON(err.Status(), gin.H{
+		"error": err,
+	})
+
+	return true
+}
+
+// allowSigninVerificationEmail 限制 deny 策略下登录时重新发送验证邮件的频率，
+// 避免每次密码正确的登录都发送邮件。未设置 RateLimitRepository 或计数失败时不限制
+func (h *Handler) allowSigninVerificationEmail(c *gin.Context, u *model.User) bool {
+	if h.RateLimitRepository == nil {
+		return true
+	}
+
+	key := fmt.Sprintf("signin_verification_email:%s", u.UID)
+	wait, err := h.RateLimitRepository.Hit(c.Request.Context(), key, 1, signinVerificationEmailInterval)
+	if err != nil {
+		log.Printf("无法记录用户 %v 的验证邮件发送次数：%v\n", u.UID, err)
+		return true
+	}
+
+	return wait == 0
+}
@@ -0,0 +1,366 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestVerifyEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockUserService := new(mocks.MockUserService)
+	mockTokenService := new(mocks.MockTokenService)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:            router,
+		UserService:  mockUserService,
+		TokenService: mockTokenService,
+	})
+
+	t.Run("成功", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		uid, _ := uuid.NewRandom()
+		verifiedUser := &model.User{
+			UID:           uid,
+			Email:         "bob@bob.com",
+			EmailVerified: true,
+		}
+
+		mockUserService.
+			On("VerifyEmail", mock.AnythingOfType("*context.emptyCtx"), "aValidToken").
+			Return(verifiedUser, nil)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"token": "aValidToken",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/verify-email", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"message": "邮箱已验证",
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		// 无需登录的接口不签发 ID 令牌
+		mockTokenService.AssertNotCalled(t, "NewIDToken", mock.Anything, mock.Anything)
+	})
+
+	t.Run("缺少令牌", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(gin.H{})
+
+		request, _ := http.NewRequest(http.MethodPost, "/verify-email", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNumberOfCalls(t, "VerifyEmail", 1)
+	})
+
+	t.Run("令牌无效", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockError := apperrors.NewAuthorization("无效的邮箱验证令牌")
+		mockUserService.
+			On("VerifyEmail", mock.AnythingOfType("*context.emptyCtx"), "anInvalidToken").
+			Return(nil, mockError)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"token": "anInvalidToken",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/verify-email", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+}
+
+func TestResendVerificationEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID: uid,
+	}
+
+	mockUserService := new(mocks.MockUserService)
+	mockTokenService := new(mocks.MockTokenService)
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", ctxUser)
+	})
+
+	NewHandler(&Config{
+		R:            router,
+		UserService:  mockUserService,
+		TokenService: mockTokenService,
+	})
+
+	t.Run("成功", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockUser := &model.User{
+			UID:   uid,
+			Email: "bob@bob.com",
+		}
+
+		mockUserService.
+			On("Get", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(mockUser, nil).Once()
+		mockUserService.
+			On("SendEmailVerification", mock.AnythingOfType("*context.emptyCtx"), mockUser).
+			Return(nil)
+
+		request, _ := http.NewRequest(http.MethodPost, "/verify-email/resend", nil)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserService.AssertCalled(t, "SendEmailVerification", mock.AnythingOfType("*context.emptyCtx"), mockUser)
+	})
+
+	t.Run("邮箱已验证", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockUser := &model.User{
+			UID:           uid,
+			Email:         "verified@bob.com",
+			EmailVerified: true,
+		}
+		mockError := apperrors.NewConflict("verified email", mockUser.Email)
+
+		mockUserService.
+			On("Get", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(mockUser, nil).Once()
+		mockUserService.
+			On("SendEmailVerification", mock.AnythingOfType("*context.emptyCtx"), mockUser).
+			Return(mockError)
+
+		request, _ := http.NewRequest(http.MethodPost, "/verify-email/resend", nil)
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+}
+
+func TestUnverifiedEmailPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	email := "unverified@bob.com"
+	password := "testpassword"
+
+	t.Run("deny 策略下注册不签发令牌", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		router := gin.Default()
+		NewHandler(&Config{
+			R:                     router,
+			UserService:           mockUserService,
+			TokenService:          mockTokenService,
+			UnverifiedEmailPolicy: UnverifiedEmailDeny,
+		})
+
+		u := &model.User{
+			Email:    email,
+			Password: password,
+		}
+
+		mockUserService.
+			On("Signup", mock.AnythingOfType("*context.emptyCtx"), u).
+			Return(nil)
+
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(gin.H{
+			"email":    email,
+			"password": password,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/signup", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"user": u,
+		})
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("deny 策略下拒绝登录并重新发送验证邮件", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		router := gin.Default()
+		NewHandler(&Config{
+			R:                     router,
+			UserService:           mockUserService,
+			TokenService:          mockTokenService,
+			UnverifiedEmailPolicy: UnverifiedEmailDeny,
+		})
+
+		u := &model.User{
+			Email:    email,
+			Password: password,
+		}
+
+		mockUserService.
+			On("Signin", mock.AnythingOfType("*context.emptyCtx"), u).
+			Return(nil)
+		mockUserService.
+			On("SendEmailVerification", mock.AnythingOfType("*context.emptyCtx"), u).
+			Return(nil)
+
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(gin.H{
+			"email":    email,
+			"password": password,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/signin", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"error": apperrors.NewForbidden("请先验证邮箱"),
+		})
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertCalled(t, "SendEmailVerification", mock.AnythingOfType("*context.emptyCtx"), u)
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("deny 策略下限制重新发送验证邮件的频率", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockRateLimitRepository := new(mocks.MockRateLimitRepository)
+
+		router := gin.Default()
+		NewHandler(&Config{
+			R:                     router,
+			UserService:           mockUserService,
+			TokenService:          mockTokenService,
+			RateLimitRepository:   mockRateLimitRepository,
+			UnverifiedEmailPolicy: UnverifiedEmailDeny,
+		})
+
+		uid, _ := uuid.NewRandom()
+		u := &model.User{
+			Email:    email,
+			Password: password,
+		}
+
+		mockUserService.
+			On("Signin", mock.AnythingOfType("*context.emptyCtx"), u).
+			Run(func(args mock.Arguments) {
+				args.Get(1).(*model.User).UID = uid
+			}).
+			Return(nil)
+		mockUserService.
+			On("SendEmailVerification", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.User")).
+			Return(nil)
+
+		hitArgs := mock.Arguments{
+			mock.AnythingOfType("*context.emptyCtx"),
+			"signin_verification_email:" + uid.String(),
+			1,
+			signinVerificationEmailInterval,
+		}
+		mockRateLimitRepository.On("Hit", hitArgs...).Return(time.Duration(0), nil).Once()
+		mockRateLimitRepository.On("Hit", hitArgs...).Return(5*time.Minute, nil)
+
+		for i := 0; i < 2; i++ {
+			rr := httptest.NewRecorder()
+
+			reqBody, _ := json.Marshal(gin.H{
+				"email":    email,
+				"password": password,
+			})
+
+			request, _ := http.NewRequest(http.MethodPost, "/signin", bytes.NewBuffer(reqBody))
+			request.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, http.StatusForbidden, rr.Code)
+		}
+
+		// 第二次登录仍被拒绝，但不再发送邮件
+		mockRateLimitRepository.AssertNumberOfCalls(t, "Hit", 2)
+		mockUserService.AssertNumberOfCalls(t, "SendEmailVerification", 1)
+	})
+
+	t.Run("restrict 策略下无法上传头像", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		uid, _ := uuid.NewRandom()
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &model.User{
+				UID: uid,
+			})
+		})
+
+		NewHandler(&Config{
+			R:                     router,
+			UserService:           mockUserService,
+			TokenService:          mockTokenService,
+			MaxBodyBytes:          4 * 1024,
+			UnverifiedEmailPolicy: UnverifiedEmailRestrict,
+		})
+
+		rr := httptest.NewRecorder()
+
+		request := multipartImageRequest(t, http.MethodPost, "imageFile", []byte("content"))
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		mockUserService.AssertNotCalled(t, "SetProfileImage", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
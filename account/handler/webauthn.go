@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+// webAuthnRegisterReq 中 credential 为 navigator.credentials.create() 返回的 PublicKeyCredential 的 JSON 序列化
+type webAuthnRegisterReq struct {
+	Name       string `json:"name" binding:"lte=64"`
+	Credential struct {
+		ID       string `json:"id" binding:"required"`
+		Response struct {
+			ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+			AttestationObject string `json:"attestationObject" binding:"required"`
+		} `json:"response"`
+	} `json:"credential"`
+}
+
+// webAuthnLoginReq 为 navigator.credentials.get() 返回的 PublicKeyCredential 的 JSON 序列化
+type webAuthnLoginReq struct {
+	ID       string `json:"id" binding:"required"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+		AuthenticatorData string `json:"authenticatorData" binding:"required"`
+		Signature         string `json:"signature" binding:"required"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// BeginWebAuthnRegistration 返回注册通行密钥所需的参数
+func (h *Handler) BeginWebAuthnRegistration(c *gin.Context) {
+	authUser, exists := c.Get("user")
+
+	if !exists {
+		log.Printf("由于未知原因，无法从请求环境中提取用户：%v\n", c)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	u := authUser.(*model.User)
+
+	ctx := c.Request.Context()
+	options, err := h.WebAuthnService.BeginRegistration(ctx, u)
+
+	if err != nil {
+		log.Printf("无法为用户 %v 开始注册通行密钥：%v\n", u.UID, err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"publicKey": options,
+	})
+}
+
+// FinishWebAuthnRegistration 校验并保存新注册的通行密钥
+func (h *Handler) FinishWebAuthnRegistration(c *gin.Context) {
+	authUser, exists := c.Get("user")
+
+	if !exists {
+		log.Printf("由于未知原因，无法从请求环境中提取用户：%v\n", c)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	var req webAuthnRegisterReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	u := authUser.(*model.User)
+
+	ctx := c.Request.Context()
+	credential, err := h.WebAuthnService.FinishRegistration(ctx, u, req.Name, &model.WebAuthnAttestation{
+		ID:                req.Credential.ID,
+		ClientDataJSON:    req.Credential.Response.ClientDataJSON,
+		AttestationObject: req.Credential.Response.AttestationObject,
+	})
+
+	if err != nil {
+		log.Printf("用户 %v 注册通行密钥失败：%v\n", u.UID, err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"credential": credential,
+	})
+}
+
+// BeginWebAuthnLogin 返回使用通行密钥登录所需的参数
+func (h *Handler) BeginWebAuthnLogin(c *gin.Context) {
+	ctx := c.Request.Context()
+	options, err := h.WebAuthnService.BeginLogin(ctx)
+
+	if err != nil {
+		log.Printf("无法开始通行密钥登录：%v\n", err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"publicKey": options,
+	})
+}
+
+// FinishWebAuthnLogin 校验通行密钥的签名并签发令牌。通行密钥已包含用户验证，不再要求 TOTP
+func (h *Handler) FinishWebAuthnLogin(c *gin.Context) {
+	var req webAuthnLoginReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	u, err := h.WebAuthnService.FinishLogin(ctx, &model.WebAuthnAssertion{
+		ID:                req.ID,
+		ClientDataJSON:    req.Response.ClientDataJSON,
+		AuthenticatorData: req.Response.AuthenticatorData,
+		Signature:         req.Response.Signature,
+		UserHandle:        req.Response.UserHandle,
+	})
+
+	if err != nil {
+		log.Printf("通行密钥登录失败：%v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if h.denyUnverifiedEmail(c, u) {
+		return
+	}
+
+	tokens, err := h.TokenService.NewPairFromUser(ctx, u, "", clientInfo(c))
+	if err != nil {
+		log.Printf("创建用户令牌失败：%v\n", err.Error())
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+	})
+}
+
+// WebAuthnCredentials 列出当前用户注册的通行密钥
+func (h *Handler) WebAuthnCredentials(c *gin.Context) {
+	authUser, exists := c.Get("user")
+
+	if !exists {
+		log.Printf("由于未知原因，无法从请求环境中提取用户：%v\n", c)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	uid := authUser.(*model.User).UID
+
+	ctx := c.Request.Context()
+	credentials, err := h.WebAuthnService.ListCredentials(ctx, uid)
+
+	if err != nil {
+		log.Printf("无法获取用户 %v 的通行密钥：%v\n", uid, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"credentials": credentials,
+	})
+}
+
+// DeleteWebAuthnCredential 删除当前用户的某个通行密钥
+func (h *Handler) DeleteWebAuthnCredential(c *gin.Context) {
+	authUser, exists := c.Get("user")
+
+	if !exists {
+		log.Printf("由于未知原因，无法从请求环境中提取用户：%v\n", c)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	uid := authUser.(*model.User).UID
+	id := c.Param("id")
+
+	ctx := c.Request.Context()
+
+	if err := h.WebAuthnService.DeleteCredential(ctx, uid, id); err != nil {
+		log.Printf("无法删除用户 %v 的通行密钥 %v：%v\n", uid, id, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "通行密钥已删除",
+	})
+}
@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWebAuthnRegistration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	ctxUser := &model.User{
+		UID:   uid,
+		Email: "bob@bob.com",
+	}
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", ctxUser)
+	})
+
+	mockUserService := new(mocks.MockUserService)
+	mockTokenService := new(mocks.MockTokenService)
+	mockWebAuthnService := new(mocks.MockWebAuthnService)
+
+	NewHandler(&Config{
+		R:               router,
+		UserService:     mockUserService,
+		TokenService:    mockTokenService,
+		WebAuthnService: mockWebAuthnService,
+	})
+
+	t.Run("开始注册", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		options := &model.WebAuthnCreationOptions{
+			Challenge: "aChallenge",
+		}
+		mockWebAuthnService.
+			On("BeginRegistration", mock.AnythingOfType("*context.emptyCtx"), ctxUser).
+			Return(options, nil)
+
+		request, _ := http.NewRequest(http.MethodPost, "/webauthn/register/begin", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"publicKey": options,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("完成注册", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		attestation := &model.WebAuthnAttestation{
+			ID:                "aCredentialID",
+			ClientDataJSON:    "aClientDataJSON",
+			AttestationObject: "anAttestationObject",
+		}
+		credential := &model.WebAuthnCredential{
+			ID:   "aCredentialID",
+			UID:  uid,
+			Name: "MacBook",
+		}
+		mockWebAuthnService.
+			On("FinishRegistration", mock.AnythingOfType("*context.emptyCtx"), ctxUser, "MacBook", attestation).
+			Return(credential, nil)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"name": "MacBook",
+			"credential": gin.H{
+				"id": "aCredentialID",
+				"response": gin.H{
+					"clientDataJSON":    "aClientDataJSON",
+					"attestationObject": "anAttestationObject",
+				},
+			},
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/webauthn/register/finish", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"credential": credential,
+		})
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("缺少凭据", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		reqBody, _ := json.Marshal(gin.H{
+			"name": "MacBook",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/webauthn/register/finish", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("列出通行密钥", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		credentials := []*model.WebAuthnCredential{
+			{ID: "aCredentialID", UID: uid, Name: "MacBook"},
+		}
+		mockWebAuthnService.
+			On("ListCredentials", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(credentials, nil)
+
+		request, _ := http.NewRequest(http.MethodGet, "/webauthn/credentials", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"credentials": credentials,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("删除不存在的通行密钥", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockErr := apperrors.NewNotFound("credential", "missing")
+		mockWebAuthnService.
+			On("DeleteCredential", mock.AnythingOfType("*context.emptyCtx"), uid, "missing").
+			Return(mockErr)
+
+		request, _ := http.NewRequest(http.MethodDelete, "/webauthn/credentials/missing", nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestWebAuthnLogin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockUserService := new(mocks.MockUserService)
+	mockTokenService := new(mocks.MockTokenService)
+	mockWebAuthnService := new(mocks.MockWebAuthnService)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:               router,
+		UserService:     mockUserService,
+		TokenService:    mockTokenService,
+		WebAuthnService: mockWebAuthnService,
+	})
+
+	uid, _ := uuid.NewRandom()
+	mockUser := &model.User{
+		UID:         uid,
+		Email:       "bob@bob.com",
+		TOTPEnabled: true,
+	}
+
+	t.Run("开始登录", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		options := &model.WebAuthnRequestOptions{
+			Challenge: "aChallenge",
+		}
+		mockWebAuthnService.
+			On("BeginLogin", mock.AnythingOfType("*context.emptyCtx")).
+			Return(options, nil)
+
+		request, _ := http.NewRequest(http.MethodPost, "/webauthn/login/begin", nil)
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"publicKey": options,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("签发令牌，不要求 TOTP", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		assertion := &model.WebAuthnAssertion{
+			ID:                "aCredentialID",
+			ClientDataJSON:    "aClientDataJSON",
+			AuthenticatorData: "anAuthenticatorData",
+			Signature:         "aSignature",
+			UserHandle:        "aUserHandle",
+		}
+		mockTokenPair := &model.TokenPair{
+			IDToken:      "idToken",
+			RefreshToken: "refreshToken",
+		}
+
+		mockWebAuthnService.
+			On("FinishLogin", mock.AnythingOfType("*context.emptyCtx"), assertion).
+			Return(mockUser, nil)
+		mockTokenService.
+			On("NewPairFromUser", mock.AnythingOfType("*context.emptyCtx"), mockUser, "", mock.AnythingOfType("*model.ClientInfo")).
+			Return(mockTokenPair, nil)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"id": "aCredentialID",
+			"response": gin.H{
+				"clientDataJSON":    "aClientDataJSON",
+				"authenticatorData": "anAuthenticatorData",
+				"signature":         "aSignature",
+				"userHandle":        "aUserHandle",
+			},
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/webauthn/login/finish", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		respBody, _ := json.Marshal(gin.H{
+			"tokens": mockTokenPair,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertNotCalled(t, "NewMFAChallenge", mock.Anything, mock.Anything)
+	})
+
+	t.Run("签名无效", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		mockErr := apperrors.NewAuthorization("通行密钥无效")
+		mockWebAuthnService.
+			On("FinishLogin", mock.AnythingOfType("*context.emptyCtx"), mock.MatchedBy(func(a *model.WebAuthnAssertion) bool {
+				return a.Signature == "badSignature"
+			})).
+			Return(nil, mockErr)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"id": "aCredentialID",
+			"response": gin.H{
+				"clientDataJSON":    "aClientDataJSON",
+				"authenticatorData": "anAuthenticatorData",
+				"signature":         "badSignature",
+			},
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/webauthn/login/finish", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockTokenService.AssertNumberOfCalls(t, "NewPairFromUser", 1)
+	})
+}
@@ -0,0 +1,19 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS 公开用于验证 ID 令牌的公钥，其他服务可据此自行验证令牌
+func (h *Handler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.TokenService.JWKS())
+}
+
+// OpenIDConfiguration 返回签发者与 JWKS 的元数据，供其他服务以 issuer 发现验证 ID 令牌的公钥，不能用于 OIDC 登录流程
+func (h *Handler) OpenIDConfiguration(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.TokenService.OpenIDConfiguration())
+}
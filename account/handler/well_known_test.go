@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWKS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockJWKS := &model.JWKS{
+		Keys: []model.JWK{
+			{
+				Kty: "RSA",
+				Use: "sig",
+				Alg: "RS256",
+				Kid: "a_kid",
+				N:   "modulus",
+				E:   "AQAB",
+			},
+		},
+	}
+
+	mockTokenService := new(mocks.MockTokenService)
+	mockTokenService.On("JWKS").Return(mockJWKS)
+
+	rr := httptest.NewRecorder()
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:            router,
+		TokenService: mockTokenService,
+	})
+
+	request, _ := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", http.NoBody)
+
+	router.ServeHTTP(rr, request)
+
+	respBody, _ := json.Marshal(mockJWKS)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, respBody, rr.Body.Bytes())
+	assert.NotEmpty(t, rr.Header().Get("Cache-Control"))
+	mockTokenService.AssertExpectations(t)
+}
+
+func TestOpenIDConfiguration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockConfig := &model.OpenIDConfiguration{
+		Issuer:                           "http://malcorp.test/api/account",
+		JWKSURI:                          "http://malcorp.test/api/account/.well-known/jwks.json",
+		ResponseTypesSupported:           []string{"id_token"},
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: []string{"RS256"},
+		ClaimsSupported:                  []string{"sub", "iss", "aud"},
+	}
+
+	mockTokenService := new(mocks.MockTokenService)
+	mockTokenService.On("OpenIDConfiguration").Return(mockConfig)
+
+	rr := httptest.NewRecorder()
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		R:            router,
+		TokenService: mockTokenService,
+	})
+
+	request, _ := http.NewRequest(http.MethodGet, "/.well-known/openid-configuration", http.NoBody)
+
+	router.ServeHTTP(rr, request)
+
+	respBody, _ := json.Marshal(mockConfig)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, respBody, rr.Body.Bytes())
+	mockTokenService.AssertExpectations(t)
+}
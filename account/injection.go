@@ -1,31 +1,314 @@
 package main
 
 import (
+	"context"
+	"crypto"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/FuZhouJohn/memrizr/account/geoip"
 	"github.com/FuZhouJohn/memrizr/account/handler"
+	"github.com/FuZhouJohn/memrizr/account/handler/middleware"
+	"github.com/FuZhouJohn/memrizr/account/mailer"
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/passwordpolicy"
 	"github.com/FuZhouJohn/memrizr/account/repository"
 	"github.com/FuZhouJohn/memrizr/account/service"
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 )
 
-func inject(d *dataSources) (*gin.Engine, error) {
+// defaultRateLimits 限制登录、MFA 与找回密码等接口，防止暴力破解密码与验证码
+const defaultRateLimits = "/signin=ip:20/1m,email:10/15m;" +
+	"/signin/mfa=ip:20/1m,mfaToken:5/5m;" +
+	"/webauthn/login/finish=ip:20/1m;" +
+	"/password/forgot=ip:10/1h,email:3/1h;" +
+	"/password/reset=ip:20/1h;" +
+	"/unlock=ip:20/1h;" +
+	"/sessions/revoke=ip:20/1h"
+
+// inject 创建各层依赖并返回路由，后台任务（如删除过期的认证事件）在 ctx 被取消时停止
+func inject(ctx context.Context, d *dataSources) (*gin.Engine, error) {
 	log.Println("开始注入数据源")
 
 	userRepository := repository.NewUserRepository(d.DB)
 	tokenRepository := repository.NewTokenRepository(d.RedisClient)
+	oneTimeTokenRepository := repository.NewOneTimeTokenRepository(d.RedisClient)
+	recoveryCodeRepository := repository.NewRecoveryCodeRepository(d.DB)
+	webAuthnCredentialRepository := repository.NewWebAuthnCredentialRepository(d.DB)
+	rateLimitRepository := repository.NewRateLimitRepository(d.RedisClient)
+	authEventRepository := repository.NewAuthEventRepository(d.DB)
+	deviceRepository := repository.NewDeviceRepository(d.DB)
+	personalAccessTokenRepository := repository.NewPersonalAccessTokenRepository(d.DB)
+	roleRepository := repository.NewRoleRepository(d.DB)
+
+	// 图片默认保存在本地目录，IMAGE_STORAGE=s3 时保存在对象存储中
+	imageBaseURL := os.Getenv("IMAGE_BASE_URL")
+	imageDir := ""
+
+	var imageRepository model.ImageRepository
+	if d.StorageClient != nil {
+		bucketName := os.Getenv("S3_BUCKET")
+		if imageBaseURL == "" {
+			imageBaseURL = fmt.Sprintf("%s/%s", d.StorageClient.EndpointURL(), bucketName)
+		}
+
+		imageRepository = repository.NewS3ImageRepository(d.StorageClient, bucketName, imageBaseURL)
+	} else {
+		imageDir = os.Getenv("IMAGE_DIR")
+		if imageDir == "" {
+			return nil, fmt.Errorf("必须设置 IMAGE_DIR 或 IMAGE_STORAGE=s3")
+		}
+
+		if err := os.MkdirAll(imageDir, 0755); err != nil {
+			return nil, fmt.Errorf("无法创建图片目录 %v：%w", imageDir, err)
+		}
+
+		if imageBaseURL == "" {
+			imageBaseURL = os.Getenv("ACCOUNT_API_URL") + "/images"
+		}
+
+		imageRepository = repository.NewLocalImageRepository(imageDir, imageBaseURL)
+	}
+
+	// 邮箱验证令牌的签名密钥、有效期，以及验证邮件中的链接所指向的页面
+	verificationSecret := os.Getenv("EMAIL_VERIFICATION_SECRET")
+	verificationURL := os.Getenv("EMAIL_VERIFICATION_URL")
+
+	var err error
+
+	verificationExp := int64(86400)
+	if v := os.Getenv("EMAIL_VERIFICATION_EXP"); v != "" {
+		verificationExp, err = strconv.ParseInt(v, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无法将 EMAIL_VERIFICATION_EXP 转换为整数：%w", err)
+		}
+	}
+
+	if verificationSecret == "" || verificationURL == "" {
+		return nil, fmt.Errorf("必须设置 EMAIL_VERIFICATION_SECRET 与 EMAIL_VERIFICATION_URL")
+	}
+
+	// 恢复码 HMAC 摘要的密钥，更换后已生成的恢复码全部失效
+	recoveryCodeSecret := os.Getenv("RECOVERY_CODE_SECRET")
+	if recoveryCodeSecret == "" {
+		return nil, fmt.Errorf("必须设置 RECOVERY_CODE_SECRET")
+	}
+
+	// 重置密码令牌的有效期，以及重置密码邮件中的链接所指向的页面
+	resetURL := os.Getenv("PASSWORD_RESET_URL")
+
+	resetExp := int64(900)
+	if v := os.Getenv("PASSWORD_RESET_EXP"); v != "" {
+		resetExp, err = strconv.ParseInt(v, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无法将 PASSWORD_RESET_EXP 转换为整数：%w", err)
+		}
+	}
+
+	if resetURL == "" {
+		return nil, fmt.Errorf("必须设置 PASSWORD_RESET_URL")
+	}
+
+	// 验证器中显示的服务名称，以及登录时 MFA 令牌的有效期
+	totpIssuer := os.Getenv("TOTP_ISSUER")
+	if totpIssuer == "" {
+		totpIssuer = "Memrizr"
+	}
+
+	mfaChallengeExp := int64(300)
+	if v := os.Getenv("MFA_CHALLENGE_EXP"); v != "" {
+		mfaChallengeExp, err = strconv.ParseInt(v, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无法将 MFA_CHALLENGE_EXP 转换为整数：%w", err)
+		}
+	}
+
+	// 连续密码错误多少次后锁定账号，以及每次锁定的时长，锁定次数超出时使用最后一个时长
+	lockoutThreshold := 5
+	if v := os.Getenv("LOCKOUT_THRESHOLD"); v != "" {
+		lockoutThreshold, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("无法将 LOCKOUT_THRESHOLD 转换为整数：%w", err)
+		}
+	}
+
+	lockoutDurationsConfig := os.Getenv("LOCKOUT_DURATIONS")
+	if lockoutDurationsConfig == "" {
+		lockoutDurationsConfig = "1m,5m,15m,1h"
+	}
+
+	var lockoutDurations []time.Duration
+	for _, v := range strings.Split(lockoutDurationsConfig, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || d < time.Second {
+			return nil, fmt.Errorf("无效的 LOCKOUT_DURATIONS：%v", lockoutDurationsConfig)
+		}
+
+		lockoutDurations = append(lockoutDurations, d)
+	}
+
+	// 解除锁定邮件中的链接所指向的页面，以及解除锁定令牌的有效期
+	unlockURL := os.Getenv("ACCOUNT_UNLOCK_URL")
+	if unlockURL == "" {
+		return nil, fmt.Errorf("必须设置 ACCOUNT_UNLOCK_URL")
+	}
+
+	unlockExp := int64(86400)
+	if v := os.Getenv("ACCOUNT_UNLOCK_EXP"); v != "" {
+		unlockExp, err = strconv.ParseInt(v, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无法将 ACCOUNT_UNLOCK_EXP 转换为整数：%w", err)
+		}
+	}
+
+	// 新密码的长度（按字符计算）与强度要求，默认使用内置的泄露密码列表，
+	// PASSWORD_BREACHED_FILE 可指向 Have I Been Pwned 格式的 SHA-1 列表
+	passwordPolicyConfig := &passwordpolicy.Config{
+		MinStrength: 2,
+	}
+
+	for name, dst := range map[string]*int{
+		"PASSWORD_MIN_LENGTH":   &passwordPolicyConfig.MinLength,
+		"PASSWORD_MAX_LENGTH":   &passwordPolicyConfig.MaxLength,
+		"PASSWORD_MIN_STRENGTH": &passwordPolicyConfig.MinStrength,
+	} {
+		if v := os.Getenv(name); v != "" {
+			if *dst, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("无法将 %v 转换为整数：%w", name, err)
+			}
+		}
+	}
+
+	if breachedFile := os.Getenv("PASSWORD_BREACHED_FILE"); breachedFile != "" {
+		f, err := os.Open(breachedFile)
+		if err != nil {
+			return nil, fmt.Errorf("无法打开泄露密码列表 %v：%w", breachedFile, err)
+		}
+
+		passwordPolicyConfig.Breached, err = passwordpolicy.LoadBreachedHashes(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("无法读取泄露密码列表 %v：%w", breachedFile, err)
+		}
+	} else {
+		passwordPolicyConfig.Breached, err = passwordpolicy.DefaultBreachedHashes()
+		if err != nil {
+			return nil, fmt.Errorf("无法读取内置的泄露密码列表：%w", err)
+		}
+	}
+
+	passwordPolicy, err := passwordpolicy.New(passwordPolicyConfig)
+	if err != nil {
+		return nil, fmt.Errorf("无效的密码策略：%w", err)
+	}
+
+	unverifiedEmailPolicy := handler.UnverifiedEmailPolicy(os.Getenv("UNVERIFIED_EMAIL_POLICY"))
+	switch unverifiedEmailPolicy {
+	case "":
+		unverifiedEmailPolicy = handler.UnverifiedEmailAllow
+	case handler.UnverifiedEmailAllow, handler.UnverifiedEmailRestrict, handler.UnverifiedEmailDeny:
+	default:
+		return nil, fmt.Errorf("无效的 UNVERIFIED_EMAIL_POLICY：%v，可选值为 allow、restrict 与 deny", unverifiedEmailPolicy)
+	}
+
+	// 邮件默认使用中文模板，请求带有 Accept-Language 时按其选择语言
+	mail, err := mailer.NewMailer(&mailer.Config{
+		Sender:          d.MailSender,
+		From:            os.Getenv("MAIL_FROM"),
+		DefaultLanguage: os.Getenv("MAIL_DEFAULT_LANGUAGE"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("无法创建邮件服务：%w", err)
+	}
+
+	// 认证事件默认保留一年，每隔 AUDIT_RETENTION_INTERVAL 删除一次过期的记录
+	auditRetention := 365 * 24 * time.Hour
+	if v := os.Getenv("AUDIT_RETENTION"); v != "" {
+		auditRetention, err = time.ParseDuration(v)
+		if err != nil || auditRetention <= 0 {
+			return nil, fmt.Errorf("无效的 AUDIT_RETENTION：%v", v)
+		}
+	}
+
+	auditRetentionInterval := time.Hour
+	if v := os.Getenv("AUDIT_RETENTION_INTERVAL"); v != "" {
+		auditRetentionInterval, err = time.ParseDuration(v)
+		if err != nil || auditRetentionInterval <= 0 {
+			return nil, fmt.Errorf("无效的 AUDIT_RETENTION_INTERVAL：%v", v)
+		}
+	}
+
+	auditService := service.NewAuditService(&service.ASConfig{
+		AuthEventRepository: authEventRepository,
+		Retention:           auditRetention,
+	})
+
+	go service.RunAuditRetention(ctx, auditService, auditRetentionInterval)
 
 	userService := service.NewUserService(&service.USConfig{
-		UserRepository: userRepository,
+		UserRepository:             userRepository,
+		ImageRepository:            imageRepository,
+		OneTimeTokenRepository:     oneTimeTokenRepository,
+		RecoveryCodeRepository:     recoveryCodeRepository,
+		Mailer:                     mail,
+		AuditLogger:                auditService,
+		VerificationSecret:         verificationSecret,
+		RecoveryCodeSecret:         recoveryCodeSecret,
+		VerificationExpirationSecs: verificationExp,
+		VerificationURL:            verificationURL,
+		ResetExpirationSecs:        resetExp,
+		ResetURL:                   resetURL,
+		TOTPIssuer:                 totpIssuer,
+		MFAChallengeExpirationSecs: mfaChallengeExp,
+		LockoutThreshold:           lockoutThreshold,
+		LockoutDurations:           lockoutDurations,
+		UnlockExpirationSecs:       unlockExp,
+		UnlockURL:                  unlockURL,
+	})
+
+	// 新设备登录提醒中撤销会话的链接所指向的页面，以及撤销令牌的有效期
+	revokeSessionURL := os.Getenv("SESSION_REVOKE_URL")
+	if revokeSessionURL == "" {
+		return nil, fmt.Errorf("必须设置 SESSION_REVOKE_URL")
+	}
+
+	revokeSessionExp := int64(7 * 86400)
+	if v := os.Getenv("SESSION_REVOKE_EXP"); v != "" {
+		revokeSessionExp, err = strconv.ParseInt(v, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无法将 SESSION_REVOKE_EXP 转换为整数：%w", err)
+		}
+	}
+
+	// 设置 GEOIP_DB_FILE 时使用本地的 MaxMind 格式数据库确定登录地点，否则只按 User-Agent 与网段判断设备。
+	// 数据库在服务运行期间一直打开
+	var geoLocator model.GeoLocator
+	if geoIPFile := os.Getenv("GEOIP_DB_FILE"); geoIPFile != "" {
+		locator, err := geoip.Open(geoIPFile)
+		if err != nil {
+			return nil, err
+		}
+
+		geoLocator = locator
+	}
+
+	loginAlertService := service.NewLoginAlertService(&service.LASConfig{
+		DeviceRepository:       deviceRepository,
+		TokenRepository:        tokenRepository,
+		OneTimeTokenRepository: oneTimeTokenRepository,
+		Mailer:                 mail,
+		GeoLocator:             geoLocator,
+		AuditLogger:            auditService,
+		RevokeExpirationSecs:   revokeSessionExp,
+		RevokeURL:              revokeSessionURL,
 	})
 
-	// load rsa keys
+	// 加载签署 ID 令牌的密钥，支持 RSA、ECDSA P-256 与 Ed25519
 	privKeyFile := os.Getenv("PRIV_KEY_FILE")
 	priv, err := ioutil.ReadFile(privKeyFile)
 
@@ -33,65 +316,189 @@ func inject(d *dataSources) (*gin.Engine, error) {
 		return nil, fmt.Errorf("无法读取私钥 pem 文件： %w", err)
 	}
 
-	privKey, err := jwt.ParseRSAPrivateKeyFromPEM(priv)
+	privKey, err := service.ParsePrivateKeyFromPEM(priv)
 	if err != nil {
 		return nil, fmt.Errorf("无法转换私钥： %w", err)
 	}
 
-	pubKeyFile := os.Getenv("PUB_KEY_FILE")
-	pub, err := ioutil.ReadFile(pubKeyFile)
+	// 已轮换下来的公钥，在其签署的令牌过期前仍用于验证，多个文件以逗号分隔
+	var retiringKeys []crypto.PublicKey
+	for _, pubKeyFile := range strings.Split(os.Getenv("RETIRING_PUB_KEY_FILES"), ",") {
+		pubKeyFile = strings.TrimSpace(pubKeyFile)
+		if pubKeyFile == "" {
+			continue
+		}
 
+		pub, err := ioutil.ReadFile(pubKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("无法读取公钥 pem 文件 %v： %w", pubKeyFile, err)
+		}
+
+		pubKey, err := service.ParsePublicKeyFromPEM(pub)
+		if err != nil {
+			return nil, fmt.Errorf("无法转换公钥 %v： %w", pubKeyFile, err)
+		}
+
+		retiringKeys = append(retiringKeys, pubKey)
+	}
+
+	keyRing, err := service.NewKeyRing(privKey, retiringKeys...)
 	if err != nil {
-		return nil, fmt.Errorf("无法读取公钥 pem 文件： %w", err)
+		return nil, fmt.Errorf("无法创建密钥环： %w", err)
 	}
 
-	pubKey, err := jwt.ParseRSAPublicKeyFromPEM(pub)
+	// 签名算法由私钥类型决定，ID_TOKEN_SIGNING_ALG 用于确认部署的密钥与预期的算法一致
+	if alg := os.Getenv("ID_TOKEN_SIGNING_ALG"); alg != "" {
+		if _, method, _ := keyRing.SigningKey(); method.Alg() != alg {
+			return nil, fmt.Errorf("私钥的签名算法 %v 与 ID_TOKEN_SIGNING_ALG=%v 不一致", method.Alg(), alg)
+		}
+	}
 
-	if err != nil {
-		return nil, fmt.Errorf("无法转换公钥： %w", err)
+	// ID 令牌的 iss 与 aud，iss 同时是签发者元数据所在的地址
+	issuer := os.Getenv("ID_TOKEN_ISSUER")
+	audience := os.Getenv("ID_TOKEN_AUDIENCE")
+
+	if issuer == "" || audience == "" {
+		return nil, fmt.Errorf("必须设置 ID_TOKEN_ISSUER 与 ID_TOKEN_AUDIENCE")
 	}
 
 	// load refresh token secret from env variable
 	refreshSecret := os.Getenv("REFRESH_SECRET")
+	if refreshSecret == "" {
+		return nil, fmt.Errorf("必须设置 REFRESH_SECRET")
+	}
 
-	idTokenExp := os.Getenv("ID_TOKEN_EXP")
-	refreshTokenExp := os.Getenv("REFRESH_TOKEN_EXP")
-
-	idExp, err := strconv.ParseInt(idTokenExp, 0, 64)
-	if err != nil {
-		return nil, fmt.Errorf("无法将 ID_TOKEN_EXP 转换为整数：%w", err)
+	// ID 令牌默认 15 分钟过期，刷新令牌默认 3 天过期
+	idExp := int64(900)
+	if v := os.Getenv("ID_TOKEN_EXP"); v != "" {
+		idExp, err = strconv.ParseInt(v, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无法将 ID_TOKEN_EXP 转换为整数：%w", err)
+		}
 	}
 
-	refreshExp, err := strconv.ParseInt(refreshTokenExp, 0, 64)
-	if err != nil {
-		return nil, fmt.Errorf("无法将 REFRESH_TOKEN_EXP 转换为整数：%w", err)
+	refreshExp := int64(3 * 86400)
+	if v := os.Getenv("REFRESH_TOKEN_EXP"); v != "" {
+		refreshExp, err = strconv.ParseInt(v, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无法将 REFRESH_TOKEN_EXP 转换为整数：%w", err)
+		}
 	}
 
 	tokenService := service.NewTokenService(&service.TSConfig{
 		TokenRepository:       tokenRepository,
-		PrivKey:               privKey,
-		PubKey:                pubKey,
+		RoleRepository:        roleRepository,
+		KeyRing:               keyRing,
+		Issuer:                issuer,
+		Audience:              audience,
 		RefreshSecret:         refreshSecret,
 		IDExpirationSecs:      idExp,
 		RefreshExpirationSecs: refreshExp,
+		AuditLogger:           auditService,
+		LoginAlertService:     loginAlertService,
+	})
+
+	// 通行密钥绑定的域名与允许的页面来源，多个来源以逗号分隔
+	webAuthnRPID := os.Getenv("WEBAUTHN_RP_ID")
+	if webAuthnRPID == "" {
+		webAuthnRPID = "malcorp.test"
+	}
+
+	webAuthnRPName := os.Getenv("WEBAUTHN_RP_NAME")
+	if webAuthnRPName == "" {
+		webAuthnRPName = "Memrizr"
+	}
+
+	var webAuthnOrigins []string
+	for _, origin := range strings.Split(os.Getenv("WEBAUTHN_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			webAuthnOrigins = append(webAuthnOrigins, origin)
+		}
+	}
+
+	if len(webAuthnOrigins) == 0 {
+		webAuthnOrigins = []string{"http://" + webAuthnRPID}
+	}
+
+	webAuthnService := service.NewWebAuthnService(&service.WASConfig{
+		UserRepository:         userRepository,
+		CredentialRepository:   webAuthnCredentialRepository,
+		OneTimeTokenRepository: oneTimeTokenRepository,
+		RPID:                   webAuthnRPID,
+		RPName:                 webAuthnRPName,
+		RPOrigins:              webAuthnOrigins,
+		Timeout:                5 * time.Minute,
+		AuditLogger:            auditService,
+	})
+
+	personalAccessTokenService := service.NewPersonalAccessTokenService(&service.PATSConfig{
+		PersonalAccessTokenRepository: personalAccessTokenRepository,
+		UserRepository:                userRepository,
+		AuditLogger:                   auditService,
+	})
+
+	roleService := service.NewRoleService(&service.RSConfig{
+		RoleRepository: roleRepository,
+		AuditLogger:    auditService,
 	})
 
 	router := gin.Default()
 
 	baseURL := os.Getenv("ACCOUNT_API_URL")
 
-	handlerTimeout := os.Getenv("HANDLER_TIMEOUT")
-	ht, err := strconv.ParseInt(handlerTimeout, 0, 64)
+	// 处理请求的超时（秒）与请求体的大小上限，默认 5 秒与 4MB
+	ht := int64(5)
+	if v := os.Getenv("HANDLER_TIMEOUT"); v != "" {
+		ht, err = strconv.ParseInt(v, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无法将 HANDLER_TIMEOUT 转为为整数：%w", err)
+		}
+	}
+
+	mbb := int64(4 << 20)
+	if v := os.Getenv("MAX_BODY_BYTES"); v != "" {
+		mbb, err = strconv.ParseInt(v, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无法将 MAX_BODY_BYTES 转为为整数：%w", err)
+		}
+	}
+
+	// 各个路由的请求次数限制，未设置 RATE_LIMITS 时使用 defaultRateLimits
+	rateLimitsConfig := os.Getenv("RATE_LIMITS")
+	if rateLimitsConfig == "" {
+		rateLimitsConfig = defaultRateLimits
+	}
+
+	rateLimits, err := middleware.ParseRateLimits(rateLimitsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("无法解析 RATE_LIMITS：%w", err)
+	}
+
+	// 部署在反向代理之后时，TRUSTED_PROXIES 为代理的 IP 或 CIDR，以逗号分隔。未设置时不读取 X-Forwarded-For
+	trustedProxies, err := middleware.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
 	if err != nil {
-		return nil, fmt.Errorf("无法将 HANDLER_TIMEOUT 转为为整数：%w", err)
+		return nil, fmt.Errorf("无法解析 TRUSTED_PROXIES：%w", err)
 	}
 
 	handler.NewHandler(&handler.Config{
-		R:               router,
-		UserService:     userService,
-		TokenService:    tokenService,
-		BaseURL:         baseURL,
-		TimeoutDuration: time.Duration(time.Duration(ht) * time.Second),
+		R:                          router,
+		UserService:                userService,
+		TokenService:               tokenService,
+		WebAuthnService:            webAuthnService,
+		PasswordPolicy:             passwordPolicy,
+		AuditService:               auditService,
+		LoginAlertService:          loginAlertService,
+		PersonalAccessTokenService: personalAccessTokenService,
+		RoleService:                roleService,
+		BaseURL:                    baseURL,
+		TimeoutDuration:            time.Duration(time.Duration(ht) * time.Second),
+		MaxBodyBytes:               mbb,
+		ImageDir:                   imageDir,
+		UnverifiedEmailPolicy:      unverifiedEmailPolicy,
+		TrustedProxies:             trustedProxies,
+		RateLimitRepository:        rateLimitRepository,
+		RateLimits:                 rateLimits,
+		AdminToken:                 os.Getenv("ADMIN_TOKEN"),
 	})
 
 	return router, nil
@@ -0,0 +1,102 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// AsyncSender 将邮件放入队列后立即返回，由后台 worker 交给下层 Sender 投递，
+// 请求的耗时因此不受邮件服务器影响。投递失败只会写入日志
+type AsyncSender struct {
+	sender  Sender
+	timeout time.Duration
+	queue   chan *Message
+	wg      sync.WaitGroup
+	// mu 保护 closed，避免关闭队列后仍有请求写入
+	mu     sync.RWMutex
+	closed bool
+}
+
+type AsyncConfig struct {
+	Sender    Sender
+	Workers   int
+	QueueSize int
+	// 单封邮件的投递超时
+	Timeout time.Duration
+}
+
+func NewAsyncSender(c *AsyncConfig) *AsyncSender {
+	workers := c.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	s := &AsyncSender{
+		sender:  c.Sender,
+		timeout: c.Timeout,
+		queue:   make(chan *Message, c.QueueSize),
+	}
+
+	s.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go s.work()
+	}
+
+	return s
+}
+
+// Send 在队列已满或已关闭时返回错误而不是阻塞请求
+func (s *AsyncSender) Send(ctx context.Context, msg *Message) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return fmt.Errorf("邮件队列已关闭，无法发送至 %v", msg.To)
+	}
+
+	select {
+	case s.queue <- msg:
+		return nil
+	default:
+		return fmt.Errorf("邮件队列已满，无法发送至 %v", msg.To)
+	}
+}
+
+// Close 停止接收新邮件，并等待队列中的邮件投递完成
+func (s *AsyncSender) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	return nil
+}
+
+func (s *AsyncSender) work() {
+	defer s.wg.Done()
+
+	for msg := range s.queue {
+		s.deliver(msg)
+	}
+}
+
+// deliver 不使用请求的 context，请求结束后邮件仍需投递
+func (s *AsyncSender) deliver(msg *Message) {
+	ctx := context.Background()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	if err := s.sender.Send(ctx, msg); err != nil {
+		log.Printf("无法发送邮件至 %v，主题：%v，错误：%v\n", msg.To, msg.Subject, err)
+	}
+}
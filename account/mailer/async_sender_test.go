@@ -0,0 +1,69 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingSender 在 release 关闭前阻塞，用于模拟缓慢的邮件服务器
+type blockingSender struct {
+	mu      sync.Mutex
+	release chan struct{}
+	sent    []*Message
+}
+
+func (s *blockingSender) Send(ctx context.Context, msg *Message) error {
+	<-s.release
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, msg)
+
+	return nil
+}
+
+func TestAsyncSender(t *testing.T) {
+	t.Run("不等待投递，关闭时发送完队列中的邮件", func(t *testing.T) {
+		sender := &blockingSender{release: make(chan struct{})}
+		s := NewAsyncSender(&AsyncConfig{
+			Sender:    sender,
+			Workers:   1,
+			QueueSize: 10,
+		})
+
+		for i := 0; i < 5; i++ {
+			err := s.Send(context.Background(), &Message{To: fmt.Sprintf("%d@test.com", i)})
+			assert.NoError(t, err)
+		}
+
+		close(sender.release)
+		assert.NoError(t, s.Close())
+		assert.Len(t, sender.sent, 5)
+
+		err := s.Send(context.Background(), &Message{To: "zhuangjinan@test.com"})
+		assert.Error(t, err)
+	})
+
+	t.Run("队列已满", func(t *testing.T) {
+		sender := &blockingSender{release: make(chan struct{})}
+		s := NewAsyncSender(&AsyncConfig{
+			Sender:    sender,
+			Workers:   1,
+			QueueSize: 1,
+		})
+
+		var err error
+		// worker 最多取走一封，队列中最多再放一封
+		for i := 0; i < 3 && err == nil; i++ {
+			err = s.Send(context.Background(), &Message{To: "zhuangjinan@test.com"})
+		}
+		assert.Error(t, err)
+
+		close(sender.release)
+		assert.NoError(t, s.Close())
+	})
+}
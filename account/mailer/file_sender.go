@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+)
+
+// fileSender 将邮件以 .eml 文件写入目录而不投递，用于测试与没有邮件服务器的环境
+type fileSender struct {
+	Dir string
+}
+
+func NewFileSender(dir string) Sender {
+	return &fileSender{
+		Dir: dir,
+	}
+}
+
+func (s *fileSender) Send(ctx context.Context, msg *Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return fmt.Errorf("无法编码邮件：%w", err)
+	}
+
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+
+	// 文件名以时间开头，按名称排序即为发送顺序
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), hex.EncodeToString(b))
+
+	if err := ioutil.WriteFile(filepath.Join(s.Dir, name), data, 0644); err != nil {
+		return fmt.Errorf("无法写入邮件文件：%w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,271 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+)
+
+// 模板位于 templates/{语言}/{邮件类型}.subject.txt、.txt 与 .html，
+// 新增语言时需要为每种邮件类型提供全部三个文件
+//
+//go:embed templates
+var templateFS embed.FS
+
+const (
+	verifyEmailTemplate   = "verify_email"
+	resetPasswordTemplate = "reset_password"
+	unlockAccountTemplate = "unlock_account"
+	loginAlertTemplate    = "login_alert"
+)
+
+var templateNames = []string{verifyEmailTemplate, resetPasswordTemplate, unlockAccountTemplate, loginAlertTemplate}
+
+// loginAlertTimeLayout 为登录提醒中登录时间的格式，时间统一使用 UTC
+const loginAlertTimeLayout = "2006-01-02 15:04 MST"
+
+// templateData 为渲染模板时可用的字段，Login 只在登录提醒中可用
+type templateData struct {
+	Name  string
+	Email string
+	Link  string
+	Login *loginData
+}
+
+type loginData struct {
+	Device   string
+	IP       string
+	Location string
+	Time     string
+}
+
+type templateSet struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// mailer 按 context 中的语言渲染邮件，并交由 Sender 投递
+type mailer struct {
+	Sender          Sender
+	From            string
+	DefaultLanguage string
+	templates       map[string]map[string]*templateSet
+}
+
+// Config 为 mailer 的依赖与配置，DefaultLanguage 为空时使用 zh
+type Config struct {
+	Sender          Sender
+	From            string
+	DefaultLanguage string
+}
+
+// NewMailer 解析内嵌的模板并返回 model.Mailer
+func NewMailer(c *Config) (model.Mailer, error) {
+	if _, err := addressOf(c.From); err != nil {
+		return nil, fmt.Errorf("无效的发件人：%w", err)
+	}
+
+	templates, err := parseTemplates(templateFS)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultLanguage := c.DefaultLanguage
+	if defaultLanguage == "" {
+		defaultLanguage = "zh"
+	}
+
+	if _, ok := templates[defaultLanguage]; !ok {
+		return nil, fmt.Errorf("不支持的默认邮件语言：%v", defaultLanguage)
+	}
+
+	return &mailer{
+		Sender:          c.Sender,
+		From:            c.From,
+		DefaultLanguage: defaultLanguage,
+		templates:       templates,
+	}, nil
+}
+
+func (m *mailer) SendEmailVerification(ctx context.Context, u *model.User, link string) error {
+	return m.send(ctx, verifyEmailTemplate, u, link)
+}
+
+func (m *mailer) SendPasswordReset(ctx context.Context, u *model.User, link string) error {
+	return m.send(ctx, resetPasswordTemplate, u, link)
+}
+
+func (m *mailer) SendAccountUnlock(ctx context.Context, u *model.User, link string) error {
+	return m.send(ctx, unlockAccountTemplate, u, link)
+}
+
+func (m *mailer) SendLoginAlert(ctx context.Context, u *model.User, alert *model.LoginAlert, link string) error {
+	return m.sendData(ctx, loginAlertTemplate, u, &templateData{
+		Name:  u.Name,
+		Email: u.Email,
+		Link:  link,
+		Login: &loginData{
+			Device:   alert.Device,
+			IP:       alert.IP,
+			Location: alert.Location,
+			Time:     alert.Time.UTC().Format(loginAlertTimeLayout),
+		},
+	})
+}
+
+func (m *mailer) send(ctx context.Context, name string, u *model.User, link string) error {
+	return m.sendData(ctx, name, u, &templateData{
+		Name:  u.Name,
+		Email: u.Email,
+		Link:  link,
+	})
+}
+
+func (m *mailer) sendData(ctx context.Context, name string, u *model.User, data *templateData) error {
+	msg, err := m.render(model.LanguageFromContext(ctx), name, data)
+	if err != nil {
+		return err
+	}
+
+	msg.To = u.Email
+
+	return m.Sender.Send(ctx, msg)
+}
+
+func (m *mailer) render(lang string, name string, data *templateData) (*Message, error) {
+	set := m.templates[m.language(lang)][name]
+
+	var subject, text, html bytes.Buffer
+	if err := set.subject.Execute(&subject, data); err != nil {
+		return nil, fmt.Errorf("无法渲染邮件 %v：%w", name, err)
+	}
+	if err := set.text.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("无法渲染邮件 %v：%w", name, err)
+	}
+	if err := set.html.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("无法渲染邮件 %v：%w", name, err)
+	}
+
+	return &Message{
+		From:    m.From,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// language 从 Accept-Language 格式的语言列表中选出权重最高且有模板的语言，
+// 如 zh-CN 依次匹配 zh-CN 与 zh，都没有时使用默认语言
+func (m *mailer) language(accept string) string {
+	for _, tag := range parseAcceptLanguage(accept) {
+		tag = strings.ToLower(tag)
+		if _, ok := m.templates[tag]; ok {
+			return tag
+		}
+
+		if i := strings.Index(tag, "-"); i > 0 {
+			if _, ok := m.templates[tag[:i]]; ok {
+				return tag[:i]
+			}
+		}
+	}
+
+	return m.DefaultLanguage
+}
+
+// parseAcceptLanguage 按权重从高到低返回语言标签，忽略 q=0 与 *
+func parseAcceptLanguage(accept string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var langs []weighted
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+
+		if q > 0 {
+			langs = append(langs, weighted{tag, q})
+		}
+	}
+
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].q > langs[j].q
+	})
+
+	tags := make([]string, len(langs))
+	for i, l := range langs {
+		tags[i] = l.tag
+	}
+
+	return tags
+}
+
+// parseTemplates 解析 templates 下每种语言的全部模板，缺少文件时返回错误
+func parseTemplates(fsys fs.FS) (map[string]map[string]*templateSet, error) {
+	dirs, err := fs.ReadDir(fsys, "templates")
+	if err != nil {
+		return nil, fmt.Errorf("无法读取邮件模板：%w", err)
+	}
+
+	templates := make(map[string]map[string]*templateSet)
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+
+		lang := dir.Name()
+		templates[lang] = make(map[string]*templateSet)
+
+		for _, name := range templateNames {
+			prefix := fmt.Sprintf("templates/%s/%s", lang, name)
+
+			subject, err := texttemplate.ParseFS(fsys, prefix+".subject.txt")
+			if err != nil {
+				return nil, fmt.Errorf("无法解析邮件模板 %v：%w", prefix, err)
+			}
+
+			text, err := texttemplate.ParseFS(fsys, prefix+".txt")
+			if err != nil {
+				return nil, fmt.Errorf("无法解析邮件模板 %v：%w", prefix, err)
+			}
+
+			html, err := htmltemplate.ParseFS(fsys, prefix+".html")
+			if err != nil {
+				return nil, fmt.Errorf("无法解析邮件模板 %v：%w", prefix, err)
+			}
+
+			templates[lang][name] = &templateSet{
+				subject: subject,
+				text:    text,
+				html:    html,
+			}
+		}
+	}
+
+	return templates, nil
+}
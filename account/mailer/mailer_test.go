@@ -0,0 +1,161 @@
+package mailer
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// readMail 读取 fileSender 写入的唯一一封邮件，返回解码后的主题与各部分正文
+func readMail(t *testing.T, dir string) (*mail.Message, string, map[string]string) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	data, err := ioutil.ReadFile(files[0])
+	assert.NoError(t, err)
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+	assert.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	assert.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	parts := make(map[string]string)
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+
+		// multipart.Reader 会自动解码 quoted-printable
+		body, err := ioutil.ReadAll(part)
+		assert.NoError(t, err)
+
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[contentType] = string(body)
+	}
+
+	return msg, subject, parts
+}
+
+func TestMailer(t *testing.T) {
+	u := &model.User{
+		Email: "zhuangjinan@test.com",
+		Name:  "庄佳南",
+	}
+
+	newMailer := func(t *testing.T) (model.Mailer, string) {
+		dir := t.TempDir()
+
+		m, err := NewMailer(&Config{
+			Sender: NewFileSender(dir),
+			From:   "Memrizr <noreply@memrizr.test>",
+		})
+		assert.NoError(t, err)
+
+		return m, dir
+	}
+
+	t.Run("默认使用中文模板", func(t *testing.T) {
+		m, dir := newMailer(t)
+
+		link := "https://memrizr.test/verify?token=a&b"
+		err := m.SendEmailVerification(context.Background(), u, link)
+		assert.NoError(t, err)
+
+		msg, subject, parts := readMail(t, dir)
+		assert.Equal(t, "验证你的邮箱地址", subject)
+		assert.Equal(t, u.Email, msg.Header.Get("To"))
+		assert.Equal(t, "Memrizr <noreply@memrizr.test>", msg.Header.Get("From"))
+		assert.True(t, strings.HasSuffix(msg.Header.Get("Message-ID"), "@memrizr.test>"))
+
+		assert.Contains(t, parts["text/plain"], "庄佳南，你好")
+		assert.Contains(t, parts["text/plain"], link)
+		// HTML 模板会转义链接
+		assert.Contains(t, parts["text/html"], `href="https://memrizr.test/verify?token=a&amp;b"`)
+	})
+
+	t.Run("按 Accept-Language 选择语言", func(t *testing.T) {
+		m, dir := newMailer(t)
+
+		ctx := model.ContextWithLanguage(context.Background(), "fr-FR, en-US;q=0.8, zh;q=0.5")
+		err := m.SendPasswordReset(ctx, u, "https://memrizr.test/reset?token=a")
+		assert.NoError(t, err)
+
+		_, subject, parts := readMail(t, dir)
+		assert.Equal(t, "Reset your password", subject)
+		assert.Contains(t, parts["text/plain"], "Hi 庄佳南,")
+		assert.Contains(t, parts["text/html"], "https://memrizr.test/reset?token=a")
+	})
+
+	t.Run("解除锁定邮件", func(t *testing.T) {
+		m, dir := newMailer(t)
+
+		err := m.SendAccountUnlock(context.Background(), u, "https://memrizr.test/unlock?token=a")
+		assert.NoError(t, err)
+
+		_, subject, parts := readMail(t, dir)
+		assert.Equal(t, "你的账号已被暂时锁定", subject)
+		assert.Contains(t, parts["text/plain"], "https://memrizr.test/unlock?token=a")
+		assert.Contains(t, parts["text/html"], `href="https://memrizr.test/unlock?token=a"`)
+	})
+
+	t.Run("新设备登录提醒", func(t *testing.T) {
+		m, dir := newMailer(t)
+
+		alert := &model.LoginAlert{
+			Device:   "Firefox / Linux",
+			IP:       "203.0.113.7",
+			Location: "Shanghai, China",
+			Time:     time.Date(2021, 7, 11, 18, 30, 0, 0, time.FixedZone("CST", 8*3600)),
+		}
+
+		ctx := model.ContextWithLanguage(context.Background(), "en")
+		err := m.SendLoginAlert(ctx, u, alert, "https://memrizr.test/revoke-session?token=a")
+		assert.NoError(t, err)
+
+		_, subject, parts := readMail(t, dir)
+		assert.Equal(t, "New sign-in to your account", subject)
+		assert.Contains(t, parts["text/plain"], "Device: Firefox / Linux")
+		assert.Contains(t, parts["text/plain"], "IP address: 203.0.113.7 (Shanghai, China)")
+		assert.Contains(t, parts["text/plain"], "Time: 2021-07-11 10:30 UTC")
+		assert.Contains(t, parts["text/html"], `href="https://memrizr.test/revoke-session?token=a"`)
+	})
+
+	t.Run("无效的配置", func(t *testing.T) {
+		_, err := NewMailer(&Config{
+			Sender: NewLogSender(),
+			From:   "",
+		})
+		assert.Error(t, err)
+
+		_, err = NewMailer(&Config{
+			Sender:          NewLogSender(),
+			From:            "noreply@memrizr.test",
+			DefaultLanguage: "fr",
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assert.Equal(t, []string{"en", "zh-CN", "zh"}, parseAcceptLanguage("zh-CN;q=0.9, en, zh;q=0.8, *;q=0.5, fr;q=0"))
+	assert.Empty(t, parseAcceptLanguage(""))
+}
@@ -0,0 +1,24 @@
+package mailer
+
+import (
+	"context"
+	"log"
+)
+
+// Sender 负责投递渲染后的邮件
+type Sender interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// logSender 不投递邮件，仅记录收件人与主题。正文中的链接包含重置密码等令牌，
+// 任何能读取日志的人都可以借此接管账户，因此不写入日志
+type logSender struct{}
+
+func NewLogSender() Sender {
+	return &logSender{}
+}
+
+func (s *logSender) Send(ctx context.Context, msg *Message) error {
+	log.Printf("发送邮件至 %v，主题：%v\n", msg.To, msg.Subject)
+	return nil
+}
@@ -0,0 +1,30 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogSender(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	err := NewLogSender().Send(context.Background(), &Message{
+		To:      "bob@bob.com",
+		Subject: "重置密码",
+		Text:    "http://localhost:3000/reset-password?token=asecrettoken",
+		HTML:    "<a href=\"http://localhost:3000/reset-password?token=asecrettoken\">重置密码</a>",
+	})
+
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "bob@bob.com")
+	assert.Contains(t, buf.String(), "重置密码")
+	// 正文中的令牌不能出现在日志中
+	assert.NotContains(t, buf.String(), "asecrettoken")
+}
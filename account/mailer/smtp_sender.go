@@ -0,0 +1,106 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// smtpSender 通过 SMTP 投递邮件。Username 为空时不进行认证，可直接用于 MailHog 等本地邮件捕获工具
+type smtpSender struct {
+	Addr     string
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+func NewSMTPSender(c *SMTPConfig) Sender {
+	return &smtpSender{
+		Addr:     net.JoinHostPort(c.Host, c.Port),
+		Username: c.Username,
+		Password: c.Password,
+		Timeout:  c.Timeout,
+	}
+}
+
+func (s *smtpSender) Send(ctx context.Context, msg *Message) error {
+	from, err := addressOf(msg.From)
+	if err != nil {
+		return err
+	}
+
+	to, err := addressOf(msg.To)
+	if err != nil {
+		return err
+	}
+
+	data, err := msg.Bytes()
+	if err != nil {
+		return fmt.Errorf("无法编码邮件：%w", err)
+	}
+
+	// net/smtp 没有超时设置，由连接的 deadline 限制整个会话的时长
+	dialer := &net.Dialer{Timeout: s.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("无法连接 SMTP 服务器 %v：%w", s.Addr, err)
+	}
+	defer conn.Close()
+
+	if s.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(s.Timeout))
+	}
+
+	host, _, _ := net.SplitHostPort(s.Addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return fmt.Errorf("无法建立 SMTP 会话：%w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("STARTTLS 失败：%w", err)
+		}
+	}
+
+	if s.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+			return fmt.Errorf("SMTP 认证失败：%w", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("MAIL FROM 失败：%w", err)
+	}
+
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("RCPT TO 失败：%w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("DATA 失败：%w", err)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("无法写入邮件内容：%w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("无法写入邮件内容：%w", err)
+	}
+
+	return client.Quit()
+}
@@ -0,0 +1,102 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTPServer 实现 MailHog 所需的最小 SMTP 子集（不支持 STARTTLS 与认证），返回收到的命令与邮件内容
+func fakeSMTPServer(t *testing.T) (string, chan []string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan []string, 1)
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { fmt.Fprintf(conn, "%s\r\n", s) }
+
+		var lines []string
+		reply("220 fake ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				reply("250-fake")
+				reply("250 8BITMIME")
+			case line == "DATA":
+				reply("354 go ahead")
+				for {
+					data, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					data = strings.TrimRight(data, "\r\n")
+					if data == "." {
+						break
+					}
+					lines = append(lines, data)
+				}
+				reply("250 ok")
+			case line == "QUIT":
+				reply("221 bye")
+				received <- lines
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	return ln.Addr().String(), received
+}
+
+func TestSMTPSender(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+	host, port, _ := net.SplitHostPort(addr)
+
+	s := NewSMTPSender(&SMTPConfig{
+		Host:    host,
+		Port:    port,
+		Timeout: 5 * time.Second,
+	})
+
+	err := s.Send(context.Background(), &Message{
+		From:    "Memrizr <noreply@memrizr.test>",
+		To:      "zhuangjinan@test.com",
+		Subject: "Verify your email address",
+		Text:    "text",
+		HTML:    "<p>html</p>",
+	})
+	assert.NoError(t, err)
+
+	select {
+	case lines := <-received:
+		assert.Contains(t, lines, "MAIL FROM:<noreply@memrizr.test> BODY=8BITMIME")
+		assert.Contains(t, lines, "RCPT TO:<zhuangjinan@test.com>")
+		assert.Contains(t, lines, "To: zhuangjinan@test.com")
+		assert.Contains(t, lines, "Subject: Verify your email address")
+	case <-time.After(5 * time.Second):
+		t.Fatal("SMTP 服务器没有收到邮件")
+	}
+}
@@ -19,7 +19,11 @@ func main() {
 		log.Fatalf("无法初始化数据源：%v\n", err)
 	}
 
-	router, err := inject(ds)
+	// 取消后停止后台任务
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	router, err := inject(jobsCtx, ds)
 	if err != nil {
 		log.Fatalf("注入数据源失败")
 	}
@@ -44,6 +48,8 @@ func main() {
 
 	<-quit
 
+	stopJobs()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
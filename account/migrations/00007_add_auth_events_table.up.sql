@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS auth_events (
+  id BIGSERIAL PRIMARY KEY,
+  uid uuid,
+  type VARCHAR NOT NULL,
+  detail VARCHAR NOT NULL DEFAULT '',
+  email VARCHAR NOT NULL DEFAULT '',
+  ip VARCHAR NOT NULL DEFAULT '',
+  user_agent VARCHAR NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS auth_events_uid_id_idx ON auth_events (uid, id DESC);
+CREATE INDEX IF NOT EXISTS auth_events_created_at_idx ON auth_events (created_at);
+
+-- 审计日志只能追加，过期记录由保留期任务删除
+CREATE OR REPLACE FUNCTION auth_events_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'auth_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS auth_events_append_only ON auth_events;
+CREATE TRIGGER auth_events_append_only
+  BEFORE UPDATE ON auth_events
+  FOR EACH ROW EXECUTE PROCEDURE auth_events_append_only();
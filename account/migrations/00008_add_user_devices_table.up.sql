@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS user_devices (
+  id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+  uid uuid NOT NULL REFERENCES users (uid) ON DELETE CASCADE,
+  user_agent VARCHAR NOT NULL,
+  network VARCHAR NOT NULL,
+  location VARCHAR NOT NULL DEFAULT '',
+  first_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (uid, user_agent, network)
+);
@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS roles (
+  name VARCHAR PRIMARY KEY,
+  description VARCHAR NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+  role VARCHAR NOT NULL REFERENCES roles (name) ON DELETE CASCADE ON UPDATE CASCADE,
+  permission VARCHAR NOT NULL,
+  PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+  uid uuid NOT NULL REFERENCES users (uid) ON DELETE CASCADE,
+  role VARCHAR NOT NULL REFERENCES roles (name) ON DELETE CASCADE ON UPDATE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (uid, role)
+);
+
+CREATE INDEX IF NOT EXISTS user_roles_role_idx ON user_roles (role);
+
+-- 内置角色，权限的含义见 model/role.go
+INSERT INTO roles (name, description) VALUES
+  ('admin', '管理员'),
+  ('support', '客服')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('admin', 'roles:read'),
+  ('admin', 'roles:manage'),
+  ('admin', 'users:unlock'),
+  ('support', 'roles:read'),
+  ('support', 'users:unlock')
+ON CONFLICT (role, permission) DO NOTHING;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS recovery_codes_uid_code_hash_idx;
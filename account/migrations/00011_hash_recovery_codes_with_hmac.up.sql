@@ -0,0 +1,4 @@
+-- 恢复码改为保存 HMAC 摘要，以密码哈希保存的旧恢复码无法再校验，删除后由用户重新生成
+DELETE FROM recovery_codes WHERE code_hash LIKE '$%';
+
+CREATE INDEX IF NOT EXISTS recovery_codes_uid_code_hash_idx ON recovery_codes (uid, code_hash);
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 认证事件的类型
+const (
+	AuthEventSignup         = "SIGNUP"
+	AuthEventSigninSuccess  = "SIGNIN_SUCCESS"
+	AuthEventSigninFailure  = "SIGNIN_FAILURE"
+	AuthEventMFASuccess     = "MFA_SUCCESS"
+	AuthEventMFAFailure     = "MFA_FAILURE"
+	AuthEventPasswordChange = "PASSWORD_CHANGE"
+	AuthEventTokenReuse     = "TOKEN_REUSE_DETECTED"
+	AuthEventNewDevice      = "NEW_DEVICE_SIGNIN"
+	AuthEventSessionRevoked = "SESSION_REVOKED"
+	AuthEventSignout        = "SIGNOUT"
+
+	AuthEventPersonalAccessTokenCreated = "PERSONAL_ACCESS_TOKEN_CREATED"
+	AuthEventPersonalAccessTokenRevoked = "PERSONAL_ACCESS_TOKEN_REVOKED"
+	AuthEventRoleAssigned               = "ROLE_ASSIGNED"
+	AuthEventRoleUnassigned             = "ROLE_UNASSIGNED"
+)
+
+// AuthEvent 为审计日志中的一条认证事件。UID 为 uuid.Nil 表示无法对应到用户（如使用不存在的邮箱登录），
+// Detail 补充说明事件，如登录方式或失败原因
+type AuthEvent struct {
+	ID        int64     `db:"id" json:"id"`
+	UID       uuid.UUID `db:"uid" json:"-"`
+	Type      string    `db:"type" json:"type"`
+	Detail    string    `db:"detail" json:"detail"`
+	Email     string    `db:"email" json:"-"`
+	IP        string    `db:"ip" json:"ip"`
+	UserAgent string    `db:"user_agent" json:"userAgent"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+}
@@ -0,0 +1,16 @@
+package model
+
+import "context"
+
+type clientInfoKey struct{}
+
+// ContextWithClientInfo 返回带有客户端信息的 context，用于在服务中记录认证事件的来源
+func ContextWithClientInfo(ctx context.Context, client *ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, client)
+}
+
+// ClientInfoFromContext 返回 context 中的客户端信息，不存在时返回 nil
+func ClientInfoFromContext(ctx context.Context) *ClientInfo {
+	client, _ := ctx.Value(clientInfoKey{}).(*ClientInfo)
+	return client
+}
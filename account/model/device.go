@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Device 为用户登录过的设备。UserAgent 为去掉版本号的浏览器与操作系统，如 Chrome / Windows，
+// Network 为 IP 所在的网段，Location 为 IP 所在的城市与国家，未配置地理位置数据库时为空
+type Device struct {
+	ID          uuid.UUID `db:"id" json:"-"`
+	UID         uuid.UUID `db:"uid" json:"-"`
+	UserAgent   string    `db:"user_agent" json:"-"`
+	Network     string    `db:"network" json:"-"`
+	Location    string    `db:"location" json:"-"`
+	FirstSeenAt time.Time `db:"first_seen_at" json:"-"`
+	LastSeenAt  time.Time `db:"last_seen_at" json:"-"`
+}
+
+// LoginAlert 为新设备登录提醒邮件中的登录信息
+type LoginAlert struct {
+	Device   string
+	IP       string
+	Location string
+	Time     time.Time
+}
@@ -2,6 +2,8 @@ package model
 
 import (
 	"context"
+	"io"
+	"mime/multipart"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,20 +13,187 @@ type UserService interface {
 	Get(ctx context.Context, uid uuid.UUID) (*User, error)
 	Signup(ctx context.Context, u *User) error
 	Signin(ctx context.Context, u *User) error
+	UpdateDetails(ctx context.Context, u *User) error
+	SetProfileImage(ctx context.Context, uid uuid.UUID, imageFileHeader *multipart.FileHeader) (*User, error)
+	ClearProfileImage(ctx context.Context, uid uuid.UUID) (*User, error)
+	SendEmailVerification(ctx context.Context, u *User) error
+	VerifyEmail(ctx context.Context, token string) (*User, error)
+	RequestPasswordReset(ctx context.Context, email string) error
+	FindByPasswordResetToken(ctx context.Context, token string) (*User, error)
+	ResetPassword(ctx context.Context, token string, password string) (uuid.UUID, error)
+	ChangePassword(ctx context.Context, uid uuid.UUID, currentPassword string, newPassword string) error
+	EnrollTOTP(ctx context.Context, uid uuid.UUID) (*TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, uid uuid.UUID, code string) (*User, []string, error)
+	DisableTOTP(ctx context.Context, uid uuid.UUID, code string) (*User, error)
+	NewMFAChallenge(ctx context.Context, u *User) (string, error)
+	VerifyMFAChallenge(ctx context.Context, mfaToken string, code string) (*User, error)
+	RegenerateRecoveryCodes(ctx context.Context, uid uuid.UUID, code string) ([]string, error)
+	CountRecoveryCodes(ctx context.Context, uid uuid.UUID) (int, error)
+	UnlockAccount(ctx context.Context, token string) error
+	ClearLockout(ctx context.Context, uid uuid.UUID) error
+}
+
+// WebAuthnService 负责通行密钥的注册与登录仪式
+type WebAuthnService interface {
+	BeginRegistration(ctx context.Context, u *User) (*WebAuthnCreationOptions, error)
+	FinishRegistration(ctx context.Context, u *User, name string, attestation *WebAuthnAttestation) (*WebAuthnCredential, error)
+	BeginLogin(ctx context.Context) (*WebAuthnRequestOptions, error)
+	FinishLogin(ctx context.Context, assertion *WebAuthnAssertion) (*User, error)
+	ListCredentials(ctx context.Context, uid uuid.UUID) ([]*WebAuthnCredential, error)
+	DeleteCredential(ctx context.Context, uid uuid.UUID, id string) error
 }
 
 type TokenService interface {
-	NewPairFromUser(ctx context.Context, u *User, prevTokenID string) (*TokenPair, error)
+	NewPairFromUser(ctx context.Context, u *User, prevTokenID string, client *ClientInfo) (*TokenPair, error)
+	NewIDToken(ctx context.Context, u *User) (string, error)
 	ValidateIDToken(tokenString string) (*User, error)
+	ValidateRefreshToken(refreshTokenString string) (*RefreshToken, error)
+	Signout(ctx context.Context, uid uuid.UUID, tokenID string) error
+	ListSessions(ctx context.Context, uid uuid.UUID) ([]*TokenFamily, error)
+	RevokeSession(ctx context.Context, uid uuid.UUID, sessionID string) error
+	JWKS() *JWKS
+	OpenIDConfiguration() *OpenIDConfiguration
+}
+
+// LoginAlertService 在用户从未见过的设备登录时发送提醒邮件，邮件中的令牌可以撤销该次登录的会话
+type LoginAlertService interface {
+	NewSession(ctx context.Context, u *User, session *TokenFamily)
+	RevokeSession(ctx context.Context, token string) error
+}
+
+// PersonalAccessTokenService 管理用户的个人访问令牌，Validate 在令牌有效时返回其所属用户并记录使用时间
+type PersonalAccessTokenService interface {
+	Create(ctx context.Context, uid uuid.UUID, name string, scopes []string, expiresIn time.Duration) (*PersonalAccessToken, string, error)
+	List(ctx context.Context, uid uuid.UUID) ([]*PersonalAccessToken, error)
+	Revoke(ctx context.Context, uid uuid.UUID, id uuid.UUID) error
+	RevokeAll(ctx context.Context, uid uuid.UUID) error
+	Validate(ctx context.Context, token string) (*User, *PersonalAccessToken, error)
+}
+
+// RoleService 查询与分配用户的角色，变更在用户下一次获取 ID 令牌时生效
+type RoleService interface {
+	ListUserRoles(ctx context.Context, uid uuid.UUID) ([]*Role, error)
+	AssignRole(ctx context.Context, actorUID uuid.UUID, uid uuid.UUID, role string) error
+	UnassignRole(ctx context.Context, actorUID uuid.UUID, uid uuid.UUID, role string) error
 }
 
 type UserRepository interface {
 	FindByID(ctx context.Context, uid uuid.UUID) (*User, error)
 	FindByEmail(ctx context.Context, email string) (*User, error)
 	Create(ctx context.Context, u *User) error
+	Update(ctx context.Context, u *User) error
+	UpdateImage(ctx context.Context, uid uuid.UUID, imageURL string) (*User, error)
+	SetEmailVerified(ctx context.Context, uid uuid.UUID, email string) (*User, error)
+	UpdatePassword(ctx context.Context, uid uuid.UUID, password string) error
+	RehashPassword(ctx context.Context, uid uuid.UUID, oldPassword string, password string) error
+	UpdateTOTP(ctx context.Context, uid uuid.UUID, secret string, enabled bool) (*User, error)
+	UseTOTPStep(ctx context.Context, uid uuid.UUID, step int64) error
+	RecordFailedSignin(ctx context.Context, uid uuid.UUID, threshold int, lockoutSecs []int64) (*User, error)
+	ResetSigninFailures(ctx context.Context, uid uuid.UUID) error
+}
+
+// RecoveryCodeRepository 保存用户的恢复码，ReplaceRecoveryCodes 会删除该用户已有的全部恢复码
+type RecoveryCodeRepository interface {
+	ReplaceRecoveryCodes(ctx context.Context, uid uuid.UUID, codeHashes []string) error
+	CountUnusedRecoveryCodes(ctx context.Context, uid uuid.UUID) (int, error)
+	UseRecoveryCode(ctx context.Context, uid uuid.UUID, codeHash string) error
+	DeleteRecoveryCodes(ctx context.Context, uid uuid.UUID) error
+}
+
+type WebAuthnCredentialRepository interface {
+	Create(ctx context.Context, c *WebAuthnCredential) error
+	FindByID(ctx context.Context, id string) (*WebAuthnCredential, error)
+	ListByUID(ctx context.Context, uid uuid.UUID) ([]*WebAuthnCredential, error)
+	UpdateSignCount(ctx context.Context, id string, signCount int64) error
+	Delete(ctx context.Context, uid uuid.UUID, id string) error
 }
 
 type TokenRepository interface {
-	SetRefreshToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) error
-	DeleteRefreshToken(ctx context.Context, userID string, prevTokenID string) error
+	SetRefreshToken(ctx context.Context, userID string, tokenID string, family *TokenFamily, expiresIn time.Duration) error
+	RotateRefreshToken(ctx context.Context, userID string, prevTokenID string, tokenID string, family *TokenFamily, expiresIn time.Duration) (bool, error)
+	GetTokenFamily(ctx context.Context, userID string, tokenID string) (*TokenFamily, error)
+	ListTokenFamilies(ctx context.Context, userID string) ([]*TokenFamily, error)
+	DeleteTokenFamily(ctx context.Context, userID string, familyID string) error
+	DeleteUserRefreshTokens(ctx context.Context, userID string) error
+	AddSecurityEvent(ctx context.Context, e *SecurityEvent) error
+}
+
+// DeviceRepository 保存用户登录过的设备，Upsert 按 UID、UserAgent 与 Network 更新已有的设备
+type DeviceRepository interface {
+	ListByUID(ctx context.Context, uid uuid.UUID) ([]*Device, error)
+	Upsert(ctx context.Context, d *Device) error
+	Delete(ctx context.Context, uid uuid.UUID, id uuid.UUID) error
+}
+
+// PersonalAccessTokenRepository 按摘要查询个人访问令牌，UpdateLastUsed 记录令牌最近的使用时间
+type PersonalAccessTokenRepository interface {
+	Create(ctx context.Context, t *PersonalAccessToken) error
+	FindByHash(ctx context.Context, tokenHash string) (*PersonalAccessToken, error)
+	ListByUID(ctx context.Context, uid uuid.UUID) ([]*PersonalAccessToken, error)
+	Delete(ctx context.Context, uid uuid.UUID, id uuid.UUID) error
+	DeleteByUID(ctx context.Context, uid uuid.UUID) error
+	UpdateLastUsed(ctx context.Context, id uuid.UUID) error
+}
+
+// RoleRepository 保存用户的角色，ListByUID 返回的角色包括其授予的权限。
+// 角色或用户不存在时 Assign 返回 NotFound，用户已有该角色时不作改变
+type RoleRepository interface {
+	ListByUID(ctx context.Context, uid uuid.UUID) ([]*Role, error)
+	Assign(ctx context.Context, uid uuid.UUID, role string) error
+	Unassign(ctx context.Context, uid uuid.UUID, role string) error
+}
+
+// OneTimeTokenRepository 保存只能使用一次的令牌，purpose 区分令牌的用途
+type OneTimeTokenRepository interface {
+	SetOneTimeToken(ctx context.Context, purpose string, tokenID string, userID string, expiresIn time.Duration) error
+	ConsumeOneTimeToken(ctx context.Context, purpose string, tokenID string) (string, error)
+	GetOneTimeToken(ctx context.Context, purpose string, tokenID string) (string, error)
+}
+
+// RateLimitRepository 记录滑动窗口内的请求次数
+type RateLimitRepository interface {
+	// Hit 记录一次 key 的请求并返回 0。窗口内已有 limit 次请求时不记录，返回需要等待的时间
+	Hit(ctx context.Context, key string, limit int, window time.Duration) (time.Duration, error)
+}
+
+// AuditLogger 记录认证事件，未设置 IP 与 UserAgent 时从 context 中的 ClientInfo 获取。
+// 记录失败只写入日志，不影响请求的结果
+type AuditLogger interface {
+	Log(ctx context.Context, e *AuthEvent)
+}
+
+// AuditService 记录与查询认证事件，并删除超出保留期的记录
+type AuditService interface {
+	AuditLogger
+	ListActivity(ctx context.Context, uid uuid.UUID, beforeID int64, limit int) ([]*AuthEvent, error)
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+// AuthEventRepository 只追加认证事件，DeleteBefore 每次最多删除 limit 条 before 之前的记录
+type AuthEventRepository interface {
+	Create(ctx context.Context, e *AuthEvent) error
+	ListByUID(ctx context.Context, uid uuid.UUID, beforeID int64, limit int) ([]*AuthEvent, error)
+	DeleteBefore(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+type ImageRepository interface {
+	UpdateProfile(ctx context.Context, objName string, image io.Reader, size int64, contentType string) (string, error)
+	DeleteProfile(ctx context.Context, objName string) error
+}
+
+// PasswordPolicy 检查注册、重置与修改密码时设置的新密码，符合要求时返回 nil
+type PasswordPolicy interface {
+	Check(password string, userInputs ...string) []PasswordViolation
+}
+
+// GeoLocator 返回 IP 所在的城市与国家，如 Shanghai, China，无法确定时返回空字符串
+type GeoLocator interface {
+	Locate(ip string) string
+}
+
+type Mailer interface {
+	SendEmailVerification(ctx context.Context, u *User, link string) error
+	SendPasswordReset(ctx context.Context, u *User, link string) error
+	SendAccountUnlock(ctx context.Context, u *User, link string) error
+	SendLoginAlert(ctx context.Context, u *User, alert *LoginAlert, link string) error
 }
@@ -0,0 +1,16 @@
+package model
+
+import "context"
+
+type languageKey struct{}
+
+// ContextWithLanguage 返回带有用户首选语言的 context，用于选择邮件等内容的语言
+func ContextWithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, languageKey{}, lang)
+}
+
+// LanguageFromContext 返回 context 中的首选语言，不存在时返回空字符串
+func LanguageFromContext(ctx context.Context) string {
+	lang, _ := ctx.Value(languageKey{}).(string)
+	return lang
+}
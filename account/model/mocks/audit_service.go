@@ -0,0 +1,49 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuditService struct {
+	mock.Mock
+}
+
+func (m *MockAuditService) Log(ctx context.Context, e *model.AuthEvent) {
+	m.Called(ctx, e)
+}
+
+func (m *MockAuditService) ListActivity(ctx context.Context, uid uuid.UUID, beforeID int64, limit int) ([]*model.AuthEvent, error) {
+	ret := m.Called(ctx, uid, beforeID, limit)
+
+	var r0 []*model.AuthEvent
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.AuthEvent)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockAuditService) PurgeExpired(ctx context.Context) (int64, error) {
+	ret := m.Called(ctx)
+
+	var r0 int64
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,57 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuthEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuthEventRepository) Create(ctx context.Context, e *model.AuthEvent) error {
+	ret := m.Called(ctx, e)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockAuthEventRepository) ListByUID(ctx context.Context, uid uuid.UUID, beforeID int64, limit int) ([]*model.AuthEvent, error) {
+	ret := m.Called(ctx, uid, beforeID, limit)
+
+	var r0 []*model.AuthEvent
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.AuthEvent)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockAuthEventRepository) DeleteBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	ret := m.Called(ctx, before, limit)
+
+	var r0 int64
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,51 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDeviceRepository struct {
+	mock.Mock
+}
+
+func (m *MockDeviceRepository) ListByUID(ctx context.Context, uid uuid.UUID) ([]*model.Device, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []*model.Device
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Device)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockDeviceRepository) Upsert(ctx context.Context, d *model.Device) error {
+	ret := m.Called(ctx, d)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockDeviceRepository) Delete(ctx context.Context, uid uuid.UUID, id uuid.UUID) error {
+	ret := m.Called(ctx, uid, id)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
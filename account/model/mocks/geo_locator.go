@@ -0,0 +1,20 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type MockGeoLocator struct {
+	mock.Mock
+}
+
+func (m *MockGeoLocator) Locate(ip string) string {
+	ret := m.Called(ip)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
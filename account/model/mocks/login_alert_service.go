@@ -0,0 +1,27 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockLoginAlertService struct {
+	mock.Mock
+}
+
+func (m *MockLoginAlertService) NewSession(ctx context.Context, u *model.User, session *model.TokenFamily) {
+	m.Called(ctx, u, session)
+}
+
+func (m *MockLoginAlertService) RevokeSession(ctx context.Context, token string) error {
+	ret := m.Called(ctx, token)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
@@ -0,0 +1,56 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMailer struct {
+	mock.Mock
+}
+
+func (m *MockMailer) SendEmailVerification(ctx context.Context, u *model.User, link string) error {
+	ret := m.Called(ctx, u, link)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockMailer) SendPasswordReset(ctx context.Context, u *model.User, link string) error {
+	ret := m.Called(ctx, u, link)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockMailer) SendAccountUnlock(ctx context.Context, u *model.User, link string) error {
+	ret := m.Called(ctx, u, link)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockMailer) SendLoginAlert(ctx context.Context, u *model.User, alert *model.LoginAlert, link string) error {
+	ret := m.Called(ctx, u, alert, link)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
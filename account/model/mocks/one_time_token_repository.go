@@ -0,0 +1,55 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockOneTimeTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockOneTimeTokenRepository) SetOneTimeToken(ctx context.Context, purpose string, tokenID string, userID string, expiresIn time.Duration) error {
+	ret := m.Called(ctx, purpose, tokenID, userID, expiresIn)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockOneTimeTokenRepository) ConsumeOneTimeToken(ctx context.Context, purpose string, tokenID string) (string, error) {
+	ret := m.Called(ctx, purpose, tokenID)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockOneTimeTokenRepository) GetOneTimeToken(ctx context.Context, purpose string, tokenID string) (string, error) {
+	ret := m.Called(ctx, purpose, tokenID)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
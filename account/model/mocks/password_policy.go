@@ -0,0 +1,21 @@
+package mocks
+
+import (
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPasswordPolicy struct {
+	mock.Mock
+}
+
+func (m *MockPasswordPolicy) Check(password string, userInputs ...string) []model.PasswordViolation {
+	ret := m.Called(password, userInputs)
+
+	var r0 []model.PasswordViolation
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]model.PasswordViolation)
+	}
+
+	return r0
+}
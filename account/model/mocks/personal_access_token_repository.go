@@ -0,0 +1,89 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPersonalAccessTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockPersonalAccessTokenRepository) Create(ctx context.Context, t *model.PersonalAccessToken) error {
+	ret := m.Called(ctx, t)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockPersonalAccessTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*model.PersonalAccessToken, error) {
+	ret := m.Called(ctx, tokenHash)
+
+	var r0 *model.PersonalAccessToken
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.PersonalAccessToken)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockPersonalAccessTokenRepository) ListByUID(ctx context.Context, uid uuid.UUID) ([]*model.PersonalAccessToken, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []*model.PersonalAccessToken
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.PersonalAccessToken)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockPersonalAccessTokenRepository) Delete(ctx context.Context, uid uuid.UUID, id uuid.UUID) error {
+	ret := m.Called(ctx, uid, id)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockPersonalAccessTokenRepository) DeleteByUID(ctx context.Context, uid uuid.UUID) error {
+	ret := m.Called(ctx, uid)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockPersonalAccessTokenRepository) UpdateLastUsed(ctx context.Context, id uuid.UUID) error {
+	ret := m.Called(ctx, id)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
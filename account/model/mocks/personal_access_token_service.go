@@ -0,0 +1,94 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPersonalAccessTokenService struct {
+	mock.Mock
+}
+
+func (m *MockPersonalAccessTokenService) Create(ctx context.Context, uid uuid.UUID, name string, scopes []string, expiresIn time.Duration) (*model.PersonalAccessToken, string, error) {
+	ret := m.Called(ctx, uid, name, scopes, expiresIn)
+
+	var r0 *model.PersonalAccessToken
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.PersonalAccessToken)
+	}
+
+	var r1 string
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(string)
+	}
+
+	var r2 error
+	if ret.Get(2) != nil {
+		r2 = ret.Get(2).(error)
+	}
+
+	return r0, r1, r2
+}
+
+func (m *MockPersonalAccessTokenService) List(ctx context.Context, uid uuid.UUID) ([]*model.PersonalAccessToken, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []*model.PersonalAccessToken
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.PersonalAccessToken)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockPersonalAccessTokenService) Revoke(ctx context.Context, uid uuid.UUID, id uuid.UUID) error {
+	ret := m.Called(ctx, uid, id)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockPersonalAccessTokenService) RevokeAll(ctx context.Context, uid uuid.UUID) error {
+	ret := m.Called(ctx, uid)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockPersonalAccessTokenService) Validate(ctx context.Context, token string) (*model.User, *model.PersonalAccessToken, error) {
+	ret := m.Called(ctx, token)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 *model.PersonalAccessToken
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(*model.PersonalAccessToken)
+	}
+
+	var r2 error
+	if ret.Get(2) != nil {
+		r2 = ret.Get(2).(error)
+	}
+
+	return r0, r1, r2
+}
@@ -0,0 +1,61 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRecoveryCodeRepository struct {
+	mock.Mock
+}
+
+func (m *MockRecoveryCodeRepository) ReplaceRecoveryCodes(ctx context.Context, uid uuid.UUID, codeHashes []string) error {
+	ret := m.Called(ctx, uid, codeHashes)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockRecoveryCodeRepository) CountUnusedRecoveryCodes(ctx context.Context, uid uuid.UUID) (int, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 int
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockRecoveryCodeRepository) UseRecoveryCode(ctx context.Context, uid uuid.UUID, codeHash string) error {
+	ret := m.Called(ctx, uid, codeHash)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockRecoveryCodeRepository) DeleteRecoveryCodes(ctx context.Context, uid uuid.UUID) error {
+	ret := m.Called(ctx, uid)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
@@ -0,0 +1,51 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRoleRepository struct {
+	mock.Mock
+}
+
+func (m *MockRoleRepository) ListByUID(ctx context.Context, uid uuid.UUID) ([]*model.Role, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []*model.Role
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Role)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockRoleRepository) Assign(ctx context.Context, uid uuid.UUID, role string) error {
+	ret := m.Called(ctx, uid, role)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockRoleRepository) Unassign(ctx context.Context, uid uuid.UUID, role string) error {
+	ret := m.Called(ctx, uid, role)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/FuZhouJohn/memrizr/account/model"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -11,8 +12,8 @@ type MockTokenRepository struct {
 	mock.Mock
 }
 
-func (m *MockTokenRepository) SetRefreshToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) error {
-	ret := m.Called(ctx, userID, tokenID, expiresIn)
+func (m *MockTokenRepository) SetRefreshToken(ctx context.Context, userID string, tokenID string, family *model.TokenFamily, expiresIn time.Duration) error {
+	ret := m.Called(ctx, userID, tokenID, family, expiresIn)
 
 	var r0 error
 	if ret.Get(0) != nil {
@@ -22,8 +23,78 @@ func (m *MockTokenRepository) SetRefreshToken(ctx context.Context, userID string
 	return r0
 }
 
-func (m *MockTokenRepository) DeleteRefreshToken(ctx context.Context, userID string, prevTokenID string) error {
-	ret := m.Called(ctx, userID, prevTokenID)
+func (m *MockTokenRepository) RotateRefreshToken(ctx context.Context, userID string, prevTokenID string, tokenID string, family *model.TokenFamily, expiresIn time.Duration) (bool, error) {
+	ret := m.Called(ctx, userID, prevTokenID, tokenID, family, expiresIn)
+
+	var r0 bool
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockTokenRepository) GetTokenFamily(ctx context.Context, userID string, tokenID string) (*model.TokenFamily, error) {
+	ret := m.Called(ctx, userID, tokenID)
+
+	var r0 *model.TokenFamily
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.TokenFamily)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockTokenRepository) ListTokenFamilies(ctx context.Context, userID string) ([]*model.TokenFamily, error) {
+	ret := m.Called(ctx, userID)
+
+	var r0 []*model.TokenFamily
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.TokenFamily)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockTokenRepository) DeleteTokenFamily(ctx context.Context, userID string, familyID string) error {
+	ret := m.Called(ctx, userID, familyID)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockTokenRepository) DeleteUserRefreshTokens(ctx context.Context, userID string) error {
+	ret := m.Called(ctx, userID)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockTokenRepository) AddSecurityEvent(ctx context.Context, e *model.SecurityEvent) error {
+	ret := m.Called(ctx, e)
 
 	var r0 error
 	if ret.Get(0) != nil {
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -11,8 +12,8 @@ type MockTokenService struct {
 	mock.Mock
 }
 
-func (m *MockTokenService) NewPairFromUser(ctx context.Context, u *model.User, prevTokenID string) (*model.TokenPair, error) {
-	ret := m.Called(ctx, u, prevTokenID)
+func (m *MockTokenService) NewPairFromUser(ctx context.Context, u *model.User, prevTokenID string, client *model.ClientInfo) (*model.TokenPair, error) {
+	ret := m.Called(ctx, u, prevTokenID, client)
 
 	var r0 *model.TokenPair
 	if ret.Get(0) != nil {
@@ -27,6 +28,22 @@ func (m *MockTokenService) NewPairFromUser(ctx context.Context, u *model.User, p
 	return r0, r1
 }
 
+func (m *MockTokenService) NewIDToken(ctx context.Context, u *model.User) (string, error) {
+	ret := m.Called(ctx, u)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
 func (m *MockTokenService) ValidateIDToken(tokenString string) (*model.User, error) {
 	ret := m.Called(tokenString)
 
@@ -45,3 +62,79 @@ func (m *MockTokenService) ValidateIDToken(tokenString string) (*model.User, err
 
 	return r0, r1
 }
+
+func (m *MockTokenService) ValidateRefreshToken(refreshTokenString string) (*model.RefreshToken, error) {
+	ret := m.Called(refreshTokenString)
+
+	var r0 *model.RefreshToken
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.RefreshToken)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockTokenService) Signout(ctx context.Context, uid uuid.UUID, tokenID string) error {
+	ret := m.Called(ctx, uid, tokenID)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockTokenService) ListSessions(ctx context.Context, uid uuid.UUID) ([]*model.TokenFamily, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []*model.TokenFamily
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.TokenFamily)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockTokenService) RevokeSession(ctx context.Context, uid uuid.UUID, sessionID string) error {
+	ret := m.Called(ctx, uid, sessionID)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockTokenService) JWKS() *model.JWKS {
+	ret := m.Called()
+
+	var r0 *model.JWKS
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.JWKS)
+	}
+
+	return r0
+}
+
+func (m *MockTokenService) OpenIDConfiguration() *model.OpenIDConfiguration {
+	ret := m.Called()
+
+	var r0 *model.OpenIDConfiguration
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OpenIDConfiguration)
+	}
+
+	return r0
+}
@@ -0,0 +1,7 @@
+package model
+
+// PasswordViolation 为密码不符合的规则，如 Tag 为 gte、Param 为 8 表示密码至少需要 8 个字符
+type PasswordViolation struct {
+	Tag   string
+	Param string
+}
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// PersonalAccessTokenPrefix 为个人访问令牌的前缀，用于与 ID 令牌区分，也便于在泄露的代码中识别
+const PersonalAccessTokenPrefix = "mpat_"
+
+// 个人访问令牌的权限范围。令牌只能访问与权限范围对应的接口，
+// 修改密码、MFA、通行密钥与令牌管理等接口只接受 ID 令牌
+const (
+	// ScopeProfileRead 读取用户信息与认证活动
+	ScopeProfileRead = "profile:read"
+	// ScopeProfileWrite 修改用户信息与头像，但不能修改邮箱
+	ScopeProfileWrite = "profile:write"
+	// ScopeSessionsRead 列出已登录的设备
+	ScopeSessionsRead = "sessions:read"
+	// ScopeSessionsWrite 撤销已登录设备的会话
+	ScopeSessionsWrite = "sessions:write"
+)
+
+// PersonalAccessTokenScopes 为所有可授予的权限范围
+var PersonalAccessTokenScopes = []string{
+	ScopeProfileRead,
+	ScopeProfileWrite,
+	ScopeSessionsRead,
+	ScopeSessionsWrite,
+}
+
+// PersonalAccessToken 为用户创建的个人访问令牌，令牌本身只在创建时返回一次，只保存其摘要
+type PersonalAccessToken struct {
+	ID         uuid.UUID      `db:"id" json:"id"`
+	UID        uuid.UUID      `db:"uid" json:"-"`
+	Name       string         `db:"name" json:"name"`
+	TokenHash  string         `db:"token_hash" json:"-"`
+	Scopes     pq.StringArray `db:"scopes" json:"scopes"`
+	ExpiresAt  time.Time      `db:"expires_at" json:"expiresAt"`
+	CreatedAt  time.Time      `db:"created_at" json:"createdAt"`
+	LastUsedAt *time.Time     `db:"last_used_at" json:"lastUsedAt"`
+}
+
+// HasScopes 返回令牌是否具有 scopes 中的所有权限
+func (t *PersonalAccessToken) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		granted := false
+		for _, s := range t.Scopes {
+			if s == scope {
+				granted = true
+				break
+			}
+		}
+
+		if !granted {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecoveryCode 为启用 TOTP 时生成的一次性恢复码，只保存以 RECOVERY_CODE_SECRET 计算的 HMAC 摘要，UsedAt 为空表示未使用
+type RecoveryCode struct {
+	ID        uuid.UUID  `db:"id" json:"-"`
+	UID       uuid.UUID  `db:"uid" json:"-"`
+	CodeHash  string     `db:"code_hash" json:"-"`
+	UsedAt    *time.Time `db:"used_at" json:"-"`
+	CreatedAt time.Time  `db:"created_at" json:"-"`
+}
@@ -0,0 +1,20 @@
+package model
+
+import "github.com/lib/pq"
+
+// 权限的格式为 资源:操作，由角色授予用户，并写入 ID 令牌的 permissions claim
+const (
+	// PermissionRolesRead 查看用户的角色
+	PermissionRolesRead = "roles:read"
+	// PermissionRolesManage 为用户分配与移除角色
+	PermissionRolesManage = "roles:manage"
+	// PermissionUsersUnlock 解除用户的账号锁定
+	PermissionUsersUnlock = "users:unlock"
+)
+
+// Role 为角色及其授予的权限
+type Role struct {
+	Name        string         `db:"name" json:"name"`
+	Description string         `db:"description" json:"description"`
+	Permissions pq.StringArray `db:"permissions" json:"permissions"`
+}
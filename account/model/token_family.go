@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// TokenFamily 表示一次登录会话：同一次登录中经轮换产生的所有刷新令牌属于同一个 family，
+// TokenID 为其中最新签发的令牌
+type TokenFamily struct {
+	ID              string    `json:"id"`
+	TokenID         string    `json:"-"`
+	UserAgent       string    `json:"userAgent"`
+	IP              string    `json:"ip"`
+	CreatedAt       time.Time `json:"createdAt"`
+	LastRefreshedAt time.Time `json:"lastRefreshedAt"`
+}
+
+// ClientInfo 为发起请求的客户端信息，用于记录会话所在的设备
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+const (
+	SecurityEventTokenReuse = "REFRESH_TOKEN_REUSE"
+)
+
+// SecurityEvent 记录需要关注的安全事件，例如已轮换的刷新令牌被再次使用
+type SecurityEvent struct {
+	Type      string    `json:"type"`
+	UserID    string    `json:"userId"`
+	FamilyID  string    `json:"familyId"`
+	TokenID   string    `json:"tokenId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -1,6 +1,15 @@
 package model
 
+import "github.com/google/uuid"
+
 type TokenPair struct {
 	IDToken      string `json:"idToken"`
 	RefreshToken string `json:"refreshToken"`
 }
+
+// RefreshToken 为校验通过后的刷新令牌，SS 为签名后的令牌字符串
+type RefreshToken struct {
+	ID  uuid.UUID `json:"-"`
+	UID uuid.UUID `json:"-"`
+	SS  string    `json:"refreshToken"`
+}
@@ -0,0 +1,7 @@
+package model
+
+// TOTPEnrollment 为绑定 TOTP 时返回的密钥，URI 为 otpauth:// 格式，可由客户端生成二维码供验证器扫描
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
@@ -1,12 +1,50 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type User struct {
-	UID      uuid.UUID `db:"uid" json:"uid"`
-	Email    string    `db:"email" json:"email"`
-	Password string    `db:"password" json:"-"`
-	Name     string    `db:"name" json:"name"`
-	ImageURL string    `db:"image_url" json:"imageUrl"`
-	Website  string    `db:"website" json:"website"`
+	UID               uuid.UUID  `db:"uid" json:"uid"`
+	Email             string     `db:"email" json:"email"`
+	Password          string     `db:"password" json:"-"`
+	Name              string     `db:"name" json:"name"`
+	ImageURL          string     `db:"image_url" json:"imageUrl"`
+	Website           string     `db:"website" json:"website"`
+	EmailVerified     bool       `db:"email_verified" json:"emailVerified"`
+	TOTPSecret        string     `db:"totp_secret" json:"-"`
+	TOTPEnabled       bool       `db:"totp_enabled" json:"totpEnabled"`
+	TOTPLastUsedStep  int64      `db:"totp_last_used_step" json:"-"`
+	FailedSigninCount int        `db:"failed_signin_count" json:"-"`
+	LockoutCount      int        `db:"lockout_count" json:"-"`
+	LockedUntil       *time.Time `db:"locked_until" json:"-"`
+	// Roles 与 Permissions 不保存在 users 表中，由 TokenService 签发 ID 令牌时查询并写入 claims
+	Roles       []string `db:"-" json:"-"`
+	Permissions []string `db:"-" json:"-"`
+}
+
+// Locked 返回账号在 now 时是否处于锁定中
+func (u *User) Locked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+// HasPermissions 返回用户是否具有 perms 中的所有权限
+func (u *User) HasPermissions(perms ...string) bool {
+	for _, perm := range perms {
+		granted := false
+		for _, p := range u.Permissions {
+			if p == perm {
+				granted = true
+				break
+			}
+		}
+
+		if !granted {
+			return false
+		}
+	}
+
+	return true
 }
@@ -0,0 +1,30 @@
+package model
+
+// JWK 为 RFC 7517 定义的 JSON Web Key，仅包含公钥部分
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS 为 /.well-known/jwks.json 返回的公钥集合
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// OpenIDConfiguration 为 /.well-known/openid-configuration 返回的元数据，只描述签发者与验证 ID 令牌所需的公钥。
+// 本服务以 /signin 等接口签发令牌，没有 OAuth 的 authorization_endpoint，因此不是完整的 OIDC discovery 文档
+type OpenIDConfiguration struct {
+	Issuer                           string   `json:"issuer"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	ResponseTypesSupported           []string `json:"response_types_supported"`
+	SubjectTypesSupported            []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+	ClaimsSupported                  []string `json:"claims_supported"`
+}
@@ -0,0 +1,89 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// 内置的泄露密码列表只包含 SHA-1 哈希，不包含明文
+//
+//go:embed data/breached_sha1.txt
+var defaultBreachedData []byte
+
+// 与 Have I Been Pwned 的 range 接口一致，以哈希的前 5 位分组
+const breachedPrefixLength = 5
+
+// BreachedHashes 为泄露密码的 SHA-1 哈希，以前缀分组保存，查询时只需比较同一前缀下的后缀
+type BreachedHashes struct {
+	ranges map[string][]string
+}
+
+// DefaultBreachedHashes 返回内置的泄露密码列表
+func DefaultBreachedHashes() (*BreachedHashes, error) {
+	return LoadBreachedHashes(bytes.NewReader(defaultBreachedData))
+}
+
+// LoadBreachedHashes 读取每行一个 SHA-1 哈希的列表，可带有 `:次数` 后缀，以 # 开头的行为注释。
+// Have I Been Pwned 下载的 SHA-1 列表可以直接使用
+func LoadBreachedHashes(r io.Reader) (*BreachedHashes, error) {
+	ranges := make(map[string][]string)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if i := strings.Index(text, ":"); i >= 0 {
+			text = text[:i]
+		}
+
+		hash := strings.ToUpper(text)
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("第 %v 行不是有效的 SHA-1 哈希：%v", line, text)
+		}
+
+		prefix := hash[:breachedPrefixLength]
+		ranges[prefix] = append(ranges[prefix], hash[breachedPrefixLength:])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("无法读取泄露密码列表：%w", err)
+	}
+
+	for _, suffixes := range ranges {
+		sort.Strings(suffixes)
+	}
+
+	return &BreachedHashes{ranges: ranges}, nil
+}
+
+// Contains 返回密码是否出现在泄露密码列表中
+func (b *BreachedHashes) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	suffixes := b.ranges[hash[:breachedPrefixLength]]
+	suffix := hash[breachedPrefixLength:]
+
+	i := sort.SearchStrings(suffixes, suffix)
+	return i < len(suffixes) && suffixes[i] == suffix
+}
+
+// Len 返回列表中哈希的数量
+func (b *BreachedHashes) Len() int {
+	n := 0
+	for _, suffixes := range b.ranges {
+		n += len(suffixes)
+	}
+
+	return n
+}
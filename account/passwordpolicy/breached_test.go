@@ -0,0 +1,43 @@
+package passwordpolicy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreachedHashes(t *testing.T) {
+	t.Run("内置列表", func(t *testing.T) {
+		b, err := DefaultBreachedHashes()
+		assert.NoError(t, err)
+
+		assert.Greater(t, b.Len(), 0)
+		assert.True(t, b.Contains("123456"))
+		assert.True(t, b.Contains("password"))
+		// 与 Have I Been Pwned 一致，区分大小写
+		assert.False(t, b.Contains("PASSWORD"))
+		assert.False(t, b.Contains("kX9#mQ2$vL7!"))
+	})
+
+	t.Run("Have I Been Pwned 格式", func(t *testing.T) {
+		// SHA-1("password") 与 SHA-1("123456")
+		data := "# 注释\n5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8:9545824\n\n7C4A8D09CA3762AF61E59520943DC26494F8941B\n"
+
+		b, err := LoadBreachedHashes(strings.NewReader(data))
+		assert.NoError(t, err)
+
+		assert.Equal(t, 2, b.Len())
+		assert.True(t, b.Contains("password"))
+		assert.True(t, b.Contains("123456"))
+		assert.False(t, b.Contains("qwerty"))
+	})
+
+	t.Run("无效的哈希", func(t *testing.T) {
+		_, err := LoadBreachedHashes(strings.NewReader("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD\n"))
+		assert.Error(t, err)
+
+		_, err = LoadBreachedHashes(strings.NewReader("ZBAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\n"))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,101 @@
+// Package passwordpolicy 检查新设置的密码是否符合长度、强度要求，且不在泄露密码列表中。
+// 所有检查都在本地完成，不访问网络
+package passwordpolicy
+
+import (
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+)
+
+// MaxLengthLimit 为 MaxLength 的上限，请求参数也以此限制密码长度，避免计算超长密码的哈希
+const MaxLengthLimit = 1024
+
+const (
+	defaultMinLength = 8
+	defaultMaxLength = 128
+)
+
+// 不符合规则时 model.PasswordViolation 的 Tag
+const (
+	TagMinLength = "gte"
+	TagMaxLength = "lte"
+	TagStrength  = "strength"
+	TagBreached  = "breached"
+)
+
+// Config 中长度按字符计算，MinLength 与 MaxLength 为 0 时分别使用 8 与 128。
+// MinStrength 为 0-4，0 表示不检查强度；Breached 为 nil 时不检查是否泄露
+type Config struct {
+	MinLength   int
+	MaxLength   int
+	MinStrength int
+	Breached    *BreachedHashes
+}
+
+type passwordPolicy struct {
+	MinLength   int
+	MaxLength   int
+	MinStrength int
+	Breached    *BreachedHashes
+}
+
+// New 校验配置并返回 model.PasswordPolicy
+func New(c *Config) (model.PasswordPolicy, error) {
+	p := &passwordPolicy{
+		MinLength:   c.MinLength,
+		MaxLength:   c.MaxLength,
+		MinStrength: c.MinStrength,
+		Breached:    c.Breached,
+	}
+
+	if p.MinLength == 0 {
+		p.MinLength = defaultMinLength
+	}
+
+	if p.MaxLength == 0 {
+		p.MaxLength = defaultMaxLength
+	}
+
+	if p.MinLength < 1 || p.MaxLength > MaxLengthLimit || p.MinLength > p.MaxLength {
+		return nil, fmt.Errorf("密码长度应在 1 至 %v 之间，且最小长度不能大于最大长度：%v-%v", MaxLengthLimit, p.MinLength, p.MaxLength)
+	}
+
+	if p.MinStrength < 0 || p.MinStrength > len(strengthThresholds) {
+		return nil, fmt.Errorf("密码强度应在 0 至 %v 之间：%v", len(strengthThresholds), p.MinStrength)
+	}
+
+	return p, nil
+}
+
+// Check 返回密码不符合的全部规则，userInputs 中的邮箱等信息出现在密码中时会降低强度
+func (p *passwordPolicy) Check(password string, userInputs ...string) []model.PasswordViolation {
+	var violations []model.PasswordViolation
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, model.PasswordViolation{Tag: TagMinLength, Param: strconv.Itoa(p.MinLength)})
+	}
+
+	// 超长的密码不再估算强度
+	if length > p.MaxLength {
+		return append(violations, model.PasswordViolation{Tag: TagMaxLength, Param: strconv.Itoa(p.MaxLength)})
+	}
+
+	var inDictionary func(string) bool
+	if p.Breached != nil {
+		inDictionary = p.Breached.Contains
+	}
+
+	if p.MinStrength > 0 && estimateStrength(password, userInputs, inDictionary) < p.MinStrength {
+		violations = append(violations, model.PasswordViolation{Tag: TagStrength, Param: strconv.Itoa(p.MinStrength)})
+	}
+
+	if p.Breached != nil && p.Breached.Contains(password) {
+		violations = append(violations, model.PasswordViolation{Tag: TagBreached})
+	}
+
+	return violations
+}
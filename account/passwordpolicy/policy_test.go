@@ -0,0 +1,84 @@
+package passwordpolicy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	breached, err := DefaultBreachedHashes()
+	assert.NoError(t, err)
+
+	policy, err := New(&Config{
+		MinStrength: 2,
+		Breached:    breached,
+	})
+	assert.NoError(t, err)
+
+	t.Run("符合要求", func(t *testing.T) {
+		for _, password := range []string{
+			"correct horse battery staple",
+			"kX9#mQ2$vL7!",
+			// 长度按字符计算，且允许 128 个字符的密码
+			"一二三四五六七八",
+			strings.Repeat("kX9#mQ2$", 16),
+		} {
+			assert.Nil(t, policy.Check(password, "bob@bob.com"), password)
+		}
+	})
+
+	t.Run("太短", func(t *testing.T) {
+		violations := policy.Check("kX9#mQ2")
+		assert.Equal(t, []model.PasswordViolation{{Tag: TagMinLength, Param: "8"}}, violations)
+	})
+
+	t.Run("太长", func(t *testing.T) {
+		violations := policy.Check(strings.Repeat("kX9#mQ2$", 16) + "a")
+		assert.Equal(t, []model.PasswordViolation{{Tag: TagMaxLength, Param: "128"}}, violations)
+	})
+
+	t.Run("强度不足", func(t *testing.T) {
+		for _, password := range []string{"abcdefghijkl", "qwertyuiop12", "aaaaaaaaaaaa"} {
+			violations := policy.Check(password)
+			assert.Equal(t, []model.PasswordViolation{{Tag: TagStrength, Param: "2"}}, violations, password)
+		}
+	})
+
+	t.Run("包含用户信息", func(t *testing.T) {
+		assert.Nil(t, policy.Check("zhuangjinan2024"))
+
+		violations := policy.Check("zhuangjinan2024", "zhuangjinan@memrizr.test")
+		assert.Equal(t, []model.PasswordViolation{{Tag: TagStrength, Param: "2"}}, violations)
+	})
+
+	t.Run("已泄露", func(t *testing.T) {
+		violations := policy.Check("password123")
+		assert.Equal(t, []model.PasswordViolation{
+			{Tag: TagStrength, Param: "2"},
+			{Tag: TagBreached},
+		}, violations)
+	})
+
+	t.Run("默认只检查长度", func(t *testing.T) {
+		policy, err := New(&Config{})
+		assert.NoError(t, err)
+
+		assert.Nil(t, policy.Check("password123"))
+		assert.Equal(t, []model.PasswordViolation{{Tag: TagMinLength, Param: "8"}}, policy.Check("short"))
+	})
+
+	t.Run("无效的配置", func(t *testing.T) {
+		for _, c := range []*Config{
+			{MinLength: 16, MaxLength: 8},
+			{MaxLength: MaxLengthLimit + 1},
+			{MinLength: -1},
+			{MinStrength: 5},
+		} {
+			_, err := New(c)
+			assert.Error(t, err)
+		}
+	})
+}
@@ -0,0 +1,198 @@
+package passwordpolicy
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// 密码强度分为 0-4 级，对应估算熵（位）的下限
+var strengthThresholds = []float64{20, 28, 36, 45}
+
+const (
+	// 重复、连续或键盘上相邻的字符几乎不增加猜测次数，按 1 位计算
+	predictableCharBits = 1
+	// 常见密码与用户信息作为一个整体猜测，按从列表中选取一项计算
+	dictionaryTokenBits = 10
+	userInputTokenBits  = 2
+	// 在字典中查找的片段的最小长度
+	minTokenLength = 4
+)
+
+var keyboardRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]\\",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+}
+
+// leetReplacer 还原常见的字符替换，如 p@ssw0rd
+var leetReplacer = strings.NewReplacer("@", "a", "4", "a", "3", "e", "1", "i", "!", "i", "0", "o", "$", "s", "5", "s", "7", "t")
+
+// estimateStrength 粗略估算密码强度（0-4）。与 zxcvbn 的思路类似，但只识别以下模式：
+// 用户信息、inDictionary 中的常见密码（包括常见的字符替换）、重复字符、连续字符与键盘上相邻的字符，
+// 其余字符按所用字符集的大小计算熵
+func estimateStrength(password string, userInputs []string, inDictionary func(string) bool) int {
+	bits := estimateBits(password, userInputs, inDictionary)
+
+	score := 0
+	for _, threshold := range strengthThresholds {
+		if bits >= threshold {
+			score++
+		}
+	}
+
+	return score
+}
+
+func estimateBits(password string, userInputs []string, inDictionary func(string) bool) float64 {
+	// 逐个字符转为小写，保证与 original 的下标一致
+	original := []rune(password)
+	lower := make([]rune, len(original))
+	for i, r := range original {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	matched := make([]bool, len(lower))
+	bits := 0.0
+
+	// 用户信息，如邮箱的用户名与域名
+	for _, input := range userInputs {
+		for _, token := range userInputTokens(input) {
+			for _, i := range indexAll(lower, []rune(token)) {
+				if !anyMatched(matched[i : i+len([]rune(token))]) {
+					markMatched(matched[i : i+len([]rune(token))])
+					bits += userInputTokenBits
+				}
+			}
+		}
+	}
+
+	// 从长到短查找常见密码，每个片段作为一个整体
+	if inDictionary != nil {
+		for length := len(lower); length >= minTokenLength; length-- {
+			for i := 0; i+length <= len(lower); i++ {
+				if anyMatched(matched[i : i+length]) {
+					continue
+				}
+
+				token := string(lower[i : i+length])
+				if inDictionary(token) || inDictionary(leetReplacer.Replace(token)) {
+					markMatched(matched[i : i+length])
+					bits += dictionaryTokenBits
+				}
+			}
+		}
+	}
+
+	// 剩余字符中重复、连续与键盘上相邻的字符
+	pool := 0
+	var classes [5]bool
+	for i, r := range original {
+		if matched[i] {
+			continue
+		}
+		classes[charClass(r)] = true
+	}
+	for class, present := range classes {
+		if present {
+			pool += charClassSizes[class]
+		}
+	}
+
+	charBits := math.Log2(float64(pool))
+	for i := range lower {
+		if matched[i] {
+			continue
+		}
+
+		if i > 0 && !matched[i-1] && predictable(lower[i-1], lower[i]) {
+			bits += predictableCharBits
+		} else {
+			bits += charBits
+		}
+	}
+
+	return bits
+}
+
+// 字符集依次为小写字母、大写字母、数字、ASCII 符号与其他字符
+var charClassSizes = [5]int{26, 26, 10, 33, 100}
+
+func charClass(r rune) int {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return 0
+	case r >= 'A' && r <= 'Z':
+		return 1
+	case r >= '0' && r <= '9':
+		return 2
+	case r < unicode.MaxASCII:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// predictable 返回 cur 是否为 prev 的重复、连续字符或键盘上相邻的字符
+func predictable(prev rune, cur rune) bool {
+	if cur == prev || cur == prev+1 || cur == prev-1 {
+		return true
+	}
+
+	for _, row := range keyboardRows {
+		i := strings.IndexRune(row, prev)
+		if i < 0 {
+			continue
+		}
+
+		if j := strings.IndexRune(row, cur); j >= 0 && (j == i+1 || j == i-1) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// userInputTokens 将邮箱等信息拆分为长度不小于 3 的片段，如 bob.smith@example.com 拆分为 bob、smith 与 example
+func userInputTokens(input string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var tokens []string
+	for _, f := range fields {
+		if len([]rune(f)) >= 3 && f != "com" {
+			tokens = append(tokens, f)
+		}
+	}
+
+	return tokens
+}
+
+func indexAll(s []rune, sub []rune) []int {
+	var indexes []int
+	for i := 0; i+len(sub) <= len(s); i++ {
+		if string(s[i:i+len(sub)]) == string(sub) {
+			indexes = append(indexes, i)
+		}
+	}
+
+	return indexes
+}
+
+func anyMatched(matched []bool) bool {
+	for _, m := range matched {
+		if m {
+			return true
+		}
+	}
+
+	return false
+}
+
+func markMatched(matched []bool) {
+	for i := range matched {
+		matched[i] = true
+	}
+}
@@ -0,0 +1,40 @@
+package passwordpolicy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateStrength(t *testing.T) {
+	breached, err := DefaultBreachedHashes()
+	assert.NoError(t, err)
+
+	for password, want := range map[string]int{
+		"password":                     0,
+		"P@ssw0rd2021":                 0,
+		"hello12345":                   0,
+		"abcdefghijkl":                 0,
+		"qwertyuiop12":                 0,
+		"testpassword":                 1,
+		"Tr0ub4dor&3":                  4,
+		"kX9#mQ2$vL7!":                 4,
+		"correct horse battery staple": 4,
+	} {
+		assert.Equal(t, want, estimateStrength(password, nil, breached.Contains), password)
+	}
+
+	// 不区分大小写地识别重复与连续字符
+	assert.Equal(t, estimateBits("abcdef", nil, nil), estimateBits("ABCDEF", nil, nil))
+	assert.Less(t, estimateBits("aaaaaaaaaaaa", nil, nil), estimateBits("hxbwqmvrpzkt", nil, nil))
+
+	// 大小写转换后长度改变的字符不会导致越界
+	assert.NotPanics(t, func() {
+		estimateStrength("İİİİİİİİ", []string{"i̇i̇i̇"}, breached.Contains)
+	})
+}
+
+func TestUserInputTokens(t *testing.T) {
+	assert.Equal(t, []string{"bob", "smith", "example"}, userInputTokens("Bob.Smith@example.com"))
+	assert.Nil(t, userInputTokens("a@b.io"))
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+)
+
+// localImageRepository 将图片保存在本地目录中，由 baseURL 对外提供访问
+type localImageRepository struct {
+	Dir     string
+	BaseURL string
+}
+
+func NewLocalImageRepository(dir string, baseURL string) model.ImageRepository {
+	return &localImageRepository{
+		Dir:     dir,
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func (r *localImageRepository) UpdateProfile(ctx context.Context, objName string, image io.Reader, size int64, contentType string) (string, error) {
+	path, err := r.objectPath(objName)
+	if err != nil {
+		return "", err
+	}
+
+	// 先写入临时文件再重命名，避免读取到写了一半的图片
+	tmp, err := ioutil.TempFile(r.Dir, ".upload-*")
+	if err != nil {
+		log.Printf("无法在 %v 中创建临时文件：%v\n", r.Dir, err)
+		return "", apperrors.NewInternal()
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, image); err != nil {
+		tmp.Close()
+		log.Printf("无法写入图片 %v：%v\n", objName, err)
+		return "", apperrors.NewInternal()
+	}
+
+	if err := tmp.Close(); err != nil {
+		log.Printf("无法写入图片 %v：%v\n", objName, err)
+		return "", apperrors.NewInternal()
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		log.Printf("无法设置图片 %v 的权限：%v\n", objName, err)
+		return "", apperrors.NewInternal()
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		log.Printf("无法保存图片 %v：%v\n", objName, err)
+		return "", apperrors.NewInternal()
+	}
+
+	return fmt.Sprintf("%s/%s", r.BaseURL, objName), nil
+}
+
+func (r *localImageRepository) DeleteProfile(ctx context.Context, objName string) error {
+	path, err := r.objectPath(objName)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("无法删除图片 %v：%v\n", objName, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// objectPath 返回对象在本地目录中的路径，对象名不允许包含目录
+func (r *localImageRepository) objectPath(objName string) (string, error) {
+	if objName == "" || objName != filepath.Base(objName) || strings.HasPrefix(objName, ".") {
+		log.Printf("无效的图片对象名：%v\n", objName)
+		return "", apperrors.NewBadRequest("无效的图片对象名")
+	}
+
+	return filepath.Join(r.Dir, objName), nil
+}
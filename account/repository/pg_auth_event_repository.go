@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type pgAuthEventRepository struct {
+	DB *sqlx.DB
+}
+
+func NewAuthEventRepository(db *sqlx.DB) model.AuthEventRepository {
+	return &pgAuthEventRepository{
+		DB: db,
+	}
+}
+
+// Create 追加一条认证事件，UID 为 uuid.Nil 时保存为 NULL
+func (r *pgAuthEventRepository) Create(ctx context.Context, e *model.AuthEvent) error {
+	query := `
+		INSERT INTO auth_events (uid, type, detail, email, ip, user_agent)
+		VALUES (NULLIF($1, '00000000-0000-0000-0000-000000000000'::uuid), $2, $3, $4, $5, $6)
+		RETURNING id, created_at;
+	`
+
+	if err := r.DB.QueryRowxContext(ctx, query, e.UID, e.Type, e.Detail, e.Email, e.IP, e.UserAgent).Scan(&e.ID, &e.CreatedAt); err != nil {
+		log.Printf("无法保存认证事件 %v，uid：%v。原因是：%v\n", e.Type, e.UID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// ListByUID 按时间倒序返回用户的认证事件，beforeID 大于 0 时只返回 ID 小于 beforeID 的事件
+func (r *pgAuthEventRepository) ListByUID(ctx context.Context, uid uuid.UUID, beforeID int64, limit int) ([]*model.AuthEvent, error) {
+	query := `
+		SELECT * FROM auth_events
+		WHERE uid=$1 AND ($2 <= 0 OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3;
+	`
+
+	events := []*model.AuthEvent{}
+
+	if err := r.DB.SelectContext(ctx, &events, query, uid, beforeID, limit); err != nil {
+		log.Printf("无法查询用户的认证事件，uid：%v。原因是：%v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return events, nil
+}
+
+// DeleteBefore 删除最多 limit 条 before 之前的认证事件并返回删除的条数，分批删除以免长时间锁表
+func (r *pgAuthEventRepository) DeleteBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := `
+		DELETE FROM auth_events
+		WHERE id IN (
+			SELECT id FROM auth_events WHERE created_at < $1 ORDER BY id LIMIT $2
+		);
+	`
+
+	result, err := r.DB.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		log.Printf("无法删除 %v 之前的认证事件。原因是：%v\n", before, err)
+		return 0, apperrors.NewInternal()
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("无法获取删除的认证事件条数。原因是：%v\n", err)
+		return 0, apperrors.NewInternal()
+	}
+
+	return n, nil
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"log"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type pgDeviceRepository struct {
+	DB *sqlx.DB
+}
+
+func NewDeviceRepository(db *sqlx.DB) model.DeviceRepository {
+	return &pgDeviceRepository{
+		DB: db,
+	}
+}
+
+func (r *pgDeviceRepository) ListByUID(ctx context.Context, uid uuid.UUID) ([]*model.Device, error) {
+	query := "SELECT * FROM user_devices WHERE uid=$1 ORDER BY last_seen_at DESC"
+
+	devices := []*model.Device{}
+
+	if err := r.DB.SelectContext(ctx, &devices, query, uid); err != nil {
+		log.Printf("无法查询用户的设备，uid：%v。原因是：%v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return devices, nil
+}
+
+// Upsert 保存设备，已存在时更新所在位置与最近登录时间，d 为保存后的设备
+func (r *pgDeviceRepository) Upsert(ctx context.Context, d *model.Device) error {
+	query := `
+		INSERT INTO user_devices (uid, user_agent, network, location)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (uid, user_agent, network)
+		DO UPDATE SET location=EXCLUDED.location, last_seen_at=NOW()
+		RETURNING *;
+	`
+
+	if err := r.DB.GetContext(ctx, d, query, d.UID, d.UserAgent, d.Network, d.Location); err != nil {
+		log.Printf("无法保存用户的设备，uid：%v。原因是：%v\n", d.UID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+func (r *pgDeviceRepository) Delete(ctx context.Context, uid uuid.UUID, id uuid.UUID) error {
+	query := "DELETE FROM user_devices WHERE uid=$1 AND id=$2"
+
+	result, err := r.DB.ExecContext(ctx, query, uid, id)
+	if err != nil {
+		log.Printf("无法删除用户的设备，uid：%v，id：%v。原因是：%v\n", uid, id, err)
+		return apperrors.NewInternal()
+	}
+
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return apperrors.NewNotFound("device", id.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type pgPersonalAccessTokenRepository struct {
+	DB *sqlx.DB
+}
+
+func NewPersonalAccessTokenRepository(db *sqlx.DB) model.PersonalAccessTokenRepository {
+	return &pgPersonalAccessTokenRepository{
+		DB: db,
+	}
+}
+
+func (r *pgPersonalAccessTokenRepository) Create(ctx context.Context, t *model.PersonalAccessToken) error {
+	query := `
+		INSERT INTO personal_access_tokens (uid, name, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING *;
+	`
+
+	if err := r.DB.GetContext(ctx, t, query, t.UID, t.Name, t.TokenHash, t.Scopes, t.ExpiresAt); err != nil {
+		log.Printf("无法保存个人访问令牌，uid：%v。原因是：%v\n", t.UID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+func (r *pgPersonalAccessTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*model.PersonalAccessToken, error) {
+	t := &model.PersonalAccessToken{}
+
+	query := "SELECT * FROM personal_access_tokens WHERE token_hash=$1"
+
+	if err := r.DB.GetContext(ctx, t, query, tokenHash); err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("无法查询个人访问令牌。原因是：%v\n", err)
+			return t, apperrors.NewInternal()
+		}
+		return t, apperrors.NewNotFound("personal access token", "")
+	}
+
+	return t, nil
+}
+
+func (r *pgPersonalAccessTokenRepository) ListByUID(ctx context.Context, uid uuid.UUID) ([]*model.PersonalAccessToken, error) {
+	query := "SELECT * FROM personal_access_tokens WHERE uid=$1 ORDER BY created_at"
+
+	tokens := []*model.PersonalAccessToken{}
+
+	if err := r.DB.SelectContext(ctx, &tokens, query, uid); err != nil {
+		log.Printf("无法查询用户的个人访问令牌，uid：%v。原因是：%v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return tokens, nil
+}
+
+func (r *pgPersonalAccessTokenRepository) Delete(ctx context.Context, uid uuid.UUID, id uuid.UUID) error {
+	result, err := r.DB.ExecContext(ctx, "DELETE FROM personal_access_tokens WHERE id=$1 AND uid=$2", id, uid)
+	if err != nil {
+		log.Printf("无法删除个人访问令牌，id：%v。原因是：%v\n", id, err)
+		return apperrors.NewInternal()
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return apperrors.NewNotFound("personal access token", id.String())
+	}
+
+	return nil
+}
+
+// DeleteByUID 删除用户的所有个人访问令牌
+func (r *pgPersonalAccessTokenRepository) DeleteByUID(ctx context.Context, uid uuid.UUID) error {
+	if _, err := r.DB.ExecContext(ctx, "DELETE FROM personal_access_tokens WHERE uid=$1", uid); err != nil {
+		log.Printf("无法删除用户的个人访问令牌，uid：%v。原因是：%v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// UpdateLastUsed 记录令牌的使用时间。脚本可能频繁调用接口，一分钟内只更新一次
+func (r *pgPersonalAccessTokenRepository) UpdateLastUsed(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE personal_access_tokens
+		SET last_used_at=NOW()
+		WHERE id=$1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
+	`
+
+	if _, err := r.DB.ExecContext(ctx, query, id); err != nil {
+		log.Printf("无法更新个人访问令牌的使用时间，id：%v。原因是：%v\n", id, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
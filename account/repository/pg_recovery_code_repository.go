@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"log"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type pgRecoveryCodeRepository struct {
+	DB *sqlx.DB
+}
+
+func NewRecoveryCodeRepository(db *sqlx.DB) model.RecoveryCodeRepository {
+	return &pgRecoveryCodeRepository{
+		DB: db,
+	}
+}
+
+// ReplaceRecoveryCodes 在同一事务中删除旧的恢复码并写入新的恢复码
+func (r *pgRecoveryCodeRepository) ReplaceRecoveryCodes(ctx context.Context, uid uuid.UUID, codeHashes []string) error {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Printf("无法开始事务，uid：%v。原因是：%v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE uid=$1", uid); err != nil {
+		log.Printf("无法删除用户的恢复码，uid：%v。原因是：%v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	for _, codeHash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO recovery_codes (uid, code_hash) VALUES ($1, $2)", uid, codeHash); err != nil {
+			log.Printf("无法保存用户的恢复码，uid：%v。原因是：%v\n", uid, err)
+			return apperrors.NewInternal()
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("无法提交恢复码，uid：%v。原因是：%v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+func (r *pgRecoveryCodeRepository) CountUnusedRecoveryCodes(ctx context.Context, uid uuid.UUID) (int, error) {
+	query := "SELECT COUNT(*) FROM recovery_codes WHERE uid=$1 AND used_at IS NULL"
+
+	var count int
+
+	if err := r.DB.GetContext(ctx, &count, query, uid); err != nil {
+		log.Printf("无法统计用户的恢复码，uid：%v。原因是：%v\n", uid, err)
+		return 0, apperrors.NewInternal()
+	}
+
+	return count, nil
+}
+
+// UseRecoveryCode 按摘要将用户的恢复码标记为已使用，恢复码不存在或已被使用（如并发请求）时返回 NotFound
+func (r *pgRecoveryCodeRepository) UseRecoveryCode(ctx context.Context, uid uuid.UUID, codeHash string) error {
+	query := "UPDATE recovery_codes SET used_at=NOW() WHERE uid=$1 AND code_hash=$2 AND used_at IS NULL"
+
+	result, err := r.DB.ExecContext(ctx, query, uid, codeHash)
+	if err != nil {
+		log.Printf("无法使用恢复码，uid：%v。原因是：%v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return apperrors.NewNotFound("recovery code", uid.String())
+	}
+
+	return nil
+}
+
+func (r *pgRecoveryCodeRepository) DeleteRecoveryCodes(ctx context.Context, uid uuid.UUID) error {
+	if _, err := r.DB.ExecContext(ctx, "DELETE FROM recovery_codes WHERE uid=$1", uid); err != nil {
+		log.Printf("无法删除用户的恢复码，uid：%v。原因是：%v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"log"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type pgRoleRepository struct {
+	DB *sqlx.DB
+}
+
+func NewRoleRepository(db *sqlx.DB) model.RoleRepository {
+	return &pgRoleRepository{
+		DB: db,
+	}
+}
+
+func (r *pgRoleRepository) ListByUID(ctx context.Context, uid uuid.UUID) ([]*model.Role, error) {
+	query := `
+		SELECT r.name, r.description,
+			COALESCE(array_agg(p.permission ORDER BY p.permission) FILTER (WHERE p.permission IS NOT NULL), '{}') AS permissions
+		FROM user_roles ur
+		JOIN roles r ON r.name = ur.role
+		LEFT JOIN role_permissions p ON p.role = r.name
+		WHERE ur.uid=$1
+		GROUP BY r.name, r.description
+		ORDER BY r.name;
+	`
+
+	roles := []*model.Role{}
+
+	if err := r.DB.SelectContext(ctx, &roles, query, uid); err != nil {
+		log.Printf("无法查询用户的角色，uid：%v。原因是：%v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return roles, nil
+}
+
+func (r *pgRoleRepository) Assign(ctx context.Context, uid uuid.UUID, role string) error {
+	query := "INSERT INTO user_roles (uid, role) VALUES ($1, $2) ON CONFLICT (uid, role) DO NOTHING"
+
+	if _, err := r.DB.ExecContext(ctx, query, uid, role); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "foreign_key_violation" {
+			if err.Constraint == "user_roles_uid_fkey" {
+				return apperrors.NewNotFound("uid", uid.String())
+			}
+			return apperrors.NewNotFound("role", role)
+		}
+
+		log.Printf("无法为用户分配角色，uid：%v，role：%v。原因是：%v\n", uid, role, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+func (r *pgRoleRepository) Unassign(ctx context.Context, uid uuid.UUID, role string) error {
+	result, err := r.DB.ExecContext(ctx, "DELETE FROM user_roles WHERE uid=$1 AND role=$2", uid, role)
+	if err != nil {
+		log.Printf("无法移除用户的角色，uid：%v，role：%v。原因是：%v\n", uid, role, err)
+		return apperrors.NewInternal()
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return apperrors.NewNotFound("role", role)
+	}
+
+	return nil
+}
@@ -2,7 +2,9 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"log"
+	"strconv"
 
 	"github.com/FuZhouJohn/memrizr/account/model"
 	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
@@ -60,3 +62,186 @@ func (r *pgUserRepository) FindByEmail(ctx context.Context, email string) (*mode
 
 	return user, nil
 }
+
+func (r *pgUserRepository) Update(ctx context.Context, u *model.User) error {
+	query := `
+		UPDATE users
+		SET name=:name, email=:email, website=:website, email_verified=(email_verified AND email=:email)
+		WHERE uid=:uid
+		RETURNING *;
+	`
+
+	nstmt, err := r.DB.PrepareNamedContext(ctx, query)
+
+	if err != nil {
+		log.Printf("无法准备更新用户详情的语句：%v\n", err)
+		return apperrors.NewInternal()
+	}
+	defer nstmt.Close()
+
+	if err := nstmt.GetContext(ctx, u, u); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
+			log.Printf("无法将用户邮箱更新为：%v。原因是：%v\n", u.Email, err.Code.Name())
+			return apperrors.NewConflict("email", u.Email)
+		}
+
+		log.Printf("无法更新用户详情，uid：%v。原因是：%v\n", u.UID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+func (r *pgUserRepository) UpdateImage(ctx context.Context, uid uuid.UUID, imageURL string) (*model.User, error) {
+	query := `
+		UPDATE users
+		SET image_url=$2
+		WHERE uid=$1
+		RETURNING *;
+	`
+
+	u := &model.User{}
+
+	if err := r.DB.GetContext(ctx, u, query, uid, imageURL); err != nil {
+		log.Printf("无法更新用户头像，uid：%v。原因是：%v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return u, nil
+}
+
+// SetEmailVerified 将用户的邮箱标记为已验证，仅当用户当前的邮箱仍为 email 时生效
+func (r *pgUserRepository) SetEmailVerified(ctx context.Context, uid uuid.UUID, email string) (*model.User, error) {
+	query := `
+		UPDATE users
+		SET email_verified=TRUE
+		WHERE uid=$1 AND email=$2
+		RETURNING *;
+	`
+
+	u := &model.User{}
+
+	if err := r.DB.GetContext(ctx, u, query, uid, email); err != nil {
+		if err == sql.ErrNoRows {
+			log.Printf("无法验证邮箱，用户 %v 的邮箱已不是 %v\n", uid, email)
+			return nil, apperrors.NewNotFound("email", email)
+		}
+
+		log.Printf("无法验证邮箱，uid：%v。原因是：%v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return u, nil
+}
+
+func (r *pgUserRepository) UpdatePassword(ctx context.Context, uid uuid.UUID, password string) error {
+	query := "UPDATE users SET password=$2 WHERE uid=$1"
+
+	result, err := r.DB.ExecContext(ctx, query, uid, password)
+	if err != nil {
+		log.Printf("无法更新用户密码，uid：%v。原因是：%v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return nil
+}
+
+// RehashPassword 仅当密码哈希仍是 oldPassword 时才替换为新的哈希，
+// 避免登录时的升级覆盖同时进行的修改或重置密码
+func (r *pgUserRepository) RehashPassword(ctx context.Context, uid uuid.UUID, oldPassword string, password string) error {
+	query := "UPDATE users SET password=$2 WHERE uid=$1 AND password=$3"
+
+	result, err := r.DB.ExecContext(ctx, query, uid, password, oldPassword)
+	if err != nil {
+		log.Printf("无法升级用户密码哈希，uid：%v。原因是：%v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return apperrors.NewNotFound("password", uid.String())
+	}
+
+	return nil
+}
+
+// UpdateTOTP 保存用户的 TOTP 密钥与启用状态，重新绑定时清除已使用的时间步
+func (r *pgUserRepository) UpdateTOTP(ctx context.Context, uid uuid.UUID, secret string, enabled bool) (*model.User, error) {
+	query := `
+		UPDATE users
+		SET totp_secret=$2, totp_enabled=$3, totp_last_used_step=CASE WHEN totp_secret=$2 THEN totp_last_used_step ELSE 0 END
+		WHERE uid=$1
+		RETURNING *;
+	`
+
+	u := &model.User{}
+
+	if err := r.DB.GetContext(ctx, u, query, uid, secret, enabled); err != nil {
+		log.Printf("无法更新用户的 TOTP 设置，uid：%v。原因是：%v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return u, nil
+}
+
+// UseTOTPStep 记录验证码所在的时间步，同一时间步及更早的验证码不能再次使用，
+// 时间步不大于已记录的值时返回 NotFound
+func (r *pgUserRepository) UseTOTPStep(ctx context.Context, uid uuid.UUID, step int64) error {
+	query := "UPDATE users SET totp_last_used_step=$2 WHERE uid=$1 AND totp_last_used_step < $2"
+
+	result, err := r.DB.ExecContext(ctx, query, uid, step)
+	if err != nil {
+		log.Printf("无法记录 TOTP 时间步，uid：%v。原因是：%v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return apperrors.NewNotFound("totp step", strconv.FormatInt(step, 10))
+	}
+
+	return nil
+}
+
+// RecordFailedSignin 累加密码错误次数，达到 threshold 时清零并锁定账号，
+// 第 n 次锁定的时长为 lockoutSecs[n-1]，超出时使用最后一项。在同一语句中完成，避免并发请求重复锁定
+func (r *pgUserRepository) RecordFailedSignin(ctx context.Context, uid uuid.UUID, threshold int, lockoutSecs []int64) (*model.User, error) {
+	query := `
+		UPDATE users
+		SET failed_signin_count=CASE WHEN failed_signin_count + 1 >= $2 THEN 0 ELSE failed_signin_count + 1 END,
+			lockout_count=CASE WHEN failed_signin_count + 1 >= $2 THEN lockout_count + 1 ELSE lockout_count END,
+			locked_until=CASE WHEN failed_signin_count + 1 >= $2
+				THEN now() + make_interval(secs => ($3::BIGINT[])[LEAST(lockout_count + 1, cardinality($3::BIGINT[]))])
+				ELSE locked_until END
+		WHERE uid=$1
+		RETURNING *;
+	`
+
+	u := &model.User{}
+
+	if err := r.DB.GetContext(ctx, u, query, uid, threshold, pq.Array(lockoutSecs)); err != nil {
+		log.Printf("无法记录密码错误次数，uid：%v。原因是：%v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return u, nil
+}
+
+// ResetSigninFailures 清除密码错误次数、锁定次数与锁定状态，用户不存在时返回 NotFound
+func (r *pgUserRepository) ResetSigninFailures(ctx context.Context, uid uuid.UUID) error {
+	query := "UPDATE users SET failed_signin_count=0, lockout_count=0, locked_until=NULL WHERE uid=$1"
+
+	result, err := r.DB.ExecContext(ctx, query, uid)
+	if err != nil {
+		log.Printf("无法解除账号锁定，uid：%v。原因是：%v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return nil
+}
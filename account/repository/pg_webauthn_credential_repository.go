@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strconv"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type pgWebAuthnCredentialRepository struct {
+	DB *sqlx.DB
+}
+
+func NewWebAuthnCredentialRepository(db *sqlx.DB) model.WebAuthnCredentialRepository {
+	return &pgWebAuthnCredentialRepository{
+		DB: db,
+	}
+}
+
+func (r *pgWebAuthnCredentialRepository) Create(ctx context.Context, c *model.WebAuthnCredential) error {
+	query := `
+		INSERT INTO webauthn_credentials (id, uid, public_key, sign_count, name)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING *;
+	`
+
+	if err := r.DB.GetContext(ctx, c, query, c.ID, c.UID, c.PublicKey, c.SignCount, c.Name); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
+			log.Printf("通行密钥已被注册：%v\n", c.ID)
+			return apperrors.NewConflict("credential", c.ID)
+		}
+
+		log.Printf("无法保存通行密钥，uid：%v。原因是：%v\n", c.UID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+func (r *pgWebAuthnCredentialRepository) FindByID(ctx context.Context, id string) (*model.WebAuthnCredential, error) {
+	c := &model.WebAuthnCredential{}
+
+	query := "SELECT * FROM webauthn_credentials WHERE id=$1"
+
+	if err := r.DB.GetContext(ctx, c, query, id); err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("无法查询通行密钥 %v。原因是：%v\n", id, err)
+		}
+		return c, apperrors.NewNotFound("credential", id)
+	}
+
+	return c, nil
+}
+
+func (r *pgWebAuthnCredentialRepository) ListByUID(ctx context.Context, uid uuid.UUID) ([]*model.WebAuthnCredential, error) {
+	query := "SELECT * FROM webauthn_credentials WHERE uid=$1 ORDER BY created_at"
+
+	credentials := []*model.WebAuthnCredential{}
+
+	if err := r.DB.SelectContext(ctx, &credentials, query, uid); err != nil {
+		log.Printf("无法查询用户的通行密钥，uid：%v。原因是：%v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return credentials, nil
+}
+
+// UpdateSignCount 记录登录时的签名计数与时间。计数为 0 表示验证器不支持计数（如同步的通行密钥），
+// 否则只能递增，不满足时返回 NotFound，并发使用同一签名计数的请求中只有一个能成功
+func (r *pgWebAuthnCredentialRepository) UpdateSignCount(ctx context.Context, id string, signCount int64) error {
+	query := `
+		UPDATE webauthn_credentials
+		SET sign_count=$2, last_used_at=NOW()
+		WHERE id=$1 AND ($2 = 0 OR sign_count < $2);
+	`
+
+	result, err := r.DB.ExecContext(ctx, query, id, signCount)
+	if err != nil {
+		log.Printf("无法更新通行密钥的签名计数，id：%v。原因是：%v\n", id, err)
+		return apperrors.NewInternal()
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return apperrors.NewNotFound("sign count", strconv.FormatInt(signCount, 10))
+	}
+
+	return nil
+}
+
+func (r *pgWebAuthnCredentialRepository) Delete(ctx context.Context, uid uuid.UUID, id string) error {
+	result, err := r.DB.ExecContext(ctx, "DELETE FROM webauthn_credentials WHERE id=$1 AND uid=$2", id, uid)
+	if err != nil {
+		log.Printf("无法删除通行密钥，id：%v。原因是：%v\n", id, err)
+		return apperrors.NewInternal()
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return apperrors.NewNotFound("credential", id)
+	}
+
+	return nil
+}
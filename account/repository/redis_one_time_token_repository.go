@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/go-redis/redis/v8"
+)
+
+type redisOneTimeTokenRepository struct {
+	Redis *redis.Client
+}
+
+func NewOneTimeTokenRepository(redisClient *redis.Client) model.OneTimeTokenRepository {
+	return &redisOneTimeTokenRepository{
+		Redis: redisClient,
+	}
+}
+
+func oneTimeTokenKey(purpose string, tokenID string) string {
+	return fmt.Sprintf("one_time_token:%s:%s", purpose, tokenID)
+}
+
+// SetOneTimeToken 保存令牌所属的用户，令牌过期后自动删除
+func (r *redisOneTimeTokenRepository) SetOneTimeToken(ctx context.Context, purpose string, tokenID string, userID string, expiresIn time.Duration) error {
+	key := oneTimeTokenKey(purpose, tokenID)
+
+	if err := r.Redis.Set(ctx, key, userID, expiresIn).Err(); err != nil {
+		log.Printf("无法保存 %v 令牌，uid：%v。原因是：%v\n", purpose, userID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// ConsumeOneTimeToken 返回令牌所属的用户并删除令牌，令牌不存在、已过期或已被使用时返回 Authorization 错误
+func (r *redisOneTimeTokenRepository) ConsumeOneTimeToken(ctx context.Context, purpose string, tokenID string) (string, error) {
+	key := oneTimeTokenKey(purpose, tokenID)
+
+	// GET 与 DEL 在同一事务中执行，保证并发请求中只有一个能取得令牌
+	var get *redis.StringCmd
+	_, err := r.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pipe.Del(ctx, key)
+		return nil
+	})
+
+	if err == redis.Nil {
+		return "", apperrors.NewAuthorization("令牌无效、已过期或已被使用")
+	}
+
+	if err != nil {
+		log.Printf("无法读取 %v 令牌 %v。原因是：%v\n", purpose, tokenID, err)
+		return "", apperrors.NewInternal()
+	}
+
+	return get.Val(), nil
+}
+
+// GetOneTimeToken 返回令牌所属的用户但不删除令牌，令牌不存在或已过期时返回 Authorization 错误
+func (r *redisOneTimeTokenRepository) GetOneTimeToken(ctx context.Context, purpose string, tokenID string) (string, error) {
+	userID, err := r.Redis.Get(ctx, oneTimeTokenKey(purpose, tokenID)).Result()
+
+	if err == redis.Nil {
+		return "", apperrors.NewAuthorization("令牌无效、已过期或已被使用")
+	}
+
+	if err != nil {
+		log.Printf("无法读取 %v 令牌 %v。原因是：%v\n", purpose, tokenID, err)
+		return "", apperrors.NewInternal()
+	}
+
+	return userID, nil
+}
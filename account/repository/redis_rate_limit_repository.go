@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// slidingWindowScript 以有序集合记录窗口内每次请求的时间（毫秒），
+// 清理、计数与记录在同一脚本中执行，保证并发请求不会超出限制。
+// 超出限制时返回最早一次请求移出窗口前需要等待的毫秒数
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+
+if redis.call('ZCARD', KEYS[1]) >= limit then
+	local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+	return tonumber(oldest[2]) + window - now
+end
+
+redis.call('ZADD', KEYS[1], now, ARGV[4])
+redis.call('PEXPIRE', KEYS[1], window)
+return 0
+`)
+
+type redisRateLimitRepository struct {
+	Redis *redis.Client
+}
+
+func NewRateLimitRepository(redisClient *redis.Client) model.RateLimitRepository {
+	return &redisRateLimitRepository{
+		Redis: redisClient,
+	}
+}
+
+func rateLimitKey(key string) string {
+	return fmt.Sprintf("rate_limit:%s", key)
+}
+
+// Hit 记录一次请求，窗口内已有 limit 次请求时返回需要等待的时间
+func (r *redisRateLimitRepository) Hit(ctx context.Context, key string, limit int, window time.Duration) (time.Duration, error) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	// 同一毫秒内可能有多次请求，成员需要唯一
+	member := uuid.New().String()
+
+	wait, err := slidingWindowScript.Run(ctx, r.Redis, []string{rateLimitKey(key)}, now, window.Milliseconds(), limit, member).Int64()
+	if err != nil {
+		log.Printf("无法记录 %v 的请求次数。原因是：%v\n", key, err)
+		return 0, apperrors.NewInternal()
+	}
+
+	return time.Duration(wait) * time.Millisecond, nil
+}
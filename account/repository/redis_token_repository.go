@@ -2,8 +2,12 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/FuZhouJohn/memrizr/account/model"
@@ -11,6 +15,25 @@ import (
 	"github.com/go-redis/redis/v8"
 )
 
+const (
+	// 每个用户最多保留的安全事件条数
+	maxSecurityEvents = 100
+	// SCAN 每次迭代建议返回的 key 数量，同时作为批量删除的大小
+	scanBatchSize = 100
+)
+
+// rotateRefreshTokenScript 仅当 family 仍存在且最新的令牌仍是 ARGV[1] 时才写入新的令牌，
+// 比较与写入在 Redis 中原子执行，并发使用同一个刷新令牌时只有一个请求能成功
+var rotateRefreshTokenScript = redis.NewScript(`
+if redis.call("HGET", KEYS[1], "token") ~= ARGV[1] then
+	return 0
+end
+redis.call("SET", KEYS[2], ARGV[3], "PX", ARGV[7])
+redis.call("HSET", KEYS[1], "token", ARGV[2], "userAgent", ARGV[4], "ip", ARGV[5], "lastRefreshedAt", ARGV[6])
+redis.call("PEXPIRE", KEYS[1], ARGV[7])
+return 1
+`)
+
 type redisTokenRepository struct {
 	Redis *redis.Client
 }
@@ -21,19 +44,226 @@ func NewTokenRepository(redisClient *redis.Client) model.TokenRepository {
 	}
 }
 
-func (r *redisTokenRepository) SetRefreshToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) error {
-	key := fmt.Sprintf("%s:%s", userID, tokenID)
-	if err := r.Redis.Set(ctx, key, 0, expiresIn).Err(); err != nil {
+// refreshTokenKey 保存刷新令牌所属的 family，轮换后仍保留至过期，用于识别重复使用
+func refreshTokenKey(userID string, tokenID string) string {
+	return fmt.Sprintf("%s:%s", userID, tokenID)
+}
+
+// tokenFamilyKey 以 hash 保存 family 中最新签发的刷新令牌 ID 及会话信息
+func tokenFamilyKey(userID string, familyID string) string {
+	return fmt.Sprintf("%s:family:%s", userID, familyID)
+}
+
+func securityEventsKey(userID string) string {
+	return fmt.Sprintf("security_events:%s", userID)
+}
+
+// SetRefreshToken 保存新签发的刷新令牌，并将其设为 family 中最新的令牌，同时更新会话信息
+func (r *redisTokenRepository) SetRefreshToken(ctx context.Context, userID string, tokenID string, family *model.TokenFamily, expiresIn time.Duration) error {
+	tokenKey := refreshTokenKey(userID, tokenID)
+	familyKey := tokenFamilyKey(userID, family.ID)
+
+	_, err := r.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, tokenKey, family.ID, expiresIn)
+		pipe.HSet(ctx, familyKey,
+			"token", tokenID,
+			"userAgent", family.UserAgent,
+			"ip", family.IP,
+			"lastRefreshedAt", family.LastRefreshedAt.Unix(),
+		)
+		pipe.HSetNX(ctx, familyKey, "createdAt", family.CreatedAt.Unix())
+		pipe.Expire(ctx, familyKey, expiresIn)
+		return nil
+	})
+
+	if err != nil {
 		log.Printf("保存 refreshToken 失败，userID/TokenID-%s/%s：%v\n", userID, tokenID, err)
 		return apperrors.NewInternal()
 	}
 	return nil
 }
 
-func (r *redisTokenRepository) DeleteRefreshToken(ctx context.Context, userID string, tokenID string) error {
-	key := fmt.Sprintf("%s:%s", userID, tokenID)
-	if err := r.Redis.Del(ctx, key).Err(); err != nil {
-		log.Printf("删除 refreshToken 失败，userID/TokenID-%s/%s：%v\n", userID, tokenID, err)
+// RotateRefreshToken 将 family 中最新的令牌由 prevTokenID 轮换为 tokenID，并更新会话信息。
+// 若 family 已被撤销或 prevTokenID 已被轮换，不做任何写入并返回 false
+func (r *redisTokenRepository) RotateRefreshToken(ctx context.Context, userID string, prevTokenID string, tokenID string, family *model.TokenFamily, expiresIn time.Duration) (bool, error) {
+	keys := []string{
+		tokenFamilyKey(userID, family.ID),
+		refreshTokenKey(userID, tokenID),
+	}
+
+	rotated, err := rotateRefreshTokenScript.Run(ctx, r.Redis, keys,
+		prevTokenID,
+		tokenID,
+		family.ID,
+		family.UserAgent,
+		family.IP,
+		family.LastRefreshedAt.Unix(),
+		expiresIn.Milliseconds(),
+	).Int()
+
+	if err != nil {
+		log.Printf("轮换 refreshToken 失败，userID/TokenID-%s/%s：%v\n", userID, prevTokenID, err)
+		return false, apperrors.NewInternal()
+	}
+
+	return rotated == 1, nil
+}
+
+func (r *redisTokenRepository) GetTokenFamily(ctx context.Context, userID string, tokenID string) (*model.TokenFamily, error) {
+	familyID, err := r.Redis.Get(ctx, refreshTokenKey(userID, tokenID)).Result()
+
+	if err == redis.Nil {
+		log.Printf("refreshToken 不存在，userID/TokenID-%s/%s\n", userID, tokenID)
+		return nil, apperrors.NewAuthorization("无效的刷新令牌")
+	}
+
+	if err != nil {
+		log.Printf("获取 refreshToken 失败，userID/TokenID-%s/%s：%v\n", userID, tokenID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	fields, err := r.Redis.HGetAll(ctx, tokenFamilyKey(userID, familyID)).Result()
+
+	if err != nil {
+		log.Printf("获取 family 失败，userID/familyID-%s/%s：%v\n", userID, familyID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	// family 已被撤销，其中的令牌全部失效
+	if len(fields) == 0 {
+		log.Printf("refreshToken 所属的 family 已失效，userID/familyID-%s/%s\n", userID, familyID)
+		return nil, apperrors.NewAuthorization("无效的刷新令牌")
+	}
+
+	return tokenFamilyFromFields(familyID, fields), nil
+}
+
+func (r *redisTokenRepository) ListTokenFamilies(ctx context.Context, userID string) ([]*model.TokenFamily, error) {
+	pattern := tokenFamilyKey(userID, "*")
+	prefix := tokenFamilyKey(userID, "")
+
+	families := []*model.TokenFamily{}
+
+	iter := r.Redis.Scan(ctx, 0, pattern, scanBatchSize).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+
+		fields, err := r.Redis.HGetAll(ctx, key).Result()
+		if err != nil {
+			log.Printf("获取 family 失败，key-%s：%v\n", key, err)
+			return nil, apperrors.NewInternal()
+		}
+
+		// 遍历期间 family 可能已过期或被撤销
+		if len(fields) == 0 {
+			continue
+		}
+
+		families = append(families, tokenFamilyFromFields(strings.TrimPrefix(key, prefix), fields))
+	}
+
+	if err := iter.Err(); err != nil {
+		log.Printf("遍历用户 family 失败，userID-%s：%v\n", userID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].LastRefreshedAt.After(families[j].LastRefreshedAt)
+	})
+
+	return families, nil
+}
+
+// DeleteTokenFamily 撤销 family，其中的所有刷新令牌随之失效
+func (r *redisTokenRepository) DeleteTokenFamily(ctx context.Context, userID string, familyID string) error {
+	result := r.Redis.Del(ctx, tokenFamilyKey(userID, familyID))
+	if err := result.Err(); err != nil {
+		log.Printf("删除 family 失败，userID/familyID-%s/%s：%v\n", userID, familyID, err)
+		return apperrors.NewInternal()
+	}
+
+	if result.Val() < 1 {
+		return apperrors.NewNotFound("session", familyID)
+	}
+	return nil
+}
+
+func tokenFamilyFromFields(familyID string, fields map[string]string) *model.TokenFamily {
+	return &model.TokenFamily{
+		ID:              familyID,
+		TokenID:         fields["token"],
+		UserAgent:       fields["userAgent"],
+		IP:              fields["ip"],
+		CreatedAt:       unixFieldToTime(fields["createdAt"]),
+		LastRefreshedAt: unixFieldToTime(fields["lastRefreshedAt"]),
+	}
+}
+
+func unixFieldToTime(field string) time.Time {
+	sec, err := strconv.ParseInt(field, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// DeleteUserRefreshTokens 删除用户所有的刷新令牌及 family。
+// 使用 SCAN 而非 KEYS 遍历，避免在 key 较多时阻塞 Redis
+func (r *redisTokenRepository) DeleteUserRefreshTokens(ctx context.Context, userID string) error {
+	pattern := fmt.Sprintf("%s:*", userID)
+
+	iter := r.Redis.Scan(ctx, 0, pattern, scanBatchSize).Iterator()
+	keys := make([]string, 0, scanBatchSize)
+	failCount := 0
+
+	deleteKeys := func() {
+		if err := r.Redis.Del(ctx, keys...).Err(); err != nil {
+			log.Printf("删除用户 refreshToken 失败，userID-%s：%v\n", userID, err)
+			failCount++
+		}
+		keys = keys[:0]
+	}
+
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) == scanBatchSize {
+			deleteKeys()
+		}
+	}
+
+	if len(keys) > 0 {
+		deleteKeys()
+	}
+
+	if err := iter.Err(); err != nil {
+		log.Printf("遍历用户 refreshToken 失败，userID-%s：%v\n", userID, err)
+		return apperrors.NewInternal()
+	}
+
+	if failCount > 0 {
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+func (r *redisTokenRepository) AddSecurityEvent(ctx context.Context, e *model.SecurityEvent) error {
+	event, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("序列化安全事件失败：%v\n", err)
+		return apperrors.NewInternal()
+	}
+
+	key := securityEventsKey(e.UserID)
+
+	_, err = r.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(ctx, key, event)
+		pipe.LTrim(ctx, key, 0, maxSecurityEvents-1)
+		return nil
+	})
+
+	if err != nil {
+		log.Printf("保存安全事件失败，userID-%s：%v\n", e.UserID, err)
 		return apperrors.NewInternal()
 	}
 	return nil
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/minio/minio-go/v7"
+)
+
+// s3ImageRepository 将图片保存在 S3 兼容的对象存储中（AWS S3、MinIO 等）
+type s3ImageRepository struct {
+	Storage    *minio.Client
+	BucketName string
+	BaseURL    string
+}
+
+func NewS3ImageRepository(storage *minio.Client, bucketName string, baseURL string) model.ImageRepository {
+	return &s3ImageRepository{
+		Storage:    storage,
+		BucketName: bucketName,
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func (r *s3ImageRepository) UpdateProfile(ctx context.Context, objName string, image io.Reader, size int64, contentType string) (string, error) {
+	// 每次上传都使用新的对象名，因此可以长期缓存
+	_, err := r.Storage.PutObject(ctx, r.BucketName, objName, image, size, minio.PutObjectOptions{
+		ContentType:  contentType,
+		CacheControl: "public, max-age=31536000, immutable",
+	})
+
+	if err != nil {
+		log.Printf("无法上传图片 %v 至存储桶 %v：%v\n", objName, r.BucketName, err)
+		return "", apperrors.NewInternal()
+	}
+
+	return fmt.Sprintf("%s/%s", r.BaseURL, objName), nil
+}
+
+func (r *s3ImageRepository) DeleteProfile(ctx context.Context, objName string) error {
+	// 对象不存在时 RemoveObject 不会返回错误
+	if err := r.Storage.RemoveObject(ctx, r.BucketName, objName, minio.RemoveObjectOptions{}); err != nil {
+		log.Printf("无法从存储桶 %v 中删除图片 %v：%v\n", r.BucketName, objName, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
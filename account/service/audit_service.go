@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultActivityLimit = 50
+	maxActivityLimit     = 100
+	// 每批删除的过期事件条数
+	auditPurgeBatchSize = 1000
+	// 记录事件不使用请求的 context，客户端断开连接时仍然保存，但最多等待该时长
+	auditLogTimeout = 5 * time.Second
+)
+
+type auditService struct {
+	AuthEventRepository model.AuthEventRepository
+	Retention           time.Duration
+}
+
+// ASConfig 中 Retention 为认证事件的保留期，为 0 时不删除
+type ASConfig struct {
+	AuthEventRepository model.AuthEventRepository
+	Retention           time.Duration
+}
+
+func NewAuditService(c *ASConfig) model.AuditService {
+	return &auditService{
+		AuthEventRepository: c.AuthEventRepository,
+		Retention:           c.Retention,
+	}
+}
+
+func (s *auditService) Log(ctx context.Context, e *model.AuthEvent) {
+	if client := model.ClientInfoFromContext(ctx); client != nil {
+		if e.IP == "" {
+			e.IP = client.IP
+		}
+		if e.UserAgent == "" {
+			e.UserAgent = client.UserAgent
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), auditLogTimeout)
+	defer cancel()
+
+	if err := s.AuthEventRepository.Create(ctx, e); err != nil {
+		log.Printf("无法记录认证事件 %v，uid：%v，email：%v，ip：%v\n", e.Type, e.UID, e.Email, e.IP)
+	}
+}
+
+// ListActivity 按时间倒序返回用户的认证事件，beforeID 为上一页最后一条事件的 ID，limit 超出范围时使用默认值
+func (s *auditService) ListActivity(ctx context.Context, uid uuid.UUID, beforeID int64, limit int) ([]*model.AuthEvent, error) {
+	if limit <= 0 || limit > maxActivityLimit {
+		limit = defaultActivityLimit
+	}
+
+	return s.AuthEventRepository.ListByUID(ctx, uid, beforeID, limit)
+}
+
+// PurgeExpired 分批删除超出保留期的认证事件，返回删除的总条数
+func (s *auditService) PurgeExpired(ctx context.Context) (int64, error) {
+	if s.Retention <= 0 {
+		return 0, nil
+	}
+
+	before := time.Now().Add(-s.Retention)
+
+	var total int64
+	for {
+		n, err := s.AuthEventRepository.DeleteBefore(ctx, before, auditPurgeBatchSize)
+		total += n
+		if err != nil {
+			return total, err
+		}
+
+		if n < auditPurgeBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// RunAuditRetention 每隔 interval 删除一次超出保留期的认证事件，直到 ctx 被取消
+func RunAuditRetention(ctx context.Context, s model.AuditService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.PurgeExpired(ctx); err != nil {
+			log.Printf("删除过期的认证事件失败：%v\n", err)
+		} else if n > 0 {
+			log.Printf("已删除 %v 条过期的认证事件\n", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// logAuthEvent 在 l 不为 nil 时记录认证事件
+func logAuthEvent(ctx context.Context, l model.AuditLogger, eventType string, uid uuid.UUID, email string, detail string) {
+	if l == nil {
+		return
+	}
+
+	l.Log(ctx, &model.AuthEvent{
+		UID:    uid,
+		Type:   eventType,
+		Detail: detail,
+		Email:  email,
+	})
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAuditLog(t *testing.T) {
+	t.Run("从 context 中获取客户端信息", func(t *testing.T) {
+		mockAuthEventRepository := new(mocks.MockAuthEventRepository)
+		mockAuthEventRepository.On("Create", mock.Anything, mock.AnythingOfType("*model.AuthEvent")).Return(nil)
+
+		s := NewAuditService(&ASConfig{
+			AuthEventRepository: mockAuthEventRepository,
+		})
+
+		uid, _ := uuid.NewRandom()
+		ctx := model.ContextWithClientInfo(context.Background(), &model.ClientInfo{
+			UserAgent: "Mozilla/5.0",
+			IP:        "203.0.113.7",
+		})
+
+		s.Log(ctx, &model.AuthEvent{
+			UID:  uid,
+			Type: model.AuthEventSignup,
+		})
+
+		mockAuthEventRepository.AssertCalled(t, "Create", mock.Anything, &model.AuthEvent{
+			UID:       uid,
+			Type:      model.AuthEventSignup,
+			IP:        "203.0.113.7",
+			UserAgent: "Mozilla/5.0",
+		})
+	})
+
+	t.Run("请求已取消时仍然记录", func(t *testing.T) {
+		mockAuthEventRepository := new(mocks.MockAuthEventRepository)
+		mockAuthEventRepository.
+			On("Create", mock.Anything, mock.AnythingOfType("*model.AuthEvent")).
+			Run(func(args mock.Arguments) {
+				assert.NoError(t, args.Get(0).(context.Context).Err())
+			}).
+			Return(nil)
+
+		s := NewAuditService(&ASConfig{
+			AuthEventRepository: mockAuthEventRepository,
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		s.Log(ctx, &model.AuthEvent{Type: model.AuthEventSigninFailure, Email: "hello@world.com"})
+
+		mockAuthEventRepository.AssertNumberOfCalls(t, "Create", 1)
+	})
+
+	t.Run("保存失败不返回错误", func(t *testing.T) {
+		mockAuthEventRepository := new(mocks.MockAuthEventRepository)
+		mockAuthEventRepository.On("Create", mock.Anything, mock.AnythingOfType("*model.AuthEvent")).Return(apperrors.NewInternal())
+
+		s := NewAuditService(&ASConfig{
+			AuthEventRepository: mockAuthEventRepository,
+		})
+
+		assert.NotPanics(t, func() {
+			s.Log(context.Background(), &model.AuthEvent{Type: model.AuthEventSignout})
+		})
+	})
+}
+
+func TestListActivity(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	mockEvents := []*model.AuthEvent{
+		{ID: 2, UID: uid, Type: model.AuthEventSigninSuccess},
+		{ID: 1, UID: uid, Type: model.AuthEventSignup},
+	}
+
+	mockAuthEventRepository := new(mocks.MockAuthEventRepository)
+	mockAuthEventRepository.On("ListByUID", mock.AnythingOfType("*context.emptyCtx"), uid, int64(0), defaultActivityLimit).Return(mockEvents, nil)
+	mockAuthEventRepository.On("ListByUID", mock.AnythingOfType("*context.emptyCtx"), uid, int64(10), 20).Return(mockEvents[1:], nil)
+
+	s := NewAuditService(&ASConfig{
+		AuthEventRepository: mockAuthEventRepository,
+	})
+
+	ctx := context.Background()
+
+	events, err := s.ListActivity(ctx, uid, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, mockEvents, events)
+
+	// 超出上限时使用默认值
+	events, err = s.ListActivity(ctx, uid, 0, maxActivityLimit+1)
+	assert.NoError(t, err)
+	assert.Equal(t, mockEvents, events)
+
+	events, err = s.ListActivity(ctx, uid, 10, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, mockEvents[1:], events)
+
+	mockAuthEventRepository.AssertNumberOfCalls(t, "ListByUID", 3)
+}
+
+func TestPurgeExpired(t *testing.T) {
+	retention := 365 * 24 * time.Hour
+	isBeforeCutoff := mock.MatchedBy(func(before time.Time) bool {
+		cutoff := time.Now().Add(-retention)
+		return !before.After(cutoff) && before.After(cutoff.Add(-time.Minute))
+	})
+
+	t.Run("分批删除直到不足一批", func(t *testing.T) {
+		mockAuthEventRepository := new(mocks.MockAuthEventRepository)
+		mockAuthEventRepository.On("DeleteBefore", mock.AnythingOfType("*context.emptyCtx"), isBeforeCutoff, auditPurgeBatchSize).Return(int64(auditPurgeBatchSize), nil).Twice()
+		mockAuthEventRepository.On("DeleteBefore", mock.AnythingOfType("*context.emptyCtx"), isBeforeCutoff, auditPurgeBatchSize).Return(int64(3), nil).Once()
+
+		s := NewAuditService(&ASConfig{
+			AuthEventRepository: mockAuthEventRepository,
+			Retention:           retention,
+		})
+
+		n, err := s.PurgeExpired(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2*auditPurgeBatchSize+3), n)
+		mockAuthEventRepository.AssertExpectations(t)
+	})
+
+	t.Run("删除失败", func(t *testing.T) {
+		mockAuthEventRepository := new(mocks.MockAuthEventRepository)
+		mockAuthEventRepository.On("DeleteBefore", mock.AnythingOfType("*context.emptyCtx"), isBeforeCutoff, auditPurgeBatchSize).Return(int64(0), fmt.Errorf("数据库错误"))
+
+		s := NewAuditService(&ASConfig{
+			AuthEventRepository: mockAuthEventRepository,
+			Retention:           retention,
+		})
+
+		_, err := s.PurgeExpired(context.Background())
+
+		assert.Error(t, err)
+	})
+
+	t.Run("未设置保留期时不删除", func(t *testing.T) {
+		mockAuthEventRepository := new(mocks.MockAuthEventRepository)
+
+		s := NewAuditService(&ASConfig{
+			AuthEventRepository: mockAuthEventRepository,
+		})
+
+		n, err := s.PurgeExpired(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), n)
+		mockAuthEventRepository.AssertNotCalled(t, "DeleteBefore", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
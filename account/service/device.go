@@ -0,0 +1,98 @@
+package service
+
+import (
+	"net"
+	"strings"
+)
+
+// 判断设备时 IPv4 与 IPv6 地址所取的网段长度，同一网段内更换 IP（如重新拨号）不视为新设备
+const (
+	ipv4NetworkBits = 24
+	ipv6NetworkBits = 48
+	ipv4Bits        = 32
+	ipv6Bits        = 128
+)
+
+// browserPatterns 按顺序匹配，如 Edge 与 Opera 的 User-Agent 中同时包含 Chrome
+var browserPatterns = []struct {
+	token string
+	name  string
+}{
+	{"Edg/", "Edge"},
+	{"EdgiOS/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+}
+
+var osPatterns = []struct {
+	token string
+	name  string
+}{
+	{"Windows", "Windows"},
+	{"Android", "Android"},
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Mac OS X", "macOS"},
+	{"CrOS", "ChromeOS"},
+	{"Linux", "Linux"},
+}
+
+// deviceName 从 User-Agent 中提取浏览器与操作系统（不含版本号），如 Chrome / Windows，
+// 浏览器升级后仍视为同一设备。无法识别时使用产品名称，如 curl/7.68.0 返回 curl
+func deviceName(userAgent string) string {
+	browser := ""
+	for _, p := range browserPatterns {
+		if strings.Contains(userAgent, p.token) {
+			browser = p.name
+			break
+		}
+	}
+
+	os := ""
+	for _, p := range osPatterns {
+		if strings.Contains(userAgent, p.token) {
+			os = p.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " / " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	}
+
+	product := strings.TrimSpace(userAgent)
+	if i := strings.IndexAny(product, "/ "); i > 0 {
+		product = product[:i]
+	}
+	if product == "" {
+		return "Unknown"
+	}
+
+	return product
+}
+
+// ipNetwork 返回 IP 所在的网段，如 203.0.113.7 返回 203.0.113.0/24，无效的 IP 原样返回
+func ipNetwork(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ip
+	}
+
+	if v4 := addr.To4(); v4 != nil {
+		mask := net.CIDRMask(ipv4NetworkBits, ipv4Bits)
+		return (&net.IPNet{IP: v4.Mask(mask), Mask: mask}).String()
+	}
+
+	mask := net.CIDRMask(ipv6NetworkBits, ipv6Bits)
+	return (&net.IPNet{IP: addr.Mask(mask), Mask: mask}).String()
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceName(t *testing.T) {
+	testCases := []struct {
+		userAgent string
+		name      string
+	}{
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36", "Chrome / Windows"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36 Edg/91.0.864.64", "Edge / Windows"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.1.1 Safari/605.1.15", "Safari / macOS"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 14_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/91.0.4472.80 Mobile/15E148 Safari/604.1", "Chrome / iOS"},
+		{"Mozilla/5.0 (Linux; Android 11; Pixel 5) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.120 Mobile Safari/537.36", "Chrome / Android"},
+		{"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0", "Firefox / Linux"},
+		{"curl/7.68.0", "curl"},
+		{"", "Unknown"},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.name, deviceName(tc.userAgent), tc.userAgent)
+	}
+}
+
+func TestIPNetwork(t *testing.T) {
+	assert.Equal(t, "203.0.113.0/24", ipNetwork("203.0.113.7"))
+	assert.Equal(t, "203.0.113.0/24", ipNetwork("::ffff:203.0.113.200"))
+	assert.Equal(t, "2001:db8:1::/48", ipNetwork("2001:db8:1:2::1"))
+	assert.Equal(t, "", ipNetwork(""))
+}
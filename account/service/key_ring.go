@@ -0,0 +1,226 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/dgrijalva/jwt-go"
+)
+
+// KeyRing 管理签署 ID 令牌的密钥。新令牌总是使用 active 密钥签署，并在 header 中带上 kid；
+// retiring 密钥不再用于签署，但在其签署的令牌过期前仍可用于验证，轮换密钥时用户无需重新登录。
+// 支持 RSA（RS256）、ECDSA P-256（ES256）与 Ed25519（EdDSA）密钥，算法由密钥类型决定
+type KeyRing struct {
+	active *signingKey
+	// kid 到公钥的映射，包含 active 与 retiring 密钥
+	publicKeys map[string]*verificationKey
+	// 保持 JWKS 中公钥的顺序稳定，active 密钥在前
+	kids []string
+}
+
+type signingKey struct {
+	kid    string
+	method jwt.SigningMethod
+	key    crypto.Signer
+}
+
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    crypto.PublicKey
+	jwk    model.JWK
+}
+
+func NewKeyRing(active crypto.Signer, retiring ...crypto.PublicKey) (*KeyRing, error) {
+	activePub, err := newVerificationKey(active.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	k := &KeyRing{
+		active: &signingKey{
+			kid:    activePub.jwk.Kid,
+			method: activePub.method,
+			key:    active,
+		},
+		publicKeys: map[string]*verificationKey{activePub.jwk.Kid: activePub},
+		kids:       []string{activePub.jwk.Kid},
+	}
+
+	for _, pub := range retiring {
+		vk, err := newVerificationKey(pub)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := k.publicKeys[vk.jwk.Kid]; ok {
+			continue
+		}
+		k.publicKeys[vk.jwk.Kid] = vk
+		k.kids = append(k.kids, vk.jwk.Kid)
+	}
+
+	return k, nil
+}
+
+// SigningKey 返回当前用于签署令牌的密钥、签名算法及 kid
+func (k *KeyRing) SigningKey() (string, jwt.SigningMethod, crypto.Signer) {
+	return k.active.kid, k.active.method, k.active.key
+}
+
+// PublicKey 返回 kid 对应的公钥，kid 为空时返回 active 密钥的公钥，兼容未带 kid 的旧令牌。
+// 令牌声明的算法必须与密钥的算法一致，避免算法混淆攻击
+func (k *KeyRing) PublicKey(kid string, method jwt.SigningMethod) (crypto.PublicKey, error) {
+	if kid == "" {
+		kid = k.active.kid
+	}
+
+	vk, ok := k.publicKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("未知的 kid：%v", kid)
+	}
+
+	if vk.method.Alg() != method.Alg() {
+		return nil, fmt.Errorf("kid %v 不支持签名算法：%v", kid, method.Alg())
+	}
+
+	return vk.key, nil
+}
+
+// Algorithms 返回密钥环中所有密钥使用的签名算法
+func (k *KeyRing) Algorithms() []string {
+	algs := []string{}
+	seen := map[string]bool{}
+
+	for _, kid := range k.kids {
+		alg := k.publicKeys[kid].method.Alg()
+		if !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+
+	return algs
+}
+
+// JWKS 返回所有可用于验证 ID 令牌的公钥
+func (k *KeyRing) JWKS() *model.JWKS {
+	jwks := &model.JWKS{
+		Keys: make([]model.JWK, 0, len(k.kids)),
+	}
+
+	for _, kid := range k.kids {
+		jwks.Keys = append(jwks.Keys, k.publicKeys[kid].jwk)
+	}
+
+	return jwks
+}
+
+// newVerificationKey 根据公钥类型确定签名算法并生成对应的 JWK，
+// kid 按 RFC 7638 取 JWK thumbprint，同一密钥在各服务中得到的 kid 一致
+func newVerificationKey(pub crypto.PublicKey) (*verificationKey, error) {
+	var (
+		method     jwt.SigningMethod
+		jwk        model.JWK
+		thumbprint string
+	)
+
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		method = jwt.SigningMethodRS256
+		jwk = model.JWK{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+		thumbprint = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, jwk.E, jwk.N)
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("不支持的椭圆曲线：%v", key.Curve.Params().Name)
+		}
+		method = jwt.SigningMethodES256
+		jwk = model.JWK{
+			Kty: "EC",
+			Crv: "P-256",
+			X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+			Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+		}
+		thumbprint = fmt.Sprintf(`{"crv":"P-256","kty":"EC","x":"%s","y":"%s"}`, jwk.X, jwk.Y)
+	case ed25519.PublicKey:
+		method = SigningMethodEd25519
+		jwk = model.JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key),
+		}
+		thumbprint = fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`, jwk.X)
+	default:
+		return nil, fmt.Errorf("不支持的密钥类型：%T", pub)
+	}
+
+	sum := sha256.Sum256([]byte(thumbprint))
+
+	jwk.Use = "sig"
+	jwk.Alg = method.Alg()
+	jwk.Kid = base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return &verificationKey{
+		method: method,
+		key:    pub,
+		jwk:    jwk,
+	}, nil
+}
+
+// ParsePrivateKeyFromPEM 解析 PKCS#1、SEC 1 或 PKCS#8 格式的 RSA、ECDSA 与 Ed25519 私钥
+func ParsePrivateKeyFromPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("无效的 pem 数据")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("不支持的私钥类型：%T", key)
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("不支持的 pem 类型：%v", block.Type)
+	}
+}
+
+// ParsePublicKeyFromPEM 解析 PKIX 或 PKCS#1 格式的公钥
+func ParsePublicKeyFromPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("无效的 pem 数据")
+	}
+
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("不支持的 pem 类型：%v", block.Type)
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/google/uuid"
+)
+
+const revokeSessionPurpose = "revoke_session"
+
+type loginAlertService struct {
+	DeviceRepository       model.DeviceRepository
+	TokenRepository        model.TokenRepository
+	OneTimeTokenRepository model.OneTimeTokenRepository
+	Mailer                 model.Mailer
+	GeoLocator             model.GeoLocator
+	AuditLogger            model.AuditLogger
+	RevokeExpirationSecs   int64
+	RevokeURL              string
+}
+
+// LASConfig 中 RevokeURL 为提醒邮件中撤销会话的链接指向的页面，令牌以 token 参数附加在其后。
+// GeoLocator 为 nil 时只按 User-Agent 与网段判断设备，AuditLogger 为 nil 时不记录认证事件
+type LASConfig struct {
+	DeviceRepository       model.DeviceRepository
+	TokenRepository        model.TokenRepository
+	OneTimeTokenRepository model.OneTimeTokenRepository
+	Mailer                 model.Mailer
+	GeoLocator             model.GeoLocator
+	AuditLogger            model.AuditLogger
+	RevokeExpirationSecs   int64
+	RevokeURL              string
+}
+
+func NewLoginAlertService(c *LASConfig) model.LoginAlertService {
+	return &loginAlertService{
+		DeviceRepository:       c.DeviceRepository,
+		TokenRepository:        c.TokenRepository,
+		OneTimeTokenRepository: c.OneTimeTokenRepository,
+		Mailer:                 c.Mailer,
+		GeoLocator:             c.GeoLocator,
+		AuditLogger:            c.AuditLogger,
+		RevokeExpirationSecs:   c.RevokeExpirationSecs,
+		RevokeURL:              c.RevokeURL,
+	}
+}
+
+// NewSession 在登录创建新会话后比较此前见过的设备：浏览器与操作系统相同，且网段或所在城市相同时视为同一设备。
+// 见到新设备时记录下来并发送提醒邮件；用户还没有任何设备（如刚注册）时只记录不提醒。
+// 失败时只记录日志，不影响登录
+func (s *loginAlertService) NewSession(ctx context.Context, u *model.User, session *model.TokenFamily) {
+	device := &model.Device{
+		UID:       u.UID,
+		UserAgent: deviceName(session.UserAgent),
+		Network:   ipNetwork(session.IP),
+	}
+	if s.GeoLocator != nil {
+		device.Location = s.GeoLocator.Locate(session.IP)
+	}
+
+	devices, err := s.DeviceRepository.ListByUID(ctx, u.UID)
+	if err != nil {
+		log.Printf("无法获取用户 %v 的设备：%v\n", u.UID, err)
+		return
+	}
+
+	known := len(devices) == 0
+	for _, d := range devices {
+		if d.UserAgent == device.UserAgent &&
+			(d.Network == device.Network || (d.Location != "" && d.Location == device.Location)) {
+			known = true
+			break
+		}
+	}
+
+	if err := s.DeviceRepository.Upsert(ctx, device); err != nil {
+		log.Printf("无法保存用户 %v 的设备：%v\n", u.UID, err)
+		return
+	}
+
+	if known {
+		return
+	}
+
+	logAuthEvent(ctx, s.AuditLogger, model.AuthEventNewDevice, u.UID, u.Email, device.UserAgent)
+
+	if err := s.sendLoginAlert(ctx, u, session, device); err != nil {
+		log.Printf("无法向 %v 发送新设备登录提醒：%v\n", u.Email, err)
+	}
+}
+
+func (s *loginAlertService) sendLoginAlert(ctx context.Context, u *model.User, session *model.TokenFamily, device *model.Device) error {
+	token, tokenHash, err := generateOneTimeToken()
+	if err != nil {
+		return err
+	}
+
+	// 令牌对应用户、会话与设备，以空格分隔
+	value := strings.Join([]string{u.UID.String(), session.ID, device.ID.String()}, " ")
+	expiresIn := time.Duration(s.RevokeExpirationSecs) * time.Second
+	if err := s.OneTimeTokenRepository.SetOneTimeToken(ctx, revokeSessionPurpose, tokenHash, value, expiresIn); err != nil {
+		return err
+	}
+
+	link, err := linkWithToken(s.RevokeURL, token)
+	if err != nil {
+		return err
+	}
+
+	return s.Mailer.SendLoginAlert(ctx, u, &model.LoginAlert{
+		Device:   device.UserAgent,
+		IP:       session.IP,
+		Location: device.Location,
+		Time:     session.CreatedAt,
+	}, link)
+}
+
+// RevokeSession 使用提醒邮件中的令牌撤销对应的会话，并忘记该设备，此后从该设备登录仍会提醒。
+// 令牌只能使用一次，会话已过期或已退出登录时同样视为成功
+func (s *loginAlertService) RevokeSession(ctx context.Context, token string) error {
+	value, err := s.OneTimeTokenRepository.ConsumeOneTimeToken(ctx, revokeSessionPurpose, hashOneTimeToken(token))
+	if err != nil {
+		return err
+	}
+
+	fields := strings.Split(value, " ")
+	if len(fields) != 3 {
+		log.Printf("撤销会话令牌对应的值无效：%v\n", value)
+		return apperrors.NewInternal()
+	}
+
+	uid, err := uuid.Parse(fields[0])
+	if err != nil {
+		log.Printf("撤销会话令牌对应的 uid 无效：%v\n", fields[0])
+		return apperrors.NewInternal()
+	}
+	sessionID := fields[1]
+
+	if err := s.TokenRepository.DeleteTokenFamily(ctx, uid.String(), sessionID); err != nil && apperrors.Status(err) != http.StatusNotFound {
+		return err
+	}
+
+	logAuthEvent(ctx, s.AuditLogger, model.AuthEventSessionRevoked, uid, "", sessionID)
+
+	if deviceID, err := uuid.Parse(fields[2]); err == nil {
+		if err := s.DeviceRepository.Delete(ctx, uid, deviceID); err != nil && apperrors.Status(err) != http.StatusNotFound {
+			log.Printf("无法删除用户 %v 的设备 %v：%v\n", uid, deviceID, err)
+		}
+	}
+
+	return nil
+}
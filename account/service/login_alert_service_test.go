@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLoginAlertNewSession(t *testing.T) {
+	chromeOnWindows := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"
+	firefoxOnLinux := "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0"
+
+	uid, _ := uuid.NewRandom()
+	u := &model.User{
+		UID:   uid,
+		Email: "hello@world.com",
+	}
+
+	knownDevice := &model.Device{
+		UID:       uid,
+		UserAgent: "Chrome / Windows",
+		Network:   "203.0.113.0/24",
+		Location:  "Shanghai, China",
+	}
+
+	type deps struct {
+		deviceRepository       *mocks.MockDeviceRepository
+		oneTimeTokenRepository *mocks.MockOneTimeTokenRepository
+		mailer                 *mocks.MockMailer
+		auditService           *mocks.MockAuditService
+	}
+
+	newService := func(devices []*model.Device) (model.LoginAlertService, *deps) {
+		d := &deps{
+			deviceRepository:       new(mocks.MockDeviceRepository),
+			oneTimeTokenRepository: new(mocks.MockOneTimeTokenRepository),
+			mailer:                 new(mocks.MockMailer),
+			auditService:           new(mocks.MockAuditService),
+		}
+
+		mockGeoLocator := new(mocks.MockGeoLocator)
+		mockGeoLocator.On("Locate", "203.0.113.7").Return("Shanghai, China")
+		mockGeoLocator.On("Locate", "203.0.113.99").Return("Shanghai, China")
+		mockGeoLocator.On("Locate", "198.51.100.20").Return("Shanghai, China")
+		mockGeoLocator.On("Locate", "192.0.2.1").Return("Berlin, Germany")
+
+		d.deviceRepository.On("ListByUID", mock.AnythingOfType("*context.emptyCtx"), uid).Return(devices, nil)
+		d.deviceRepository.
+			On("Upsert", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.Device")).
+			Run(func(args mock.Arguments) {
+				args.Get(1).(*model.Device).ID = uuid.MustParse("8a3e5b4c-4f5e-4d7a-9c1b-2e3f4a5b6c7d")
+			}).
+			Return(nil)
+		d.oneTimeTokenRepository.
+			On("SetOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), revokeSessionPurpose, mock.AnythingOfType("string"), mock.AnythingOfType("string"), time.Hour).
+			Return(nil)
+		d.mailer.
+			On("SendLoginAlert", mock.AnythingOfType("*context.emptyCtx"), u, mock.AnythingOfType("*model.LoginAlert"), mock.AnythingOfType("string")).
+			Return(nil)
+		d.auditService.On("Log", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.AuthEvent"))
+
+		s := NewLoginAlertService(&LASConfig{
+			DeviceRepository:       d.deviceRepository,
+			OneTimeTokenRepository: d.oneTimeTokenRepository,
+			Mailer:                 d.mailer,
+			GeoLocator:             mockGeoLocator,
+			AuditLogger:            d.auditService,
+			RevokeExpirationSecs:   3600,
+			RevokeURL:              "http://malcorp.test/revoke-session",
+		})
+
+		return s, d
+	}
+
+	session := func(userAgent string, ip string) *model.TokenFamily {
+		return &model.TokenFamily{
+			ID:        "a_familyID",
+			UserAgent: userAgent,
+			IP:        ip,
+			CreatedAt: time.Unix(1626000000, 0),
+		}
+	}
+
+	t.Run("第一台设备只记录不提醒", func(t *testing.T) {
+		s, d := newService([]*model.Device{})
+
+		s.NewSession(context.Background(), u, session(chromeOnWindows, "203.0.113.7"))
+
+		d.deviceRepository.AssertCalled(t, "Upsert", mock.AnythingOfType("*context.emptyCtx"), mock.MatchedBy(func(device *model.Device) bool {
+			return device.UID == uid &&
+				device.UserAgent == "Chrome / Windows" &&
+				device.Network == "203.0.113.0/24" &&
+				device.Location == "Shanghai, China"
+		}))
+		d.mailer.AssertNotCalled(t, "SendLoginAlert", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("同一网段的已知设备", func(t *testing.T) {
+		s, d := newService([]*model.Device{knownDevice})
+
+		s.NewSession(context.Background(), u, session(chromeOnWindows, "203.0.113.99"))
+
+		d.deviceRepository.AssertNumberOfCalls(t, "Upsert", 1)
+		d.mailer.AssertNotCalled(t, "SendLoginAlert", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("网段不同但城市相同的已知设备", func(t *testing.T) {
+		s, d := newService([]*model.Device{knownDevice})
+
+		s.NewSession(context.Background(), u, session(chromeOnWindows, "198.51.100.20"))
+
+		d.mailer.AssertNotCalled(t, "SendLoginAlert", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("新设备发送提醒", func(t *testing.T) {
+		s, d := newService([]*model.Device{knownDevice})
+
+		s.NewSession(context.Background(), u, session(firefoxOnLinux, "192.0.2.1"))
+
+		var tokenHash, value string
+		for _, call := range d.oneTimeTokenRepository.Calls {
+			if call.Method == "SetOneTimeToken" {
+				tokenHash = call.Arguments.String(2)
+				value = call.Arguments.String(3)
+			}
+		}
+		assert.Equal(t, uid.String()+" a_familyID 8a3e5b4c-4f5e-4d7a-9c1b-2e3f4a5b6c7d", value)
+
+		d.mailer.AssertCalled(t, "SendLoginAlert", mock.AnythingOfType("*context.emptyCtx"), u, &model.LoginAlert{
+			Device:   "Firefox / Linux",
+			IP:       "192.0.2.1",
+			Location: "Berlin, Germany",
+			Time:     time.Unix(1626000000, 0),
+		}, mock.MatchedBy(func(link string) bool {
+			parsed, err := url.Parse(link)
+			return err == nil &&
+				strings.HasPrefix(link, "http://malcorp.test/revoke-session?") &&
+				hashOneTimeToken(parsed.Query().Get("token")) == tokenHash
+		}))
+		d.auditService.AssertCalled(t, "Log", mock.AnythingOfType("*context.emptyCtx"), &model.AuthEvent{
+			UID:    uid,
+			Type:   model.AuthEventNewDevice,
+			Detail: "Firefox / Linux",
+			Email:  u.Email,
+		})
+	})
+
+	t.Run("同一网段的其他浏览器视为新设备", func(t *testing.T) {
+		s, d := newService([]*model.Device{knownDevice})
+
+		s.NewSession(context.Background(), u, session(firefoxOnLinux, "203.0.113.7"))
+
+		d.mailer.AssertNumberOfCalls(t, "SendLoginAlert", 1)
+	})
+
+	t.Run("无法获取设备时不保存也不提醒", func(t *testing.T) {
+		mockDeviceRepository := new(mocks.MockDeviceRepository)
+		mockDeviceRepository.On("ListByUID", mock.AnythingOfType("*context.emptyCtx"), uid).Return(nil, apperrors.NewInternal())
+		mockMailer := new(mocks.MockMailer)
+
+		s := NewLoginAlertService(&LASConfig{
+			DeviceRepository: mockDeviceRepository,
+			Mailer:           mockMailer,
+		})
+
+		s.NewSession(context.Background(), u, session(firefoxOnLinux, "192.0.2.1"))
+
+		mockDeviceRepository.AssertNotCalled(t, "Upsert", mock.Anything, mock.Anything)
+		mockMailer.AssertNotCalled(t, "SendLoginAlert", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestLoginAlertRevokeSession(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	deviceID, _ := uuid.NewRandom()
+	value := uid.String() + " a_familyID " + deviceID.String()
+
+	newService := func() (model.LoginAlertService, *mocks.MockTokenRepository, *mocks.MockDeviceRepository, *mocks.MockOneTimeTokenRepository) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockDeviceRepository := new(mocks.MockDeviceRepository)
+		mockOneTimeTokenRepository := new(mocks.MockOneTimeTokenRepository)
+
+		s := NewLoginAlertService(&LASConfig{
+			DeviceRepository:       mockDeviceRepository,
+			TokenRepository:        mockTokenRepository,
+			OneTimeTokenRepository: mockOneTimeTokenRepository,
+		})
+
+		return s, mockTokenRepository, mockDeviceRepository, mockOneTimeTokenRepository
+	}
+
+	t.Run("撤销会话并忘记设备", func(t *testing.T) {
+		s, mockTokenRepository, mockDeviceRepository, mockOneTimeTokenRepository := newService()
+
+		mockOneTimeTokenRepository.
+			On("ConsumeOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), revokeSessionPurpose, hashOneTimeToken("aValidToken")).
+			Return(value, nil)
+		mockTokenRepository.On("DeleteTokenFamily", mock.AnythingOfType("*context.emptyCtx"), uid.String(), "a_familyID").Return(nil)
+		mockDeviceRepository.On("Delete", mock.AnythingOfType("*context.emptyCtx"), uid, deviceID).Return(nil)
+
+		err := s.RevokeSession(context.Background(), "aValidToken")
+
+		assert.NoError(t, err)
+		mockTokenRepository.AssertExpectations(t)
+		mockDeviceRepository.AssertExpectations(t)
+	})
+
+	t.Run("会话已失效时仍然成功", func(t *testing.T) {
+		s, mockTokenRepository, mockDeviceRepository, mockOneTimeTokenRepository := newService()
+
+		mockOneTimeTokenRepository.
+			On("ConsumeOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), revokeSessionPurpose, hashOneTimeToken("aValidToken")).
+			Return(value, nil)
+		mockTokenRepository.On("DeleteTokenFamily", mock.AnythingOfType("*context.emptyCtx"), uid.String(), "a_familyID").Return(apperrors.NewNotFound("session", "a_familyID"))
+		mockDeviceRepository.On("Delete", mock.AnythingOfType("*context.emptyCtx"), uid, deviceID).Return(nil)
+
+		err := s.RevokeSession(context.Background(), "aValidToken")
+
+		assert.NoError(t, err)
+		mockDeviceRepository.AssertExpectations(t)
+	})
+
+	t.Run("令牌无效", func(t *testing.T) {
+		s, mockTokenRepository, _, mockOneTimeTokenRepository := newService()
+
+		mockErr := apperrors.NewAuthorization("令牌无效、已过期或已被使用")
+		mockOneTimeTokenRepository.
+			On("ConsumeOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), revokeSessionPurpose, hashOneTimeToken("anInvalidToken")).
+			Return("", mockErr)
+
+		err := s.RevokeSession(context.Background(), "anInvalidToken")
+
+		assert.EqualError(t, err, mockErr.Error())
+		mockTokenRepository.AssertNotCalled(t, "DeleteTokenFamily", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -2,41 +2,188 @@ package service
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"strings"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/scrypt"
 )
 
+// 密码哈希使用 PHC 字符串格式，记录算法与参数，调整参数或更换算法后旧的哈希仍能验证：
+//
+//	$argon2id$v=19$m=65536,t=3,p=2${salt}${hash}
+//	$scrypt$ln=15,r=8,p=1${salt}${hash}
+//
+// salt 与 hash 为不带填充的标准 base64。此前的哈希为 hex(hash).hex(salt)，
+// 参数固定为 scrypt N=32768、r=8、p=1，仍可验证，登录成功后会升级为当前格式
+const (
+	passwordSaltLength = 16
+	passwordKeyLength  = 32
+)
+
+// argon2idParams 为 argon2id 的内存（KiB）、迭代次数与并行度
+type argon2idParams struct {
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+}
+
+// scryptParams 中 LogN 为 CPU/内存成本 N 的以 2 为底的对数
+type scryptParams struct {
+	LogN int
+	R    int
+	P    int
+}
+
+// defaultArgon2idParams 为新生成的哈希所用的参数，低于该参数的哈希会在登录时重新生成
+var defaultArgon2idParams = argon2idParams{
+	Memory:  64 * 1024,
+	Time:    3,
+	Threads: 2,
+}
+
+// legacyScryptParams 为旧格式 hex(hash).hex(salt) 所用的参数
+var legacyScryptParams = scryptParams{
+	LogN: 15,
+	R:    8,
+	P:    1,
+}
+
+var phcEncoding = base64.RawStdEncoding
+
+// hashPassword 使用 argon2id 与随机 salt 生成 PHC 格式的密码哈希
 func hashPassword(password string) (string, error) {
-	salt := make([]byte, 32)
-	_, err := rand.Read(salt)
-	if err != nil {
+	salt := make([]byte, passwordSaltLength)
+	if _, err := rand.Read(salt); err != nil {
 		return "", err
 	}
 
-	shash, err := scrypt.Key([]byte(password), salt, 32768, 8, 1, 32)
+	p := defaultArgon2idParams
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, passwordKeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Time, p.Threads, phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key)), nil
+}
+
+// comparePasswords 按哈希中记录的算法与参数验证密码，哈希格式无效时返回错误
+func comparePasswords(storedPassword string, suppliedPassword string) (bool, error) {
+	h, err := parsePasswordHash(storedPassword)
 	if err != nil {
-		return "", err
+		return false, err
 	}
 
-	// return hex-encoded string with salt appended to password
-	hashedPW := fmt.Sprintf("%s.%s", hex.EncodeToString(shash), hex.EncodeToString(salt))
+	var key []byte
+	switch h.Algorithm {
+	case "argon2id":
+		key = argon2.IDKey([]byte(suppliedPassword), h.Salt, h.Argon2id.Time, h.Argon2id.Memory, h.Argon2id.Threads, uint32(len(h.Key)))
+	case "scrypt":
+		key, err = scrypt.Key([]byte(suppliedPassword), h.Salt, 1<<h.Scrypt.LogN, h.Scrypt.R, h.Scrypt.P, len(h.Key))
+		if err != nil {
+			return false, fmt.Errorf("无法验证用户密码：%w", err)
+		}
+	}
 
-	return hashedPW, nil
+	return subtle.ConstantTimeCompare(key, h.Key) == 1, nil
 }
 
-func comparePasswords(storedPassword string, suppliedPassword string) (bool, error) {
-	pwsalt := strings.Split(storedPassword, ".")
+// passwordNeedsRehash 返回哈希是否为旧格式、其他算法或低于当前参数，需要在验证通过后重新生成
+func passwordNeedsRehash(storedPassword string) bool {
+	h, err := parsePasswordHash(storedPassword)
+	if err != nil || h.Legacy || h.Algorithm != "argon2id" {
+		return true
+	}
 
-	salt, err := hex.DecodeString(pwsalt[1])
+	p := defaultArgon2idParams
+	return h.Argon2id.Memory < p.Memory || h.Argon2id.Time < p.Time || h.Argon2id.Threads < p.Threads ||
+		len(h.Salt) < passwordSaltLength || len(h.Key) < passwordKeyLength
+}
 
-	if err != nil {
-		return false, fmt.Errorf("无法验证用户密码")
+// passwordHash 为解析后的密码哈希，Argon2id 与 Scrypt 中与 Algorithm 对应的一项有效
+type passwordHash struct {
+	Algorithm string
+	Legacy    bool
+	Argon2id  argon2idParams
+	Scrypt    scryptParams
+	Salt      []byte
+	Key       []byte
+}
+
+func parsePasswordHash(s string) (*passwordHash, error) {
+	if !strings.HasPrefix(s, "$") {
+		return parseLegacyPasswordHash(s)
+	}
+
+	// "", 算法, [版本,] 参数, salt, hash
+	fields := strings.Split(s, "$")
+	if len(fields) < 5 {
+		return nil, fmt.Errorf("无效的密码哈希")
+	}
+
+	h := &passwordHash{Algorithm: fields[1]}
+	params := fields[len(fields)-3]
+
+	switch {
+	case h.Algorithm == "argon2id" && len(fields) == 6:
+		var version int
+		if _, err := fmt.Sscanf(fields[2], "v=%d", &version); err != nil || version != argon2.Version {
+			return nil, fmt.Errorf("不支持的 argon2id 版本：%v", fields[2])
+		}
+
+		p := &h.Argon2id
+		if _, err := fmt.Sscanf(params, "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+			return nil, fmt.Errorf("无效的 argon2id 参数：%v", params)
+		}
+		if p.Memory == 0 || p.Time == 0 || p.Threads == 0 {
+			return nil, fmt.Errorf("无效的 argon2id 参数：%v", params)
+		}
+	case h.Algorithm == "scrypt" && len(fields) == 5:
+		p := &h.Scrypt
+		if _, err := fmt.Sscanf(params, "ln=%d,r=%d,p=%d", &p.LogN, &p.R, &p.P); err != nil {
+			return nil, fmt.Errorf("无效的 scrypt 参数：%v", params)
+		}
+		if p.LogN < 1 || p.LogN > 30 || p.R < 1 || p.P < 1 {
+			return nil, fmt.Errorf("无效的 scrypt 参数：%v", params)
+		}
+	default:
+		return nil, fmt.Errorf("不支持的密码哈希算法：%v", h.Algorithm)
 	}
 
-	shash, _ := scrypt.Key([]byte(suppliedPassword), salt, 32768, 8, 1, 32)
+	var err error
+	if h.Salt, err = phcEncoding.DecodeString(fields[len(fields)-2]); err != nil || len(h.Salt) == 0 {
+		return nil, fmt.Errorf("无效的密码哈希 salt")
+	}
+	if h.Key, err = phcEncoding.DecodeString(fields[len(fields)-1]); err != nil || len(h.Key) == 0 {
+		return nil, fmt.Errorf("无效的密码哈希")
+	}
+
+	return h, nil
+}
+
+// parseLegacyPasswordHash 解析旧格式 hex(hash).hex(salt)
+func parseLegacyPasswordHash(s string) (*passwordHash, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("无效的密码哈希")
+	}
+
+	key, err := hex.DecodeString(parts[0])
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("无效的密码哈希")
+	}
+
+	salt, err := hex.DecodeString(parts[1])
+	if err != nil || len(salt) == 0 {
+		return nil, fmt.Errorf("无效的密码哈希 salt")
+	}
 
-	return hex.EncodeToString(shash) == pwsalt[0], nil
+	return &passwordHash{
+		Algorithm: "scrypt",
+		Legacy:    true,
+		Scrypt:    legacyScryptParams,
+		Salt:      salt,
+		Key:       key,
+	}, nil
 }
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// legacyHash 按旧格式 hex(hash).hex(salt) 生成哈希
+func legacyHash(t *testing.T, password string) string {
+	salt := []byte("0123456789abcdef0123456789abcdef")
+	key, err := scrypt.Key([]byte(password), salt, 32768, 8, 1, 32)
+	assert.NoError(t, err)
+
+	return fmt.Sprintf("%s.%s", hex.EncodeToString(key), hex.EncodeToString(salt))
+}
+
+func TestPasswordHash(t *testing.T) {
+	t.Run("argon2id", func(t *testing.T) {
+		hashed, err := hashPassword("avalidpassword")
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(hashed, "$argon2id$v=19$m=65536,t=3,p=2$"))
+
+		match, err := comparePasswords(hashed, "avalidpassword")
+		assert.NoError(t, err)
+		assert.True(t, match)
+
+		match, err = comparePasswords(hashed, "awrongpassword")
+		assert.NoError(t, err)
+		assert.False(t, match)
+
+		assert.False(t, passwordNeedsRehash(hashed))
+
+		// 相同的密码每次生成不同的 salt
+		other, _ := hashPassword("avalidpassword")
+		assert.NotEqual(t, hashed, other)
+	})
+
+	t.Run("旧格式的 scrypt 哈希", func(t *testing.T) {
+		hashed := legacyHash(t, "avalidpassword")
+
+		match, err := comparePasswords(hashed, "avalidpassword")
+		assert.NoError(t, err)
+		assert.True(t, match)
+
+		match, err = comparePasswords(hashed, "awrongpassword")
+		assert.NoError(t, err)
+		assert.False(t, match)
+
+		assert.True(t, passwordNeedsRehash(hashed))
+	})
+
+	t.Run("PHC 格式的 scrypt 哈希", func(t *testing.T) {
+		salt := []byte("saltsaltsaltsalt")
+		key, _ := scrypt.Key([]byte("avalidpassword"), salt, 1<<10, 8, 1, 32)
+		hashed := fmt.Sprintf("$scrypt$ln=10,r=8,p=1$%s$%s", phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key))
+
+		match, err := comparePasswords(hashed, "avalidpassword")
+		assert.NoError(t, err)
+		assert.True(t, match)
+		assert.True(t, passwordNeedsRehash(hashed))
+	})
+
+	t.Run("参数低于当前设置的 argon2id 哈希", func(t *testing.T) {
+		salt := []byte("saltsaltsaltsalt")
+		key := argon2.IDKey([]byte("avalidpassword"), salt, 1, 1024, 1, 32)
+		hashed := fmt.Sprintf("$argon2id$v=19$m=1024,t=1,p=1$%s$%s", phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key))
+
+		match, err := comparePasswords(hashed, "avalidpassword")
+		assert.NoError(t, err)
+		assert.True(t, match)
+		assert.True(t, passwordNeedsRehash(hashed))
+	})
+
+	for _, hashed := range []string{
+		"",
+		"nodot",
+		"a.b.c",
+		"zz.00",
+		"00.",
+		"$argon2id$v=19$m=65536,t=3,p=2$c2FsdA",
+		"$argon2id$v=18$m=65536,t=3,p=2$c2FsdA$a2V5",
+		"$argon2id$v=19$m=0,t=3,p=2$c2FsdA$a2V5",
+		"$argon2id$v=19$t=3$c2FsdA$a2V5",
+		"$argon2id$v=19$m=65536,t=3,p=2$!!$a2V5",
+		"$scrypt$ln=99,r=8,p=1$c2FsdA$a2V5",
+		"$bcrypt$v=1$c2FsdA$a2V5",
+	} {
+		hashed := hashed
+		t.Run(fmt.Sprintf("无效的哈希 %q", hashed), func(t *testing.T) {
+			match, err := comparePasswords(hashed, "avalidpassword")
+			assert.Error(t, err)
+			assert.False(t, match)
+			assert.True(t, passwordNeedsRehash(hashed))
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/google/uuid"
+)
+
+// maxPersonalAccessTokenLifetime 为个人访问令牌的最长有效期，令牌必须设置有效期
+const maxPersonalAccessTokenLifetime = 365 * 24 * time.Hour
+
+type personalAccessTokenService struct {
+	PersonalAccessTokenRepository model.PersonalAccessTokenRepository
+	UserRepository                model.UserRepository
+	AuditLogger                   model.AuditLogger
+}
+
+// PATSConfig 中 AuditLogger 为 nil 时不记录认证事件
+type PATSConfig struct {
+	PersonalAccessTokenRepository model.PersonalAccessTokenRepository
+	UserRepository                model.UserRepository
+	AuditLogger                   model.AuditLogger
+}
+
+func NewPersonalAccessTokenService(c *PATSConfig) model.PersonalAccessTokenService {
+	return &personalAccessTokenService{
+		PersonalAccessTokenRepository: c.PersonalAccessTokenRepository,
+		UserRepository:                c.UserRepository,
+		AuditLogger:                   c.AuditLogger,
+	}
+}
+
+// Create 创建具有 scopes 权限、expiresIn 后过期的令牌，返回令牌信息与令牌本身，令牌本身不会被保存
+func (s *personalAccessTokenService) Create(ctx context.Context, uid uuid.UUID, name string, scopes []string, expiresIn time.Duration) (*model.PersonalAccessToken, string, error) {
+	if len(scopes) == 0 {
+		return nil, "", apperrors.NewBadRequest("至少需要一个权限范围")
+	}
+
+	for _, scope := range scopes {
+		if !validPersonalAccessTokenScope(scope) {
+			return nil, "", apperrors.NewBadRequest(fmt.Sprintf("无效的权限范围：%v", scope))
+		}
+	}
+
+	if expiresIn <= 0 || expiresIn > maxPersonalAccessTokenLifetime {
+		return nil, "", apperrors.NewBadRequest("有效期必须为 1 至 365 天")
+	}
+
+	// 摘要包括前缀，与 Validate 中一致
+	secret, _, err := generateOneTimeToken()
+	if err != nil {
+		log.Printf("无法生成个人访问令牌，uid：%v。原因是：%v\n", uid, err)
+		return nil, "", apperrors.NewInternal()
+	}
+	token := model.PersonalAccessTokenPrefix + secret
+
+	t := &model.PersonalAccessToken{
+		UID:       uid,
+		Name:      name,
+		TokenHash: hashOneTimeToken(token),
+		Scopes:    scopes,
+		ExpiresAt: time.Now().Add(expiresIn),
+	}
+
+	if err := s.PersonalAccessTokenRepository.Create(ctx, t); err != nil {
+		return nil, "", err
+	}
+
+	logAuthEvent(ctx, s.AuditLogger, model.AuthEventPersonalAccessTokenCreated, uid, "", t.ID.String())
+
+	return t, token, nil
+}
+
+func (s *personalAccessTokenService) List(ctx context.Context, uid uuid.UUID) ([]*model.PersonalAccessToken, error) {
+	return s.PersonalAccessTokenRepository.ListByUID(ctx, uid)
+}
+
+func (s *personalAccessTokenService) Revoke(ctx context.Context, uid uuid.UUID, id uuid.UUID) error {
+	if err := s.PersonalAccessTokenRepository.Delete(ctx, uid, id); err != nil {
+		return err
+	}
+
+	logAuthEvent(ctx, s.AuditLogger, model.AuthEventPersonalAccessTokenRevoked, uid, "", id.String())
+
+	return nil
+}
+
+// RevokeAll 撤销用户的所有个人访问令牌，用于重置或修改密码后让令牌随会话一起失效
+func (s *personalAccessTokenService) RevokeAll(ctx context.Context, uid uuid.UUID) error {
+	if err := s.PersonalAccessTokenRepository.DeleteByUID(ctx, uid); err != nil {
+		return err
+	}
+
+	logAuthEvent(ctx, s.AuditLogger, model.AuthEventPersonalAccessTokenRevoked, uid, "", "all")
+
+	return nil
+}
+
+// Validate 返回令牌所属的用户与令牌信息，令牌不存在、已撤销或已过期时返回 Authorization 错误。
+// 记录使用时间失败只写入日志
+func (s *personalAccessTokenService) Validate(ctx context.Context, token string) (*model.User, *model.PersonalAccessToken, error) {
+	if !strings.HasPrefix(token, model.PersonalAccessTokenPrefix) {
+		return nil, nil, apperrors.NewAuthorization("无效的个人访问令牌")
+	}
+
+	t, err := s.PersonalAccessTokenRepository.FindByHash(ctx, hashOneTimeToken(token))
+	if err != nil {
+		if apperrors.Status(err) == http.StatusNotFound {
+			return nil, nil, apperrors.NewAuthorization("无效的个人访问令牌")
+		}
+		return nil, nil, err
+	}
+
+	if !time.Now().Before(t.ExpiresAt) {
+		return nil, nil, apperrors.NewAuthorization("个人访问令牌已过期")
+	}
+
+	u, err := s.UserRepository.FindByID(ctx, t.UID)
+	if err != nil {
+		return nil, nil, apperrors.NewAuthorization("无效的个人访问令牌")
+	}
+
+	if err := s.PersonalAccessTokenRepository.UpdateLastUsed(ctx, t.ID); err != nil {
+		log.Printf("无法记录个人访问令牌 %v 的使用时间：%v\n", t.ID, err)
+	}
+
+	return u, t, nil
+}
+
+func validPersonalAccessTokenScope(scope string) bool {
+	for _, s := range model.PersonalAccessTokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPersonalAccessTokenCreate(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	tokenID, _ := uuid.NewRandom()
+
+	t.Run("成功", func(t *testing.T) {
+		mockRepository := new(mocks.MockPersonalAccessTokenRepository)
+		mockAuditService := new(mocks.MockAuditService)
+
+		s := NewPersonalAccessTokenService(&PATSConfig{
+			PersonalAccessTokenRepository: mockRepository,
+			AuditLogger:                   mockAuditService,
+		})
+
+		var saved *model.PersonalAccessToken
+		mockRepository.
+			On("Create", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.PersonalAccessToken")).
+			Run(func(args mock.Arguments) {
+				saved = args.Get(1).(*model.PersonalAccessToken)
+				saved.ID = tokenID
+			}).
+			Return(nil)
+		mockAuditService.On("Log", mock.AnythingOfType("*context.emptyCtx"), &model.AuthEvent{
+			UID:    uid,
+			Type:   model.AuthEventPersonalAccessTokenCreated,
+			Detail: tokenID.String(),
+		})
+
+		before := time.Now()
+		pat, token, err := s.Create(context.TODO(), uid, "部署脚本", []string{model.ScopeProfileRead}, 30*24*time.Hour)
+
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(token, model.PersonalAccessTokenPrefix))
+		assert.Equal(t, saved, pat)
+		assert.Equal(t, uid, pat.UID)
+		assert.Equal(t, "部署脚本", pat.Name)
+		assert.Equal(t, []string{model.ScopeProfileRead}, []string(pat.Scopes))
+		assert.WithinDuration(t, before.Add(30*24*time.Hour), pat.ExpiresAt, time.Minute)
+
+		// 只保存令牌的摘要
+		assert.Equal(t, hashOneTimeToken(token), pat.TokenHash)
+		mockAuditService.AssertExpectations(t)
+	})
+
+	t.Run("无效的参数", func(t *testing.T) {
+		mockRepository := new(mocks.MockPersonalAccessTokenRepository)
+
+		s := NewPersonalAccessTokenService(&PATSConfig{
+			PersonalAccessTokenRepository: mockRepository,
+		})
+
+		_, _, err := s.Create(context.TODO(), uid, "a", nil, time.Hour)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+
+		_, _, err = s.Create(context.TODO(), uid, "a", []string{"admin"}, time.Hour)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+
+		_, _, err = s.Create(context.TODO(), uid, "a", []string{model.ScopeProfileRead}, 0)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+
+		_, _, err = s.Create(context.TODO(), uid, "a", []string{model.ScopeProfileRead}, 366*24*time.Hour)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+
+		mockRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestPersonalAccessTokenValidate(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	tokenID, _ := uuid.NewRandom()
+	u := &model.User{
+		UID:   uid,
+		Email: "hello@world.com",
+	}
+
+	token := model.PersonalAccessTokenPrefix + "aValidSecret"
+	expiredToken := model.PersonalAccessTokenPrefix + "anExpiredSecret"
+	unknownToken := model.PersonalAccessTokenPrefix + "anUnknownSecret"
+
+	mockRepository := new(mocks.MockPersonalAccessTokenRepository)
+	mockUserRepository := new(mocks.MockUserRepository)
+
+	pat := &model.PersonalAccessToken{
+		ID:        tokenID,
+		UID:       uid,
+		TokenHash: hashOneTimeToken(token),
+		Scopes:    []string{model.ScopeProfileRead},
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	expired := &model.PersonalAccessToken{
+		ID:        tokenID,
+		UID:       uid,
+		TokenHash: hashOneTimeToken(expiredToken),
+		ExpiresAt: time.Now().Add(-time.Hour),
+	}
+
+	mockRepository.On("FindByHash", mock.AnythingOfType("*context.emptyCtx"), hashOneTimeToken(token)).Return(pat, nil)
+	mockRepository.On("FindByHash", mock.AnythingOfType("*context.emptyCtx"), hashOneTimeToken(expiredToken)).Return(expired, nil)
+	mockRepository.
+		On("FindByHash", mock.AnythingOfType("*context.emptyCtx"), hashOneTimeToken(unknownToken)).
+		Return(nil, apperrors.NewNotFound("personal access token", ""))
+	mockRepository.On("UpdateLastUsed", mock.AnythingOfType("*context.emptyCtx"), tokenID).Return(nil)
+	mockUserRepository.On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).Return(u, nil)
+
+	s := NewPersonalAccessTokenService(&PATSConfig{
+		PersonalAccessTokenRepository: mockRepository,
+		UserRepository:                mockUserRepository,
+	})
+
+	t.Run("成功并记录使用时间", func(t *testing.T) {
+		user, gotPAT, err := s.Validate(context.TODO(), token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, u, user)
+		assert.Equal(t, pat, gotPAT)
+		mockRepository.AssertCalled(t, "UpdateLastUsed", mock.AnythingOfType("*context.emptyCtx"), tokenID)
+	})
+
+	t.Run("令牌已过期", func(t *testing.T) {
+		user, _, err := s.Validate(context.TODO(), expiredToken)
+
+		assert.Nil(t, user)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+
+	t.Run("令牌不存在或已撤销", func(t *testing.T) {
+		user, _, err := s.Validate(context.TODO(), unknownToken)
+
+		assert.Nil(t, user)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+
+	t.Run("不是个人访问令牌", func(t *testing.T) {
+		user, _, err := s.Validate(context.TODO(), "aValidSecret")
+
+		assert.Nil(t, user)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		mockRepository.AssertNotCalled(t, "FindByHash", mock.Anything, hashOneTimeToken("aValidSecret"))
+	})
+}
+
+func TestPersonalAccessTokenRevoke(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	tokenID, _ := uuid.NewRandom()
+
+	mockRepository := new(mocks.MockPersonalAccessTokenRepository)
+	mockAuditService := new(mocks.MockAuditService)
+
+	s := NewPersonalAccessTokenService(&PATSConfig{
+		PersonalAccessTokenRepository: mockRepository,
+		AuditLogger:                   mockAuditService,
+	})
+
+	t.Run("成功", func(t *testing.T) {
+		mockRepository.On("Delete", mock.AnythingOfType("*context.emptyCtx"), uid, tokenID).Return(nil)
+		mockAuditService.On("Log", mock.AnythingOfType("*context.emptyCtx"), &model.AuthEvent{
+			UID:    uid,
+			Type:   model.AuthEventPersonalAccessTokenRevoked,
+			Detail: tokenID.String(),
+		})
+
+		err := s.Revoke(context.TODO(), uid, tokenID)
+
+		assert.NoError(t, err)
+		mockAuditService.AssertExpectations(t)
+	})
+
+	t.Run("令牌不存在", func(t *testing.T) {
+		otherID, _ := uuid.NewRandom()
+		mockErr := apperrors.NewNotFound("personal access token", otherID.String())
+		mockRepository.On("Delete", mock.AnythingOfType("*context.emptyCtx"), uid, otherID).Return(mockErr)
+
+		err := s.Revoke(context.TODO(), uid, otherID)
+
+		assert.Equal(t, mockErr, err)
+		mockAuditService.AssertNumberOfCalls(t, "Log", 1)
+	})
+	t.Run("撤销所有令牌", func(t *testing.T) {
+		mockRepository.On("DeleteByUID", mock.AnythingOfType("*context.emptyCtx"), uid).Return(nil)
+		mockAuditService.On("Log", mock.AnythingOfType("*context.emptyCtx"), &model.AuthEvent{
+			UID:    uid,
+			Type:   model.AuthEventPersonalAccessTokenRevoked,
+			Detail: "all",
+		})
+
+		err := s.RevokeAll(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		mockRepository.AssertCalled(t, "DeleteByUID", mock.AnythingOfType("*context.emptyCtx"), uid)
+		mockAuditService.AssertNumberOfCalls(t, "Log", 2)
+	})
+}
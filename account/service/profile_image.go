@@ -0,0 +1,254 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+
+	// 注册可解码的图片格式
+	_ "image/gif"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+
+	"golang.org/x/image/draw"
+)
+
+// 头像会被裁剪为正方形并生成以下尺寸，对象名为 {id}_{size}.jpg，
+// User.ImageURL 指向 defaultProfileImageSize，将其中的尺寸替换即可得到其他尺寸的地址
+var profileImageSizes = []int{64, 256, 512}
+
+const (
+	defaultProfileImageSize = 256
+	profileImageQuality     = 85
+	// 解码前检查图片尺寸，避免体积很小的图片解码后占用大量内存
+	maxProfileImagePixels = 50 * 1000 * 1000
+)
+
+var profileImageObjNameRegexp = regexp.MustCompile(`^(.+)_\d+\.jpg$`)
+
+type profileImageVariant struct {
+	Size int
+	Data []byte
+}
+
+// profileImageObjName 返回某个尺寸的头像对象名
+func profileImageObjName(id string, size int) string {
+	return fmt.Sprintf("%s_%d.jpg", id, size)
+}
+
+// profileImageObjNames 返回与 objName 属于同一张头像的所有对象名，
+// objName 不符合 {id}_{size}.jpg 时（如处理图片之前上传的头像）只返回其本身
+func profileImageObjNames(objName string) []string {
+	match := profileImageObjNameRegexp.FindStringSubmatch(objName)
+	if match == nil {
+		return []string{objName}
+	}
+
+	objNames := make([]string, 0, len(profileImageSizes)+1)
+	found := false
+	for _, size := range profileImageSizes {
+		name := profileImageObjName(match[1], size)
+		objNames = append(objNames, name)
+		found = found || name == objName
+	}
+
+	// 尺寸配置变更前生成的头像
+	if !found {
+		objNames = append(objNames, objName)
+	}
+
+	return objNames
+}
+
+// processProfileImage 解码 JPEG、PNG、GIF 或 WebP 图片，按 EXIF 方向摆正后居中裁剪为正方形，
+// 并重新编码为各个尺寸的 JPEG。重新编码不会保留 EXIF 等元数据
+func processProfileImage(r io.Reader) ([]profileImageVariant, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("无法读取图片：%w", err)
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("无法解析图片：%w", err)
+	}
+
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxProfileImagePixels {
+		return nil, fmt.Errorf("图片尺寸 %vx%v 超出限制", config.Width, config.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("无法解码图片：%w", err)
+	}
+
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	// 居中裁剪与旋转、翻转的先后顺序不影响结果，先裁剪可以减少需要处理的像素
+	b := img.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	cropMin := image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2)
+
+	// JPEG 不支持透明，以白色为背景
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(square, square.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(square, square.Bounds(), img, cropMin, draw.Over)
+	square = orient(square, orientation)
+
+	sizes := append([]int{}, profileImageSizes...)
+	sort.Sort(sort.Reverse(sort.IntSlice(sizes)))
+
+	// 由大到小依次缩放，较小的尺寸以上一个尺寸为源
+	variants := make([]profileImageVariant, 0, len(sizes))
+	var src image.Image = square
+	for _, size := range sizes {
+		dst := image.NewRGBA(image.Rect(0, 0, size, size))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: profileImageQuality}); err != nil {
+			return nil, fmt.Errorf("无法编码图片：%w", err)
+		}
+
+		variants = append(variants, profileImageVariant{
+			Size: size,
+			Data: buf.Bytes(),
+		})
+		src = dst
+	}
+
+	return variants, nil
+}
+
+// orient 按 EXIF 方向（1-8）变换正方形图片，使其以正确的方向显示
+func orient(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	n := img.Bounds().Dx()
+	dst := image.NewRGBA(img.Bounds())
+
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // 水平翻转
+				sx, sy = n-1-x, y
+			case 3: // 旋转 180°
+				sx, sy = n-1-x, n-1-y
+			case 4: // 垂直翻转
+				sx, sy = x, n-1-y
+			case 5: // 沿主对角线翻转
+				sx, sy = y, x
+			case 6: // 顺时针旋转 90°
+				sx, sy = y, n-1-x
+			case 7: // 沿副对角线翻转
+				sx, sy = n-1-y, n-1-x
+			case 8: // 逆时针旋转 90°
+				sx, sy = n-1-y, x
+			}
+			dst.SetRGBA(x, y, img.RGBAAt(sx, sy))
+		}
+	}
+
+	return dst
+}
+
+// jpegOrientation 读取 JPEG 中 EXIF 的 Orientation 标签，不存在或无法解析时返回 1
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return 1
+		}
+
+		marker := data[i+1]
+		// 没有长度字段的标记
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD8) {
+			i += 2
+			continue
+		}
+		// EXIF 位于图像数据之前
+		if marker == 0xD9 || marker == 0xDA {
+			return 1
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+
+		if marker == 0xE1 {
+			if o := exifOrientation(data[i+4 : i+2+length]); o != 0 {
+				return o
+			}
+		}
+
+		i += 2 + length
+	}
+
+	return 1
+}
+
+// exifOrientation 从 APP1 段中读取 IFD0 的 Orientation（0x0112）标签，不存在时返回 0
+func exifOrientation(seg []byte) int {
+	if len(seg) < 14 || string(seg[:6]) != "Exif\x00\x00" {
+		return 0
+	}
+	tiff := seg[6:]
+
+	var bo binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 0
+	}
+
+	if bo.Uint16(tiff[2:]) != 42 {
+		return 0
+	}
+
+	ifd := int64(bo.Uint32(tiff[4:]))
+	if ifd+2 > int64(len(tiff)) {
+		return 0
+	}
+
+	count := int64(bo.Uint16(tiff[ifd:]))
+	for k := int64(0); k < count; k++ {
+		entry := ifd + 2 + k*12
+		if entry+12 > int64(len(tiff)) {
+			return 0
+		}
+
+		if bo.Uint16(tiff[entry:]) == 0x0112 {
+			o := int(bo.Uint16(tiff[entry+8:]))
+			if o >= 1 && o <= 8 {
+				return o
+			}
+			return 0
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testRed  = color.RGBA{R: 255, A: 255}
+	testBlue = color.RGBA{B: 255, A: 255}
+)
+
+// testImage 左半部分为红色，右半部分为蓝色
+func testImage(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < w/2 {
+				img.SetRGBA(x, y, testRed)
+			} else {
+				img.SetRGBA(x, y, testBlue)
+			}
+		}
+	}
+
+	return img
+}
+
+func encodeTestPNG(t *testing.T, w, h int) []byte {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, testImage(w, h)))
+
+	return buf.Bytes()
+}
+
+// encodeTestJPEG 生成包含 EXIF Orientation 与 GPS 信息的 JPEG
+func encodeTestJPEG(t *testing.T, w, h int, orientation uint16) []byte {
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, testImage(w, h), &jpeg.Options{Quality: 100}))
+
+	var tiff bytes.Buffer
+	tiff.WriteString("MM")
+	binary.Write(&tiff, binary.BigEndian, uint16(42))
+	binary.Write(&tiff, binary.BigEndian, uint32(8))
+	binary.Write(&tiff, binary.BigEndian, uint16(2))
+	// Orientation，SHORT
+	binary.Write(&tiff, binary.BigEndian, []uint16{0x0112, 3})
+	binary.Write(&tiff, binary.BigEndian, uint32(1))
+	binary.Write(&tiff, binary.BigEndian, []uint16{orientation, 0})
+	// GPS IFD 指针，LONG
+	binary.Write(&tiff, binary.BigEndian, []uint16{0x8825, 4})
+	binary.Write(&tiff, binary.BigEndian, uint32(1))
+	binary.Write(&tiff, binary.BigEndian, uint32(38))
+	binary.Write(&tiff, binary.BigEndian, uint32(0))
+	// GPS IFD：GPSLatitudeRef = "N"
+	binary.Write(&tiff, binary.BigEndian, uint16(1))
+	binary.Write(&tiff, binary.BigEndian, []uint16{0x0001, 2})
+	binary.Write(&tiff, binary.BigEndian, uint32(2))
+	tiff.Write([]byte{'N', 0, 0, 0})
+	binary.Write(&tiff, binary.BigEndian, uint32(0))
+
+	seg := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+
+	var out bytes.Buffer
+	out.Write(buf.Bytes()[:2])
+	out.Write([]byte{0xFF, 0xE1})
+	binary.Write(&out, binary.BigEndian, uint16(len(seg)+2))
+	out.Write(seg)
+	out.Write(buf.Bytes()[2:])
+
+	return out.Bytes()
+}
+
+func isRed(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r > 0xc000 && g < 0x4000 && b < 0x4000
+}
+
+func isBlue(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r < 0x4000 && g < 0x4000 && b > 0xc000
+}
+
+func TestProcessProfileImage(t *testing.T) {
+	t.Run("PNG 居中裁剪并生成各个尺寸", func(t *testing.T) {
+		variants, err := processProfileImage(bytes.NewReader(encodeTestPNG(t, 400, 200)))
+		assert.NoError(t, err)
+		assert.Len(t, variants, 3)
+
+		for i, size := range []int{512, 256, 64} {
+			assert.Equal(t, size, variants[i].Size)
+
+			img, format, err := image.Decode(bytes.NewReader(variants[i].Data))
+			assert.NoError(t, err)
+			assert.Equal(t, "jpeg", format)
+			assert.Equal(t, image.Rect(0, 0, size, size), img.Bounds())
+
+			// 裁剪后左半部分为红色，右半部分为蓝色
+			assert.True(t, isRed(img.At(size/8, size/2)))
+			assert.True(t, isBlue(img.At(size-size/8, size/2)))
+		}
+	})
+
+	t.Run("JPEG 按 EXIF 方向摆正并去除元数据", func(t *testing.T) {
+		data := encodeTestJPEG(t, 400, 200, 6)
+		assert.Equal(t, 6, jpegOrientation(data))
+
+		variants, err := processProfileImage(bytes.NewReader(data))
+		assert.NoError(t, err)
+
+		for _, v := range variants {
+			assert.False(t, bytes.Contains(v.Data, []byte("Exif")))
+			assert.Equal(t, 1, jpegOrientation(v.Data))
+
+			img, err := jpeg.Decode(bytes.NewReader(v.Data))
+			assert.NoError(t, err)
+
+			// 顺时针旋转 90° 后，左侧的红色位于上方
+			assert.True(t, isRed(img.At(v.Size/2, v.Size/8)))
+			assert.True(t, isBlue(img.At(v.Size/2, v.Size-v.Size/8)))
+		}
+	})
+
+	t.Run("WebP", func(t *testing.T) {
+		data, err := ioutil.ReadFile("testdata/gopher.webp")
+		assert.NoError(t, err)
+
+		variants, err := processProfileImage(bytes.NewReader(data))
+		assert.NoError(t, err)
+		assert.Len(t, variants, 3)
+	})
+
+	t.Run("图片尺寸超出限制", func(t *testing.T) {
+		// 仅包含头部的 GIF，声明的尺寸为 65535x65535
+		data := []byte("GIF89a\xff\xff\xff\xff\x00\x00\x00")
+
+		_, err := processProfileImage(bytes.NewReader(data))
+		assert.Error(t, err)
+	})
+
+	t.Run("无法解析的图片", func(t *testing.T) {
+		_, err := processProfileImage(bytes.NewReader([]byte("not an image")))
+		assert.Error(t, err)
+	})
+}
+
+func TestProfileImageObjNames(t *testing.T) {
+	assert.Equal(t, []string{"abc_64.jpg", "abc_256.jpg", "abc_512.jpg"}, profileImageObjNames("abc_256.jpg"))
+	assert.Equal(t, []string{"abc_64.jpg", "abc_256.jpg", "abc_512.jpg", "abc_128.jpg"}, profileImageObjNames("abc_128.jpg"))
+	assert.Equal(t, []string{"old.png"}, profileImageObjNames("old.png"))
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	recoveryCodeCount = 10
+	// 每个恢复码 10 个字符，每个字符 5 位，共 50 位熵
+	recoveryCodeLength   = 10
+	recoveryCodeAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+)
+
+// generateRecoveryCodes 生成 n 个形如 abcde-fgh23 的恢复码
+func generateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	b := make([]byte, recoveryCodeLength)
+
+	for i := range codes {
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		var sb strings.Builder
+		for j, c := range b {
+			if j == recoveryCodeLength/2 {
+				sb.WriteByte('-')
+			}
+			sb.WriteByte(recoveryCodeAlphabet[int(c)%len(recoveryCodeAlphabet)])
+		}
+		codes[i] = sb.String()
+	}
+
+	return codes, nil
+}
+
+// normalizeRecoveryCode 忽略大小写、空格与连字符，便于用户手动输入
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	code = strings.ReplaceAll(code, " ", "")
+
+	return code
+}
+
+// isRecoveryCode 判断用户提交的是恢复码还是 TOTP 验证码
+func isRecoveryCode(code string) bool {
+	return len(normalizeRecoveryCode(code)) == recoveryCodeLength
+}
+
+// hashRecoveryCode 返回恢复码以 secret 为密钥的 HMAC-SHA256 摘要。恢复码有 50 位熵，
+// 不需要 argon2 这类慢哈希，校验时可直接按摘要查找；摘要包含 uid，恢复码只对所属用户有效
+func hashRecoveryCode(secret string, uid uuid.UUID, code string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(uid.String()))
+	mac.Write([]byte{0})
+	mac.Write([]byte(normalizeRecoveryCode(code)))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
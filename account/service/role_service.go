@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/google/uuid"
+)
+
+type roleService struct {
+	RoleRepository model.RoleRepository
+	AuditLogger    model.AuditLogger
+}
+
+// RSConfig 中 AuditLogger 为 nil 时不记录认证事件
+type RSConfig struct {
+	RoleRepository model.RoleRepository
+	AuditLogger    model.AuditLogger
+}
+
+func NewRoleService(c *RSConfig) model.RoleService {
+	return &roleService{
+		RoleRepository: c.RoleRepository,
+		AuditLogger:    c.AuditLogger,
+	}
+}
+
+func (s *roleService) ListUserRoles(ctx context.Context, uid uuid.UUID) ([]*model.Role, error) {
+	return s.RoleRepository.ListByUID(ctx, uid)
+}
+
+// AssignRole 为 uid 分配角色，actorUID 为执行操作的管理员，使用管理令牌时为 uuid.Nil
+func (s *roleService) AssignRole(ctx context.Context, actorUID uuid.UUID, uid uuid.UUID, role string) error {
+	if err := s.RoleRepository.Assign(ctx, uid, role); err != nil {
+		return err
+	}
+
+	logAuthEvent(ctx, s.AuditLogger, model.AuthEventRoleAssigned, uid, "", roleChangeDetail(actorUID, role))
+
+	return nil
+}
+
+// UnassignRole 移除 uid 的角色，actorUID 的含义同 AssignRole
+func (s *roleService) UnassignRole(ctx context.Context, actorUID uuid.UUID, uid uuid.UUID, role string) error {
+	if err := s.RoleRepository.Unassign(ctx, uid, role); err != nil {
+		return err
+	}
+
+	logAuthEvent(ctx, s.AuditLogger, model.AuthEventRoleUnassigned, uid, "", roleChangeDetail(actorUID, role))
+
+	return nil
+}
+
+// roleChangeDetail 返回角色变更事件的说明，如 `admin by {uid}`，审计日志只记录被操作的用户，
+// 执行操作的管理员记录在这里
+func roleChangeDetail(actorUID uuid.UUID, role string) string {
+	actor := "admin_token"
+	if actorUID != uuid.Nil {
+		actor = actorUID.String()
+	}
+
+	return fmt.Sprintf("%s by %s", role, actor)
+}
+
+// rolesAndPermissions 返回角色的名称与角色授予的所有权限，去掉重复的权限
+func rolesAndPermissions(roles []*model.Role) ([]string, []string) {
+	names := make([]string, 0, len(roles))
+	var permissions []string
+	seen := make(map[string]bool)
+
+	for _, role := range roles {
+		names = append(names, role.Name)
+		for _, p := range role.Permissions {
+			if !seen[p] {
+				seen[p] = true
+				permissions = append(permissions, p)
+			}
+		}
+	}
+
+	return names, permissions
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRoleService(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	actorUID, _ := uuid.NewRandom()
+
+	mockRoleRepository := new(mocks.MockRoleRepository)
+	mockAuditService := new(mocks.MockAuditService)
+
+	s := NewRoleService(&RSConfig{
+		RoleRepository: mockRoleRepository,
+		AuditLogger:    mockAuditService,
+	})
+
+	t.Run("分配角色", func(t *testing.T) {
+		mockRoleRepository.On("Assign", mock.AnythingOfType("*context.emptyCtx"), uid, "admin").Return(nil)
+		mockAuditService.On("Log", mock.AnythingOfType("*context.emptyCtx"), &model.AuthEvent{
+			UID:    uid,
+			Type:   model.AuthEventRoleAssigned,
+			Detail: "admin by " + actorUID.String(),
+		})
+
+		err := s.AssignRole(context.TODO(), actorUID, uid, "admin")
+
+		assert.NoError(t, err)
+		mockAuditService.AssertExpectations(t)
+	})
+
+	t.Run("角色不存在", func(t *testing.T) {
+		mockErr := apperrors.NewNotFound("role", "root")
+		mockRoleRepository.On("Assign", mock.AnythingOfType("*context.emptyCtx"), uid, "root").Return(mockErr)
+
+		err := s.AssignRole(context.TODO(), actorUID, uid, "root")
+
+		assert.Equal(t, mockErr, err)
+		mockAuditService.AssertNumberOfCalls(t, "Log", 1)
+	})
+
+	t.Run("移除角色", func(t *testing.T) {
+		mockRoleRepository.On("Unassign", mock.AnythingOfType("*context.emptyCtx"), uid, "admin").Return(nil)
+		mockAuditService.On("Log", mock.AnythingOfType("*context.emptyCtx"), &model.AuthEvent{
+			UID:    uid,
+			Type:   model.AuthEventRoleUnassigned,
+			Detail: "admin by admin_token",
+		})
+
+		// 使用管理令牌时没有执行操作的用户
+		err := s.UnassignRole(context.TODO(), uuid.Nil, uid, "admin")
+
+		assert.NoError(t, err)
+		mockAuditService.AssertNumberOfCalls(t, "Log", 2)
+	})
+}
+
+func TestRolesAndPermissions(t *testing.T) {
+	names, permissions := rolesAndPermissions([]*model.Role{
+		{Name: "admin", Permissions: []string{"roles:manage", "users:unlock"}},
+		{Name: "support", Permissions: []string{"roles:read", "users:unlock"}},
+		{Name: "empty"},
+	})
+
+	assert.Equal(t, []string{"admin", "support", "empty"}, names)
+	assert.Equal(t, []string{"roles:manage", "users:unlock", "roles:read"}, permissions)
+}
@@ -1,7 +1,10 @@
 package service
 
 import (
-	"crypto/rsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"time"
@@ -11,24 +14,44 @@ import (
 	"github.com/google/uuid"
 )
 
+// IDTokenCustomClaims 使用 OpenID Connect 标准 claims，sub 为用户的 uid，
+// roles 与 permissions 为用户的角色及其授予的权限
 type IDTokenCustomClaims struct {
-	User *model.User `json:"user"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+	Name          string   `json:"name,omitempty"`
+	Picture       string   `json:"picture,omitempty"`
+	Website       string   `json:"website,omitempty"`
+	Roles         []string `json:"roles,omitempty"`
+	Permissions   []string `json:"permissions,omitempty"`
 	jwt.StandardClaims
 }
 
-func generateIDToken(u *model.User, key *rsa.PrivateKey, exp int64) (string, error) {
+func generateIDToken(u *model.User, keyRing *KeyRing, issuer string, audience string, exp int64) (string, error) {
 	unixTime := time.Now().Unix()
 	tokenExp := unixTime + exp
 
 	claims := IDTokenCustomClaims{
-		User: u,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		Name:          u.Name,
+		Picture:       u.ImageURL,
+		Website:       u.Website,
+		Roles:         u.Roles,
+		Permissions:   u.Permissions,
 		StandardClaims: jwt.StandardClaims{
+			Subject:   u.UID.String(),
+			Issuer:    issuer,
+			Audience:  audience,
 			IssuedAt:  unixTime,
 			ExpiresAt: tokenExp,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	kid, method, key := keyRing.SigningKey()
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
 
 	ss, err := token.SignedString(key)
 	if err != nil {
@@ -84,11 +107,12 @@ func generateRefreshToken(uid uuid.UUID, refreshSecret string, exp int64) (*Refr
 	}, nil
 }
 
-func validateIDToken(tokenString string, key *rsa.PublicKey) (*IDTokenCustomClaims, error) {
+func validateIDToken(tokenString string, keyRing *KeyRing, issuer string, audience string) (*IDTokenCustomClaims, error) {
 	claims := &IDTokenCustomClaims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
-		return key, nil
+		kid, _ := t.Header["kid"].(string)
+		return keyRing.PublicKey(kid, t.Method)
 	})
 
 	if err != nil {
@@ -105,5 +129,140 @@ func validateIDToken(tokenString string, key *rsa.PublicKey) (*IDTokenCustomClai
 		return nil, fmt.Errorf("ID 令牌有效，但无法解析 claims")
 	}
 
+	if !claims.VerifyIssuer(issuer, true) {
+		return nil, fmt.Errorf("ID 令牌的签发者无效：%v", claims.Issuer)
+	}
+
+	if !claims.VerifyAudience(audience, true) {
+		return nil, fmt.Errorf("ID 令牌的受众无效：%v", claims.Audience)
+	}
+
+	return claims, nil
+}
+
+func validateRefreshToken(tokenString string, key string) (*RefreshTokenCustomClaims, error) {
+	claims := &RefreshTokenCustomClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("不支持的签名算法：%v", t.Header["alg"])
+		}
+		return []byte(key), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("刷新令牌无效")
+	}
+
+	claims, ok := token.Claims.(*RefreshTokenCustomClaims)
+
+	if !ok {
+		return nil, fmt.Errorf("刷新令牌有效，但无法解析 claims")
+	}
+
+	return claims, nil
+}
+
+// emailVerificationAudience 区分邮箱验证令牌与其他使用 HS256 签署的令牌
+const emailVerificationAudience = "verify_email"
+
+type EmailVerificationToken struct {
+	SS        string
+	ID        string
+	ExpiresIn time.Duration
+}
+
+// EmailVerificationCustomClaims 中 sub 为用户的 uid，email 为签发时用户的邮箱，
+// jti 保存在 Redis 中以保证令牌只能使用一次
+type EmailVerificationCustomClaims struct {
+	Email string `json:"email"`
+	jwt.StandardClaims
+}
+
+func generateEmailVerificationToken(u *model.User, secret string, exp int64) (*EmailVerificationToken, error) {
+	currentTime := time.Now()
+	tokenExp := currentTime.Add(time.Duration(exp) * time.Second)
+	tokenID, err := uuid.NewRandom()
+
+	if err != nil {
+		log.Println("生成邮箱验证令牌 ID 失败")
+		return nil, err
+	}
+
+	claims := EmailVerificationCustomClaims{
+		Email: u.Email,
+		StandardClaims: jwt.StandardClaims{
+			Subject:   u.UID.String(),
+			Audience:  emailVerificationAudience,
+			IssuedAt:  currentTime.Unix(),
+			ExpiresAt: tokenExp.Unix(),
+			Id:        tokenID.String(),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	ss, err := token.SignedString([]byte(secret))
+
+	if err != nil {
+		log.Println("签署邮箱验证令牌字符串失败")
+		return nil, err
+	}
+
+	return &EmailVerificationToken{
+		SS:        ss,
+		ID:        tokenID.String(),
+		ExpiresIn: tokenExp.Sub(currentTime),
+	}, nil
+}
+
+func validateEmailVerificationToken(tokenString string, key string) (*EmailVerificationCustomClaims, error) {
+	claims := &EmailVerificationCustomClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("不支持的签名算法：%v", t.Header["alg"])
+		}
+		return []byte(key), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("邮箱验证令牌无效")
+	}
+
+	claims, ok := token.Claims.(*EmailVerificationCustomClaims)
+
+	if !ok {
+		return nil, fmt.Errorf("邮箱验证令牌有效，但无法解析 claims")
+	}
+
+	if !claims.VerifyAudience(emailVerificationAudience, true) {
+		return nil, fmt.Errorf("邮箱验证令牌的受众无效：%v", claims.Audience)
+	}
+
 	return claims, nil
 }
+
+// generateOneTimeToken 生成随机的不透明令牌，返回令牌本身及其摘要，只有摘要会被保存
+func generateOneTimeToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	return token, hashOneTimeToken(token), nil
+}
+
+func hashOneTimeToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
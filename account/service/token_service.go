@@ -2,44 +2,68 @@ package service
 
 import (
 	"context"
-	"crypto/rsa"
 	"log"
+	"time"
 
 	"github.com/FuZhouJohn/memrizr/account/model"
 	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/google/uuid"
 )
 
 type tokenService struct {
 	TokenRepository       model.TokenRepository
-	PrivKey               *rsa.PrivateKey
-	PubKey                *rsa.PublicKey
+	RoleRepository        model.RoleRepository
+	KeyRing               *KeyRing
+	Issuer                string
+	Audience              string
 	RefreshSecret         string
 	IDExpirationSecs      int64
 	RefreshExpirationSecs int64
+	AuditLogger           model.AuditLogger
+	LoginAlertService     model.LoginAlertService
 }
 
+// TSConfig 中 RoleRepository 为 nil 时 ID 令牌中不包含角色与权限，AuditLogger 为 nil 时不记录认证事件，
+// LoginAlertService 为 nil 时不发送新设备登录提醒
 type TSConfig struct {
 	TokenRepository       model.TokenRepository
-	PrivKey               *rsa.PrivateKey
-	PubKey                *rsa.PublicKey
+	RoleRepository        model.RoleRepository
+	KeyRing               *KeyRing
+	Issuer                string
+	Audience              string
 	RefreshSecret         string
 	IDExpirationSecs      int64
 	RefreshExpirationSecs int64
+	AuditLogger           model.AuditLogger
+	LoginAlertService     model.LoginAlertService
 }
 
 func NewTokenService(c *TSConfig) model.TokenService {
 	return &tokenService{
 		TokenRepository:       c.TokenRepository,
-		PrivKey:               c.PrivKey,
-		PubKey:                c.PubKey,
+		RoleRepository:        c.RoleRepository,
+		KeyRing:               c.KeyRing,
+		Issuer:                c.Issuer,
+		Audience:              c.Audience,
 		RefreshSecret:         c.RefreshSecret,
 		IDExpirationSecs:      c.IDExpirationSecs,
 		RefreshExpirationSecs: c.RefreshExpirationSecs,
+		AuditLogger:           c.AuditLogger,
+		LoginAlertService:     c.LoginAlertService,
 	}
 }
 
-func (s *tokenService) NewPairFromUser(ctx context.Context, u *model.User, prevTokenID string) (*model.TokenPair, error) {
-	idToken, err := generateIDToken(u, s.PrivKey, s.IDExpirationSecs)
+func (s *tokenService) NewPairFromUser(ctx context.Context, u *model.User, prevTokenID string, client *model.ClientInfo) (*model.TokenPair, error) {
+	family, err := s.rotateFamily(ctx, u, prevTokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.loadRoles(ctx, u); err != nil {
+		return nil, err
+	}
+
+	idToken, err := generateIDToken(u, s.KeyRing, s.Issuer, s.Audience, s.IDExpirationSecs)
 
 	if err != nil {
 		log.Printf("为 uid:%v 生成 idToken 时出错，错误：%v\n", u.UID, err.Error())
@@ -53,30 +77,225 @@ func (s *tokenService) NewPairFromUser(ctx context.Context, u *model.User, prevT
 		return nil, apperrors.NewInternal()
 	}
 
-	if err := s.TokenRepository.SetRefreshToken(ctx, u.UID.String(), refreshToken.ID, refreshToken.ExpiresIn); err != nil {
-		log.Printf("存储用户 tokenID 时出错，uid：%v。错误：%v\n", u.UID, err.Error())
-		return nil, apperrors.NewInternal()
+	if client != nil {
+		family.UserAgent = client.UserAgent
+		family.IP = client.IP
 	}
+	family.LastRefreshedAt = time.Now()
 
-	if prevTokenID != "" {
-		if err := s.TokenRepository.DeleteRefreshToken(ctx, u.UID.String(), prevTokenID); err != nil {
-			log.Printf("无法删除前一个 refreshToken，uid：%v，tokenID：%v\n", u.UID.String(), prevTokenID)
+	if prevTokenID == "" {
+		if err := s.TokenRepository.SetRefreshToken(ctx, u.UID.String(), refreshToken.ID, family, refreshToken.ExpiresIn); err != nil {
+			log.Printf("存储用户 tokenID 时出错，uid：%v。错误：%v\n", u.UID, err.Error())
+			return nil, apperrors.NewInternal()
+		}
+	} else {
+		// rotateFamily 的检查与写入之间可能有并发的刷新请求，这里原子地比较并轮换，失败的一方视为重复使用
+		rotated, err := s.TokenRepository.RotateRefreshToken(ctx, u.UID.String(), prevTokenID, refreshToken.ID, family, refreshToken.ExpiresIn)
+		if err != nil {
+			log.Printf("轮换用户 tokenID 时出错，uid：%v。错误：%v\n", u.UID, err.Error())
+			return nil, apperrors.NewInternal()
+		}
+		if !rotated {
+			return nil, s.revokeReusedFamily(ctx, u, family.ID, prevTokenID)
 		}
 	}
 
+	// 每次登录（包括注册、MFA 与通行密钥）都会开启新的会话，刷新令牌时沿用原来的会话
+	if prevTokenID == "" && s.LoginAlertService != nil {
+		s.LoginAlertService.NewSession(ctx, u, family)
+	}
+
 	return &model.TokenPair{
 		IDToken:      idToken,
 		RefreshToken: refreshToken.SS,
 	}, nil
 }
 
+// NewIDToken 为用户签发新的 ID 令牌而不创建新的会话，用于用户信息变更后刷新令牌中的 claims
+func (s *tokenService) NewIDToken(ctx context.Context, u *model.User) (string, error) {
+	if err := s.loadRoles(ctx, u); err != nil {
+		return "", err
+	}
+
+	idToken, err := generateIDToken(u, s.KeyRing, s.Issuer, s.Audience, s.IDExpirationSecs)
+
+	if err != nil {
+		log.Printf("为 uid:%v 生成 idToken 时出错，错误：%v\n", u.UID, err.Error())
+		return "", apperrors.NewInternal()
+	}
+
+	return idToken, nil
+}
+
+// loadRoles 查询用户当前的角色与权限，写入 ID 令牌的 claims
+func (s *tokenService) loadRoles(ctx context.Context, u *model.User) error {
+	if s.RoleRepository == nil {
+		return nil
+	}
+
+	roles, err := s.RoleRepository.ListByUID(ctx, u.UID)
+	if err != nil {
+		log.Printf("无法获取 uid:%v 的角色，错误：%v\n", u.UID, err)
+		return err
+	}
+
+	u.Roles, u.Permissions = rolesAndPermissions(roles)
+
+	return nil
+}
+
+// rotateFamily 返回新刷新令牌所属的 family。prevTokenID 为空时开启新的 family；
+// 若 prevTokenID 已被轮换过，说明令牌可能被盗用，撤销整个 family 并记录安全事件
+func (s *tokenService) rotateFamily(ctx context.Context, u *model.User, prevTokenID string) (*model.TokenFamily, error) {
+	if prevTokenID == "" {
+		familyID, err := uuid.NewRandom()
+		if err != nil {
+			log.Printf("为 uid:%v 生成 family ID 时出错，错误：%v\n", u.UID, err.Error())
+			return nil, apperrors.NewInternal()
+		}
+		return &model.TokenFamily{
+			ID:        familyID.String(),
+			CreatedAt: time.Now(),
+		}, nil
+	}
+
+	family, err := s.TokenRepository.GetTokenFamily(ctx, u.UID.String(), prevTokenID)
+	if err != nil {
+		log.Printf("无法获取前一个 refreshToken 的 family，uid：%v，tokenID：%v\n", u.UID.String(), prevTokenID)
+		return nil, err
+	}
+
+	if family.TokenID == prevTokenID {
+		return family, nil
+	}
+
+	return nil, s.revokeReusedFamily(ctx, u, family.ID, prevTokenID)
+}
+
+// revokeReusedFamily 撤销被重复使用的刷新令牌所属的 family，记录安全事件并返回需要重新登录的错误
+func (s *tokenService) revokeReusedFamily(ctx context.Context, u *model.User, familyID string, prevTokenID string) error {
+	log.Printf("检测到 refreshToken 被重复使用，撤销 family，uid：%v，familyID：%v，tokenID：%v\n", u.UID.String(), familyID, prevTokenID)
+
+	if err := s.TokenRepository.DeleteTokenFamily(ctx, u.UID.String(), familyID); err != nil {
+		return err
+	}
+
+	event := &model.SecurityEvent{
+		Type:      model.SecurityEventTokenReuse,
+		UserID:    u.UID.String(),
+		FamilyID:  familyID,
+		TokenID:   prevTokenID,
+		CreatedAt: time.Now(),
+	}
+	if err := s.TokenRepository.AddSecurityEvent(ctx, event); err != nil {
+		log.Printf("记录安全事件失败，uid：%v，familyID：%v\n", u.UID.String(), familyID)
+	}
+	logAuthEvent(ctx, s.AuditLogger, model.AuthEventTokenReuse, u.UID, u.Email, familyID)
+
+	return apperrors.NewAuthorization("刷新令牌已被使用，请重新登录")
+}
+
 func (s *tokenService) ValidateIDToken(tokenString string) (*model.User, error) {
-	claims, err := validateIDToken(tokenString, s.PubKey)
+	claims, err := validateIDToken(tokenString, s.KeyRing, s.Issuer, s.Audience)
 
 	if err != nil {
 		log.Printf("无法验证或解析 idToken - 错误：%v\n", err)
 		return nil, apperrors.NewAuthorization("无法从 idToken 验证用户")
 	}
 
-	return claims.User, nil
+	uid, err := uuid.Parse(claims.Subject)
+
+	if err != nil {
+		log.Printf("无法解析 idToken 的 sub：%v - 错误：%v\n", claims.Subject, err)
+		return nil, apperrors.NewAuthorization("无法从 idToken 验证用户")
+	}
+
+	return &model.User{
+		UID:           uid,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+		ImageURL:      claims.Picture,
+		Website:       claims.Website,
+		Roles:         claims.Roles,
+		Permissions:   claims.Permissions,
+	}, nil
+}
+
+func (s *tokenService) ValidateRefreshToken(tokenString string) (*model.RefreshToken, error) {
+	claims, err := validateRefreshToken(tokenString, s.RefreshSecret)
+
+	if err != nil {
+		log.Printf("无法验证或解析 refreshToken - 错误：%v\n", err)
+		return nil, apperrors.NewAuthorization("无法验证刷新令牌")
+	}
+
+	tokenUUID, err := uuid.Parse(claims.Id)
+
+	if err != nil {
+		log.Printf("无法解析 refreshToken 的 ID：%v - 错误：%v\n", claims.Id, err)
+		return nil, apperrors.NewAuthorization("无法验证刷新令牌")
+	}
+
+	return &model.RefreshToken{
+		SS:  tokenString,
+		ID:  tokenUUID,
+		UID: claims.UID,
+	}, nil
+}
+
+// Signout 撤销 tokenID 所在的会话；tokenID 为空时撤销用户的所有会话
+func (s *tokenService) Signout(ctx context.Context, uid uuid.UUID, tokenID string) error {
+	if tokenID == "" {
+		if err := s.TokenRepository.DeleteUserRefreshTokens(ctx, uid.String()); err != nil {
+			return err
+		}
+
+		logAuthEvent(ctx, s.AuditLogger, model.AuthEventSignout, uid, "", "all")
+		return nil
+	}
+
+	family, err := s.TokenRepository.GetTokenFamily(ctx, uid.String(), tokenID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.TokenRepository.DeleteTokenFamily(ctx, uid.String(), family.ID); err != nil {
+		return err
+	}
+
+	logAuthEvent(ctx, s.AuditLogger, model.AuthEventSignout, uid, "", family.ID)
+	return nil
+}
+
+// ListSessions 返回用户当前有效的会话，按最近刷新时间倒序排列
+func (s *tokenService) ListSessions(ctx context.Context, uid uuid.UUID) ([]*model.TokenFamily, error) {
+	return s.TokenRepository.ListTokenFamilies(ctx, uid.String())
+}
+
+// RevokeSession 撤销用户的某个会话，该会话中的刷新令牌随之失效
+func (s *tokenService) RevokeSession(ctx context.Context, uid uuid.UUID, sessionID string) error {
+	if err := s.TokenRepository.DeleteTokenFamily(ctx, uid.String(), sessionID); err != nil {
+		return err
+	}
+
+	logAuthEvent(ctx, s.AuditLogger, model.AuthEventSessionRevoked, uid, "", sessionID)
+	return nil
+}
+
+// JWKS 返回可用于验证 ID 令牌的公钥集合
+func (s *tokenService) JWKS() *model.JWKS {
+	return s.KeyRing.JWKS()
+}
+
+// OpenIDConfiguration 返回签发者与 JWKS 的元数据，不包含 OIDC 登录流程所需的端点
+func (s *tokenService) OpenIDConfiguration() *model.OpenIDConfiguration {
+	return &model.OpenIDConfiguration{
+		Issuer:                           s.Issuer,
+		JWKSURI:                          s.Issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:           []string{"id_token"},
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: s.KeyRing.Algorithms(),
+		ClaimsSupported:                  []string{"sub", "iss", "aud", "exp", "iat", "email", "email_verified", "name", "picture", "website", "roles", "permissions"},
+	}
 }
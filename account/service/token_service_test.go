@@ -2,12 +2,20 @@ package service
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"testing"
 	"time"
 
 	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
 	"github.com/FuZhouJohn/memrizr/account/model/mocks"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/google/uuid"
@@ -23,16 +31,27 @@ func TestNewPairFromUser(t *testing.T) {
 	pub, _ := ioutil.ReadFile("../rsa_public_test.pem")
 	pubKey, _ := jwt.ParseRSAPublicKeyFromPEM(pub)
 	secret := "anotsorandomtestsecret"
+	issuer := "http://malcorp.test/api/account"
+	audience := "memrizr"
+
+	keyRing, _ := NewKeyRing(privKey)
 
 	mockTokenRepository := new(mocks.MockTokenRepository)
+	mockAuditService := new(mocks.MockAuditService)
+	mockAuditService.On("Log", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.AuthEvent"))
+	mockLoginAlertService := new(mocks.MockLoginAlertService)
+	mockLoginAlertService.On("NewSession", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.User"), mock.AnythingOfType("*model.TokenFamily"))
 	// 实例化一个共同的令牌服务，供所有测试使用
 	tokenService := NewTokenService(&TSConfig{
 		TokenRepository:       mockTokenRepository,
-		PrivKey:               privKey,
-		PubKey:                pubKey,
+		KeyRing:               keyRing,
+		Issuer:                issuer,
+		Audience:              audience,
 		RefreshSecret:         secret,
 		IDExpirationSecs:      idExp,
 		RefreshExpirationSecs: refreshExp,
+		AuditLogger:           mockAuditService,
+		LoginAlertService:     mockLoginAlertService,
 	})
 
 	uid, _ := uuid.NewRandom()
@@ -50,10 +69,18 @@ func TestNewPairFromUser(t *testing.T) {
 	}
 	prevID := "a_previous_tokenID"
 
+	familyID := "a_token_familyID"
+	familyCreatedAt := time.Now().Add(-time.Hour)
+	client := &model.ClientInfo{
+		UserAgent: "Mozilla/5.0",
+		IP:        "127.0.0.1",
+	}
+
 	setSuccessArguments := mock.Arguments{
 		mock.AnythingOfType("*context.emptyCtx"),
 		u.UID.String(),
 		mock.AnythingOfType("string"),
+		mock.AnythingOfType("*model.TokenFamily"),
 		mock.AnythingOfType("time.Duration"),
 	}
 
@@ -61,10 +88,26 @@ func TestNewPairFromUser(t *testing.T) {
 		mock.AnythingOfType("*context.emptyCtx"),
 		uidErrorCase.String(),
 		mock.AnythingOfType("string"),
+		mock.AnythingOfType("*model.TokenFamily"),
+		mock.AnythingOfType("time.Duration"),
+	}
+
+	rotateWithFamilyArguments := mock.Arguments{
+		mock.AnythingOfType("*context.emptyCtx"),
+		u.UID.String(),
+		prevID,
+		mock.AnythingOfType("string"),
+		mock.MatchedBy(func(f *model.TokenFamily) bool {
+			return f.ID == familyID &&
+				f.CreatedAt.Equal(familyCreatedAt) &&
+				f.UserAgent == client.UserAgent &&
+				f.IP == client.IP &&
+				time.Since(f.LastRefreshedAt) < 5*time.Second
+		}),
 		mock.AnythingOfType("time.Duration"),
 	}
 
-	deleteWithPrevIDArguments := mock.Arguments{
+	getFamilyWithPrevIDArguments := mock.Arguments{
 		mock.AnythingOfType("*context.emptyCtx"),
 		u.UID.String(),
 		prevID,
@@ -72,15 +115,53 @@ func TestNewPairFromUser(t *testing.T) {
 
 	mockTokenRepository.On("SetRefreshToken", setSuccessArguments...).Return(nil)
 	mockTokenRepository.On("SetRefreshToken", setErrorArguments...).Return(fmt.Errorf("Error setting refresh token"))
-	mockTokenRepository.On("DeleteRefreshToken", deleteWithPrevIDArguments...).Return(nil)
+	mockTokenRepository.On("RotateRefreshToken", rotateWithFamilyArguments...).Return(true, nil)
+	mockTokenRepository.On("GetTokenFamily", getFamilyWithPrevIDArguments...).Return(&model.TokenFamily{
+		ID:        familyID,
+		TokenID:   prevID,
+		CreatedAt: familyCreatedAt,
+	}, nil)
+
+	// 已被轮换过的令牌，family 中最新的令牌不是它
+	reusedPrevID := "a_rotated_tokenID"
+	reusedFamilyID := "a_reused_familyID"
+	getFamilyWithReusedIDArguments := mock.Arguments{
+		mock.AnythingOfType("*context.emptyCtx"),
+		u.UID.String(),
+		reusedPrevID,
+	}
+	deleteReusedFamilyArguments := mock.Arguments{
+		mock.AnythingOfType("*context.emptyCtx"),
+		u.UID.String(),
+		reusedFamilyID,
+	}
+	mockTokenRepository.On("GetTokenFamily", getFamilyWithReusedIDArguments...).Return(&model.TokenFamily{
+		ID:      reusedFamilyID,
+		TokenID: "a_newer_tokenID",
+	}, nil)
+	mockTokenRepository.On("DeleteTokenFamily", deleteReusedFamilyArguments...).Return(nil)
+	mockTokenRepository.On("AddSecurityEvent", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.SecurityEvent")).Return(nil)
+
+	// 不存在或所属 family 已被撤销的令牌
+	unknownPrevID := "an_unknown_tokenID"
+	getFamilyWithUnknownIDArguments := mock.Arguments{
+		mock.AnythingOfType("*context.emptyCtx"),
+		u.UID.String(),
+		unknownPrevID,
+	}
+	mockTokenRepository.On("GetTokenFamily", getFamilyWithUnknownIDArguments...).Return(nil, apperrors.NewAuthorization("无效的刷新令牌"))
 
 	t.Run("返回一个正确的令牌对", func(t *testing.T) {
 		ctx := context.Background()
-		tokenPair, err := tokenService.NewPairFromUser(ctx, u, prevID)
+		tokenPair, err := tokenService.NewPairFromUser(ctx, u, prevID, client)
 		assert.NoError(t, err)
 
-		mockTokenRepository.AssertCalled(t, "SetRefreshToken", setSuccessArguments...)
-		mockTokenRepository.AssertCalled(t, "DeleteRefreshToken", deleteWithPrevIDArguments...)
+		mockTokenRepository.AssertCalled(t, "GetTokenFamily", getFamilyWithPrevIDArguments...)
+		mockTokenRepository.AssertCalled(t, "RotateRefreshToken", rotateWithFamilyArguments...)
+		mockTokenRepository.AssertNotCalled(t, "SetRefreshToken", mock.Anything, u.UID.String(), mock.Anything, mock.Anything, mock.Anything)
+		mockTokenRepository.AssertNotCalled(t, "DeleteTokenFamily")
+		// 刷新令牌时沿用原来的会话，不检查设备
+		mockLoginAlertService.AssertNotCalled(t, "NewSession", mock.Anything, mock.Anything, mock.Anything)
 
 		var s string
 		assert.IsType(t, s, tokenPair.IDToken)
@@ -94,21 +175,25 @@ func TestNewPairFromUser(t *testing.T) {
 		assert.NoError(t, err)
 
 		expectedClaims := []interface{}{
-			u.UID,
+			u.UID.String(),
 			u.Email,
 			u.Name,
 			u.ImageURL,
 			u.Website,
+			issuer,
+			audience,
 		}
 		actualIDClaims := []interface{}{
-			idTokenClaims.User.UID,
-			idTokenClaims.User.Email,
-			idTokenClaims.User.Name,
-			idTokenClaims.User.ImageURL,
-			idTokenClaims.User.Website,
+			idTokenClaims.Subject,
+			idTokenClaims.Email,
+			idTokenClaims.Name,
+			idTokenClaims.Picture,
+			idTokenClaims.Website,
+			idTokenClaims.Issuer,
+			idTokenClaims.Audience,
 		}
 		assert.ElementsMatch(t, expectedClaims, actualIDClaims)
-		assert.Empty(t, idTokenClaims.User.Password)
+		assert.NotContains(t, tokenPair.IDToken, u.Password)
 
 		expiresAt := time.Unix(idTokenClaims.StandardClaims.ExpiresAt, 0)
 		expectedExpiresAt := time.Now().Add(time.Duration(idExp) * time.Second)
@@ -131,19 +216,578 @@ func TestNewPairFromUser(t *testing.T) {
 
 	t.Run("设置 refreshToken 时出错", func(t *testing.T) {
 		ctx := context.Background()
-		_, err := tokenService.NewPairFromUser(ctx, uErrorCase, "")
+		_, err := tokenService.NewPairFromUser(ctx, uErrorCase, "", client)
 		assert.Error(t, err)
 
 		mockTokenRepository.AssertCalled(t, "SetRefreshToken", setErrorArguments...)
-		mockTokenRepository.AssertNotCalled(t, "DeleteRefreshToken")
+		mockTokenRepository.AssertNotCalled(t, "GetTokenFamily", mock.Anything, uidErrorCase.String(), mock.Anything)
+		mockLoginAlertService.AssertNotCalled(t, "NewSession", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("前一个 refreshToken 已不存在", func(t *testing.T) {
+		ctx := context.Background()
+		tokenPair, err := tokenService.NewPairFromUser(ctx, u, unknownPrevID, client)
+		assert.Nil(t, tokenPair)
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+
+		mockTokenRepository.AssertCalled(t, "GetTokenFamily", getFamilyWithUnknownIDArguments...)
+		mockTokenRepository.AssertNotCalled(t, "DeleteTokenFamily")
+		mockTokenRepository.AssertNotCalled(t, "AddSecurityEvent")
+	})
+
+	t.Run("已轮换的 refreshToken 被重复使用", func(t *testing.T) {
+		ctx := context.Background()
+		tokenPair, err := tokenService.NewPairFromUser(ctx, u, reusedPrevID, client)
+		assert.Nil(t, tokenPair)
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+
+		mockTokenRepository.AssertCalled(t, "GetTokenFamily", getFamilyWithReusedIDArguments...)
+		mockTokenRepository.AssertCalled(t, "DeleteTokenFamily", deleteReusedFamilyArguments...)
+		mockTokenRepository.AssertCalled(t, "AddSecurityEvent", mock.AnythingOfType("*context.emptyCtx"), mock.MatchedBy(func(e *model.SecurityEvent) bool {
+			return e.Type == model.SecurityEventTokenReuse &&
+				e.UserID == u.UID.String() &&
+				e.FamilyID == reusedFamilyID &&
+				e.TokenID == reusedPrevID
+		}))
+		mockAuditService.AssertCalled(t, "Log", mock.AnythingOfType("*context.emptyCtx"), &model.AuthEvent{
+			UID:    u.UID,
+			Type:   model.AuthEventTokenReuse,
+			Detail: reusedFamilyID,
+			Email:  u.Email,
+		})
 	})
 
 	t.Run("当 prevID 为空", func(t *testing.T) {
 		ctx := context.Background()
-		_, err := tokenService.NewPairFromUser(ctx, u, "")
+		_, err := tokenService.NewPairFromUser(ctx, u, "", client)
 		assert.NoError(t, err)
 
 		mockTokenRepository.AssertCalled(t, "SetRefreshToken", setSuccessArguments...)
-		mockTokenRepository.AssertNotCalled(t, "DeleteRefreshToken")
+		mockTokenRepository.AssertNumberOfCalls(t, "GetTokenFamily", 3)
+		// 只有检测到重复使用时记录认证事件
+		mockAuditService.AssertNumberOfCalls(t, "Log", 1)
+		mockLoginAlertService.AssertCalled(t, "NewSession", mock.AnythingOfType("*context.emptyCtx"), u, mock.MatchedBy(func(f *model.TokenFamily) bool {
+			return f.ID != "" &&
+				f.UserAgent == client.UserAgent &&
+				f.IP == client.IP
+		}))
+	})
+}
+
+func TestNewPairFromUserConcurrentRefresh(t *testing.T) {
+	priv, _ := ioutil.ReadFile("../rsa_private_test.pem")
+	privKey, _ := jwt.ParseRSAPrivateKeyFromPEM(priv)
+	keyRing, _ := NewKeyRing(privKey)
+
+	uid, _ := uuid.NewRandom()
+	u := &model.User{
+		UID:   uid,
+		Email: "hello@world.com",
+	}
+	prevID := "a_previous_tokenID"
+	familyID := "a_token_familyID"
+
+	mockTokenRepository := new(mocks.MockTokenRepository)
+	mockAuditService := new(mocks.MockAuditService)
+	mockAuditService.On("Log", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.AuthEvent"))
+
+	// 两个请求都在对方轮换之前读到了 family，均通过 rotateFamily 的检查
+	mockTokenRepository.On("GetTokenFamily", mock.AnythingOfType("*context.emptyCtx"), uid.String(), prevID).Return(&model.TokenFamily{
+		ID:      familyID,
+		TokenID: prevID,
+	}, nil)
+	// 原子的比较并轮换只有先到的一方成功
+	rotateArguments := mock.Arguments{
+		mock.AnythingOfType("*context.emptyCtx"),
+		uid.String(),
+		prevID,
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("*model.TokenFamily"),
+		mock.AnythingOfType("time.Duration"),
+	}
+	mockTokenRepository.On("RotateRefreshToken", rotateArguments...).Return(true, nil).Once()
+	mockTokenRepository.On("RotateRefreshToken", rotateArguments...).Return(false, nil)
+	mockTokenRepository.On("DeleteTokenFamily", mock.AnythingOfType("*context.emptyCtx"), uid.String(), familyID).Return(nil)
+	mockTokenRepository.On("AddSecurityEvent", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.SecurityEvent")).Return(nil)
+
+	tokenService := NewTokenService(&TSConfig{
+		TokenRepository:       mockTokenRepository,
+		KeyRing:               keyRing,
+		Issuer:                "http://malcorp.test/api/account",
+		Audience:              "memrizr",
+		RefreshSecret:         "anotsorandomtestsecret",
+		IDExpirationSecs:      15 * 60,
+		RefreshExpirationSecs: 3 * 24 * 2600,
+		AuditLogger:           mockAuditService,
+	})
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := tokenService.NewPairFromUser(context.Background(), u, prevID, nil)
+			errs <- err
+		}()
+	}
+
+	var succeeded, reused int
+	for i := 0; i < 2; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		reused++
+	}
+
+	assert.Equal(t, 1, succeeded)
+	assert.Equal(t, 1, reused)
+	mockTokenRepository.AssertNumberOfCalls(t, "RotateRefreshToken", 2)
+	mockTokenRepository.AssertNotCalled(t, "SetRefreshToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockTokenRepository.AssertCalled(t, "DeleteTokenFamily", mock.AnythingOfType("*context.emptyCtx"), uid.String(), familyID)
+	mockTokenRepository.AssertCalled(t, "AddSecurityEvent", mock.AnythingOfType("*context.emptyCtx"), mock.MatchedBy(func(e *model.SecurityEvent) bool {
+		return e.Type == model.SecurityEventTokenReuse &&
+			e.FamilyID == familyID &&
+			e.TokenID == prevID
+	}))
+}
+
+func TestValidateRefreshToken(t *testing.T) {
+	var refreshExp int64 = 3 * 24 * 2600
+	secret := "anotsorandomtestsecret"
+
+	tokenService := NewTokenService(&TSConfig{
+		RefreshSecret:         secret,
+		RefreshExpirationSecs: refreshExp,
+	})
+
+	uid, _ := uuid.NewRandom()
+
+	testRefreshToken, _ := generateRefreshToken(uid, secret, refreshExp)
+
+	t.Run("有效的令牌", func(t *testing.T) {
+		refreshToken, err := tokenService.ValidateRefreshToken(testRefreshToken.SS)
+		assert.NoError(t, err)
+
+		assert.Equal(t, uid, refreshToken.UID)
+		assert.Equal(t, testRefreshToken.ID, refreshToken.ID.String())
+		assert.Equal(t, testRefreshToken.SS, refreshToken.SS)
+	})
+
+	t.Run("签名密钥不正确", func(t *testing.T) {
+		invalidToken, _ := generateRefreshToken(uid, "anotherSecret", refreshExp)
+
+		refreshToken, err := tokenService.ValidateRefreshToken(invalidToken.SS)
+		assert.Nil(t, refreshToken)
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+
+	t.Run("令牌已过期", func(t *testing.T) {
+		expiredToken, _ := generateRefreshToken(uid, secret, -1)
+
+		refreshToken, err := tokenService.ValidateRefreshToken(expiredToken.SS)
+		assert.Nil(t, refreshToken)
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+
+	t.Run("签名算法不正确", func(t *testing.T) {
+		claims := RefreshTokenCustomClaims{
+			UID: uid,
+			StandardClaims: jwt.StandardClaims{
+				ExpiresAt: time.Now().Add(time.Minute).Unix(),
+				Id:        uuid.New().String(),
+			},
+		}
+		ss, _ := jwt.NewWithClaims(jwt.SigningMethodHS512, claims).SignedString([]byte(secret))
+
+		refreshToken, err := tokenService.ValidateRefreshToken(ss)
+		assert.Nil(t, refreshToken)
+		assert.Error(t, err)
+	})
+}
+
+func TestSignout(t *testing.T) {
+	mockTokenRepository := new(mocks.MockTokenRepository)
+	mockAuditService := new(mocks.MockAuditService)
+	mockAuditService.On("Log", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.AuthEvent"))
+	tokenService := NewTokenService(&TSConfig{
+		TokenRepository: mockTokenRepository,
+		AuditLogger:     mockAuditService,
+	})
+
+	t.Run("撤销所有会话", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		mockTokenRepository.On("DeleteUserRefreshTokens", mock.AnythingOfType("*context.emptyCtx"), uid.String()).Return(nil)
+
+		ctx := context.Background()
+		err := tokenService.Signout(ctx, uid, "")
+
+		assert.NoError(t, err)
+		mockTokenRepository.AssertCalled(t, "DeleteUserRefreshTokens", mock.AnythingOfType("*context.emptyCtx"), uid.String())
+		mockAuditService.AssertCalled(t, "Log", mock.AnythingOfType("*context.emptyCtx"), &model.AuthEvent{
+			UID:    uid,
+			Type:   model.AuthEventSignout,
+			Detail: "all",
+		})
+	})
+
+	t.Run("撤销当前会话", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		tokenID := "a_tokenID"
+		familyID := "a_familyID"
+
+		mockTokenRepository.On("GetTokenFamily", mock.AnythingOfType("*context.emptyCtx"), uid.String(), tokenID).Return(&model.TokenFamily{
+			ID:      familyID,
+			TokenID: tokenID,
+		}, nil)
+		mockTokenRepository.On("DeleteTokenFamily", mock.AnythingOfType("*context.emptyCtx"), uid.String(), familyID).Return(nil)
+
+		ctx := context.Background()
+		err := tokenService.Signout(ctx, uid, tokenID)
+
+		assert.NoError(t, err)
+		mockTokenRepository.AssertCalled(t, "DeleteTokenFamily", mock.AnythingOfType("*context.emptyCtx"), uid.String(), familyID)
+		mockTokenRepository.AssertNotCalled(t, "DeleteUserRefreshTokens", mock.Anything, uid.String())
+		mockAuditService.AssertCalled(t, "Log", mock.AnythingOfType("*context.emptyCtx"), &model.AuthEvent{
+			UID:    uid,
+			Type:   model.AuthEventSignout,
+			Detail: familyID,
+		})
+	})
+
+	t.Run("刷新令牌已失效", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		tokenID := "an_invalid_tokenID"
+		mockErr := apperrors.NewAuthorization("无效的刷新令牌")
+
+		mockTokenRepository.On("GetTokenFamily", mock.AnythingOfType("*context.emptyCtx"), uid.String(), tokenID).Return(nil, mockErr)
+
+		ctx := context.Background()
+		err := tokenService.Signout(ctx, uid, tokenID)
+
+		assert.EqualError(t, err, mockErr.Error())
+		mockTokenRepository.AssertNotCalled(t, "DeleteTokenFamily", mock.Anything, uid.String(), mock.Anything)
+		mockAuditService.AssertNumberOfCalls(t, "Log", 2)
+	})
+}
+
+func TestListSessions(t *testing.T) {
+	mockTokenRepository := new(mocks.MockTokenRepository)
+	tokenService := NewTokenService(&TSConfig{
+		TokenRepository: mockTokenRepository,
+	})
+
+	uid, _ := uuid.NewRandom()
+	mockSessions := []*model.TokenFamily{
+		{
+			ID:              "a_familyID",
+			UserAgent:       "Mozilla/5.0",
+			IP:              "127.0.0.1",
+			CreatedAt:       time.Now().Add(-time.Hour),
+			LastRefreshedAt: time.Now(),
+		},
+	}
+
+	mockTokenRepository.On("ListTokenFamilies", mock.AnythingOfType("*context.emptyCtx"), uid.String()).Return(mockSessions, nil)
+
+	ctx := context.Background()
+	sessions, err := tokenService.ListSessions(ctx, uid)
+
+	assert.NoError(t, err)
+	assert.Equal(t, mockSessions, sessions)
+	mockTokenRepository.AssertExpectations(t)
+}
+
+func TestRevokeSession(t *testing.T) {
+	mockTokenRepository := new(mocks.MockTokenRepository)
+	tokenService := NewTokenService(&TSConfig{
+		TokenRepository: mockTokenRepository,
+	})
+
+	t.Run("成功", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		sessionID := "a_familyID"
+
+		mockTokenRepository.On("DeleteTokenFamily", mock.AnythingOfType("*context.emptyCtx"), uid.String(), sessionID).Return(nil)
+
+		ctx := context.Background()
+		err := tokenService.RevokeSession(ctx, uid, sessionID)
+
+		assert.NoError(t, err)
+		mockTokenRepository.AssertCalled(t, "DeleteTokenFamily", mock.AnythingOfType("*context.emptyCtx"), uid.String(), sessionID)
+	})
+
+	t.Run("会话不存在", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		sessionID := "an_unknown_familyID"
+		mockErr := apperrors.NewNotFound("session", sessionID)
+
+		mockTokenRepository.On("DeleteTokenFamily", mock.AnythingOfType("*context.emptyCtx"), uid.String(), sessionID).Return(mockErr)
+
+		ctx := context.Background()
+		err := tokenService.RevokeSession(ctx, uid, sessionID)
+
+		assert.EqualError(t, err, mockErr.Error())
+	})
+}
+
+func TestValidateIDToken(t *testing.T) {
+	var idExp int64 = 15 * 60
+	priv, _ := ioutil.ReadFile("../rsa_private_test.pem")
+	privKey, _ := jwt.ParseRSAPrivateKeyFromPEM(priv)
+
+	// 模拟轮换前使用的旧密钥
+	retiringKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	retiringKeyRing, _ := NewKeyRing(retiringKey)
+	unknownKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	unknownKeyRing, _ := NewKeyRing(unknownKey)
+
+	issuer := "http://malcorp.test/api/account"
+	audience := "memrizr"
+
+	keyRing, _ := NewKeyRing(privKey, retiringKey.Public())
+	tokenService := NewTokenService(&TSConfig{
+		KeyRing:          keyRing,
+		Issuer:           issuer,
+		Audience:         audience,
+		IDExpirationSecs: idExp,
+	})
+
+	uid, _ := uuid.NewRandom()
+	u := &model.User{
+		UID:      uid,
+		Email:    "hello@world.com",
+		Name:     "Bobby Bobson",
+		ImageURL: "http://malcorp.test/image.png",
+		Website:  "http://malcorp.test",
+	}
+
+	t.Run("使用 active 密钥签署", func(t *testing.T) {
+		ss, _ := generateIDToken(u, keyRing, issuer, audience, idExp)
+
+		user, err := tokenService.ValidateIDToken(ss)
+		assert.NoError(t, err)
+		assert.Equal(t, u, user)
+	})
+
+	t.Run("NewIDToken 签发的令牌可被验证", func(t *testing.T) {
+		ss, err := tokenService.NewIDToken(context.TODO(), u)
+		assert.NoError(t, err)
+
+		user, err := tokenService.ValidateIDToken(ss)
+		assert.NoError(t, err)
+		assert.Equal(t, u, user)
+	})
+
+	t.Run("签发者不正确", func(t *testing.T) {
+		ss, _ := generateIDToken(u, keyRing, "http://evil.test", audience, idExp)
+
+		user, err := tokenService.ValidateIDToken(ss)
+		assert.Nil(t, user)
+		assert.Error(t, err)
+	})
+
+	t.Run("受众不正确", func(t *testing.T) {
+		ss, _ := generateIDToken(u, keyRing, issuer, "another-app", idExp)
+
+		user, err := tokenService.ValidateIDToken(ss)
+		assert.Nil(t, user)
+		assert.Error(t, err)
+	})
+
+	t.Run("使用 retiring 密钥签署", func(t *testing.T) {
+		ss, _ := generateIDToken(u, retiringKeyRing, issuer, audience, idExp)
+
+		user, err := tokenService.ValidateIDToken(ss)
+		assert.NoError(t, err)
+		assert.Equal(t, u.UID, user.UID)
+	})
+
+	t.Run("未知的 kid", func(t *testing.T) {
+		ss, _ := generateIDToken(u, unknownKeyRing, issuer, audience, idExp)
+
+		user, err := tokenService.ValidateIDToken(ss)
+		assert.Nil(t, user)
+		assert.Error(t, err)
+	})
+
+	t.Run("kid 与签名密钥不符", func(t *testing.T) {
+		kid, _, _ := keyRing.SigningKey()
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, IDTokenCustomClaims{
+			Email: u.Email,
+			StandardClaims: jwt.StandardClaims{
+				Subject:   u.UID.String(),
+				Issuer:    issuer,
+				Audience:  audience,
+				ExpiresAt: time.Now().Add(time.Minute).Unix(),
+			},
+		})
+		token.Header["kid"] = kid
+		ss, _ := token.SignedString(unknownKey)
+
+		user, err := tokenService.ValidateIDToken(ss)
+		assert.Nil(t, user)
+		assert.Error(t, err)
+	})
+
+	t.Run("kid 与签名算法不符", func(t *testing.T) {
+		kid, _, _ := keyRing.SigningKey()
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, IDTokenCustomClaims{
+			Email: u.Email,
+			StandardClaims: jwt.StandardClaims{
+				Subject:   u.UID.String(),
+				Issuer:    issuer,
+				Audience:  audience,
+				ExpiresAt: time.Now().Add(time.Minute).Unix(),
+			},
+		})
+		token.Header["kid"] = kid
+		ss, _ := token.SignedString(retiringKey)
+
+		user, err := tokenService.ValidateIDToken(ss)
+		assert.Nil(t, user)
+		assert.Error(t, err)
+	})
+
+	t.Run("JWKS 包含所有可验证的公钥", func(t *testing.T) {
+		jwks := tokenService.JWKS()
+
+		assert.Len(t, jwks.Keys, 2)
+
+		activeKID, _, _ := keyRing.SigningKey()
+		retiringKID, _, _ := retiringKeyRing.SigningKey()
+
+		assert.Equal(t, activeKID, jwks.Keys[0].Kid)
+		assert.Equal(t, "RSA", jwks.Keys[0].Kty)
+		assert.Equal(t, "RS256", jwks.Keys[0].Alg)
+		assert.Equal(t, "AQAB", jwks.Keys[0].E)
+
+		assert.Equal(t, retiringKID, jwks.Keys[1].Kid)
+		assert.Equal(t, "EC", jwks.Keys[1].Kty)
+		assert.Equal(t, "ES256", jwks.Keys[1].Alg)
+		assert.Equal(t, "P-256", jwks.Keys[1].Crv)
+
+		for _, key := range jwks.Keys {
+			assert.Equal(t, "sig", key.Use)
+		}
+	})
+
+	t.Run("签发者元数据", func(t *testing.T) {
+		config := tokenService.OpenIDConfiguration()
+
+		assert.Equal(t, issuer, config.Issuer)
+		assert.Equal(t, issuer+"/.well-known/jwks.json", config.JWKSURI)
+		assert.Equal(t, []string{"RS256", "ES256"}, config.IDTokenSigningAlgValuesSupported)
+	})
+}
+
+func TestIDTokenRoles(t *testing.T) {
+	priv, _ := ioutil.ReadFile("../rsa_private_test.pem")
+	privKey, _ := jwt.ParseRSAPrivateKeyFromPEM(priv)
+	keyRing, _ := NewKeyRing(privKey)
+
+	uid, _ := uuid.NewRandom()
+	u := &model.User{
+		UID:   uid,
+		Email: "hello@world.com",
+	}
+
+	mockRoleRepository := new(mocks.MockRoleRepository)
+	tokenService := NewTokenService(&TSConfig{
+		RoleRepository:   mockRoleRepository,
+		KeyRing:          keyRing,
+		Issuer:           "http://malcorp.test/api/account",
+		Audience:         "memrizr",
+		IDExpirationSecs: 15 * 60,
+	})
+
+	t.Run("ID 令牌包含角色与权限", func(t *testing.T) {
+		mockRoleRepository.On("ListByUID", mock.AnythingOfType("*context.emptyCtx"), uid).Return([]*model.Role{
+			{Name: "admin", Permissions: []string{model.PermissionRolesManage, model.PermissionUsersUnlock}},
+			{Name: "support", Permissions: []string{model.PermissionUsersUnlock}},
+		}, nil).Once()
+
+		ss, err := tokenService.NewIDToken(context.TODO(), u)
+		assert.NoError(t, err)
+
+		user, err := tokenService.ValidateIDToken(ss)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"admin", "support"}, user.Roles)
+		assert.Equal(t, []string{model.PermissionRolesManage, model.PermissionUsersUnlock}, user.Permissions)
+		assert.True(t, user.HasPermissions(model.PermissionUsersUnlock))
+		assert.False(t, user.HasPermissions(model.PermissionRolesRead))
+	})
+
+	t.Run("无法获取角色时不签发令牌", func(t *testing.T) {
+		mockRoleRepository.On("ListByUID", mock.AnythingOfType("*context.emptyCtx"), uid).Return(nil, apperrors.NewInternal()).Once()
+
+		ss, err := tokenService.NewIDToken(context.TODO(), u)
+		assert.Empty(t, ss)
+		assert.Error(t, err)
+	})
+}
+
+func TestIDTokenSigningAlgorithms(t *testing.T) {
+	var idExp int64 = 15 * 60
+	issuer := "http://malcorp.test/api/account"
+	audience := "memrizr"
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	testCases := []struct {
+		alg string
+		key crypto.Signer
+	}{
+		{"RS256", rsaKey},
+		{"ES256", ecKey},
+		{"EdDSA", edKey},
+	}
+
+	u := &model.User{
+		UID:   uuid.New(),
+		Email: "hello@world.com",
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alg, func(t *testing.T) {
+			keyRing, err := NewKeyRing(tc.key)
+			assert.NoError(t, err)
+
+			tokenService := NewTokenService(&TSConfig{
+				KeyRing:          keyRing,
+				Issuer:           issuer,
+				Audience:         audience,
+				IDExpirationSecs: idExp,
+			})
+
+			ss, err := generateIDToken(u, keyRing, issuer, audience, idExp)
+			assert.NoError(t, err)
+
+			token, _, err := new(jwt.Parser).ParseUnverified(ss, &IDTokenCustomClaims{})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.alg, token.Header["alg"])
+
+			user, err := tokenService.ValidateIDToken(ss)
+			assert.NoError(t, err)
+			assert.Equal(t, u.UID, user.UID)
+
+			jwks := tokenService.JWKS()
+			assert.Len(t, jwks.Keys, 1)
+			assert.Equal(t, tc.alg, jwks.Keys[0].Alg)
+		})
+	}
+
+	t.Run("不支持的椭圆曲线", func(t *testing.T) {
+		p384Key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+
+		keyRing, err := NewKeyRing(p384Key)
+		assert.Nil(t, keyRing)
+		assert.Error(t, err)
 	})
 }
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// RFC 6238 附录 B 中 SHA1 的测试密钥 "12345678901234567890"
+const rfcTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 中为 8 位验证码，取后 6 位
+	cases := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for unix, expected := range cases {
+		code, err := totpCode(rfcTOTPSecret, unix/totpPeriod)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code)
+	}
+
+	_, err := totpCode("not base32!", 1)
+	assert.Error(t, err)
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+
+	step, ok := validateTOTP(rfcTOTPSecret, "005924", now)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1234567890/totpPeriod), step)
+
+	// 允许前后一个时间步的偏差
+	_, ok = validateTOTP(rfcTOTPSecret, "005924", now.Add(totpPeriod*time.Second))
+	assert.True(t, ok)
+
+	_, ok = validateTOTP(rfcTOTPSecret, "005924", now.Add(2*totpPeriod*time.Second))
+	assert.False(t, ok)
+
+	// 验证器中常以空格分隔
+	_, ok = validateTOTP(rfcTOTPSecret, "005 924", now)
+	assert.True(t, ok)
+
+	_, ok = validateTOTP(rfcTOTPSecret, "000000", now)
+	assert.False(t, ok)
+
+	_, ok = validateTOTP(rfcTOTPSecret, "5924", now)
+	assert.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	assert.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	uri, err := url.Parse(totpURI("Memrizr", "bob@bob.com", secret))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/Memrizr:bob@bob.com", uri.Path)
+	assert.Equal(t, secret, uri.Query().Get("secret"))
+	assert.Equal(t, "Memrizr", uri.Query().Get("issuer"))
+}
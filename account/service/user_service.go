@@ -1,25 +1,94 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
 
 	"github.com/FuZhouJohn/memrizr/account/model"
 	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
 	"github.com/google/uuid"
 )
 
+// 邮箱验证与重置密码令牌在 OneTimeTokenRepository 中的用途
+const (
+	emailVerificationPurpose = "verify_email"
+	passwordResetPurpose     = "reset_password"
+	mfaChallengePurpose      = "mfa_challenge"
+	accountUnlockPurpose     = "unlock_account"
+)
+
 type userService struct {
-	UserRepository model.UserRepository
+	UserRepository             model.UserRepository
+	ImageRepository            model.ImageRepository
+	OneTimeTokenRepository     model.OneTimeTokenRepository
+	RecoveryCodeRepository     model.RecoveryCodeRepository
+	Mailer                     model.Mailer
+	AuditLogger                model.AuditLogger
+	VerificationSecret         string
+	RecoveryCodeSecret         string
+	VerificationExpirationSecs int64
+	VerificationURL            string
+	ResetExpirationSecs        int64
+	ResetURL                   string
+	TOTPIssuer                 string
+	MFAChallengeExpirationSecs int64
+	LockoutThreshold           int
+	LockoutDurations           []time.Duration
+	UnlockExpirationSecs       int64
+	UnlockURL                  string
 }
 
+// USConfig 中 VerificationURL、ResetURL 与 UnlockURL 为邮件中链接指向的页面，令牌以 token 参数附加在其后。
+// 连续 LockoutThreshold 次密码错误后锁定账号，第 n 次锁定的时长为 LockoutDurations[n-1]，
+// 超出时使用最后一项。LockoutThreshold 为 0 时不锁定。AuditLogger 为 nil 时不记录认证事件
 type USConfig struct {
-	UserRepository model.UserRepository
+	UserRepository             model.UserRepository
+	ImageRepository            model.ImageRepository
+	OneTimeTokenRepository     model.OneTimeTokenRepository
+	RecoveryCodeRepository     model.RecoveryCodeRepository
+	Mailer                     model.Mailer
+	AuditLogger                model.AuditLogger
+	VerificationSecret         string
+	RecoveryCodeSecret         string
+	VerificationExpirationSecs int64
+	VerificationURL            string
+	ResetExpirationSecs        int64
+	ResetURL                   string
+	TOTPIssuer                 string
+	MFAChallengeExpirationSecs int64
+	LockoutThreshold           int
+	LockoutDurations           []time.Duration
+	UnlockExpirationSecs       int64
+	UnlockURL                  string
 }
 
 func NewUserService(c *USConfig) model.UserService {
 	return &userService{
-		UserRepository: c.UserRepository,
+		UserRepository:             c.UserRepository,
+		ImageRepository:            c.ImageRepository,
+		OneTimeTokenRepository:     c.OneTimeTokenRepository,
+		RecoveryCodeRepository:     c.RecoveryCodeRepository,
+		Mailer:                     c.Mailer,
+		AuditLogger:                c.AuditLogger,
+		VerificationSecret:         c.VerificationSecret,
+		RecoveryCodeSecret:         c.RecoveryCodeSecret,
+		VerificationExpirationSecs: c.VerificationExpirationSecs,
+		VerificationURL:            c.VerificationURL,
+		ResetExpirationSecs:        c.ResetExpirationSecs,
+		ResetURL:                   c.ResetURL,
+		TOTPIssuer:                 c.TOTPIssuer,
+		MFAChallengeExpirationSecs: c.MFAChallengeExpirationSecs,
+		LockoutThreshold:           c.LockoutThreshold,
+		LockoutDurations:           c.LockoutDurations,
+		UnlockExpirationSecs:       c.UnlockExpirationSecs,
+		UnlockURL:                  c.UnlockURL,
 	}
 }
 
@@ -42,12 +111,20 @@ func (s *userService) Signup(ctx context.Context, u *model.User) error {
 		return err
 	}
 
+	logAuthEvent(ctx, s.AuditLogger, model.AuthEventSignup, u.UID, u.Email, "")
+
+	// 发送失败时用户可以重新请求验证邮件，不影响注册
+	if err := s.SendEmailVerification(ctx, u); err != nil {
+		log.Printf("无法向 %v 发送验证邮件：%v\n", u.Email, err)
+	}
+
 	return nil
 }
 
 func (s *userService) Signin(ctx context.Context, u *model.User) error {
 	uFetched, err := s.UserRepository.FindByEmail(ctx, u.Email)
 	if err != nil {
+		logAuthEvent(ctx, s.AuditLogger, model.AuthEventSigninFailure, uuid.Nil, u.Email, "unknown_email")
 		return apperrors.NewAuthorization("用户名或密码错误")
 	}
 
@@ -56,10 +133,697 @@ func (s *userService) Signin(ctx context.Context, u *model.User) error {
 		return apperrors.NewInternal()
 	}
 
+	// 锁定期间无论密码是否正确都返回相同的错误，避免被用于探测账号是否被锁定，也不再累加错误次数
+	if uFetched.Locked(time.Now()) {
+		logAuthEvent(ctx, s.AuditLogger, model.AuthEventSigninFailure, uFetched.UID, u.Email, "locked")
+		return apperrors.NewAuthorization("用户名或密码错误")
+	}
+
 	if !match {
+		logAuthEvent(ctx, s.AuditLogger, model.AuthEventSigninFailure, uFetched.UID, u.Email, "wrong_password")
+		s.recordFailedSignin(ctx, uFetched)
 		return apperrors.NewAuthorization("用户名或密码错误")
 	}
 
+	if uFetched.FailedSigninCount > 0 || uFetched.LockoutCount > 0 {
+		if err := s.UserRepository.ResetSigninFailures(ctx, uFetched.UID); err != nil {
+			log.Printf("无法清除用户 %v 的密码错误次数：%v\n", uFetched.UID, err)
+		}
+		uFetched.FailedSigninCount = 0
+		uFetched.LockoutCount = 0
+		uFetched.LockedUntil = nil
+	}
+
+	// 只有登录时能拿到明文密码，借此将旧格式或低参数的哈希升级为当前格式，失败时下次登录再试。
+	// 只替换刚刚校验过的哈希，期间密码被修改或重置时放弃升级
+	if passwordNeedsRehash(uFetched.Password) {
+		if pw, err := hashPassword(u.Password); err != nil {
+			log.Printf("无法为用户 %v 重新生成密码哈希：%v\n", uFetched.UID, err)
+		} else if err := s.UserRepository.RehashPassword(ctx, uFetched.UID, uFetched.Password, pw); err != nil {
+			log.Printf("无法升级用户 %v 的密码哈希：%v\n", uFetched.UID, err)
+		} else {
+			uFetched.Password = pw
+		}
+	}
+
+	// 启用了 TOTP 时这里只表示密码正确，随后还会记录 MFA 事件
+	logAuthEvent(ctx, s.AuditLogger, model.AuthEventSigninSuccess, uFetched.UID, uFetched.Email, "password")
+
 	*u = *uFetched
 	return nil
 }
+
+// recordFailedSignin 累加密码错误次数，账号因此被锁定时发送解除锁定的邮件。
+// 失败时只记录日志，不影响登录接口的响应
+func (s *userService) recordFailedSignin(ctx context.Context, u *model.User) {
+	if s.LockoutThreshold <= 0 || len(s.LockoutDurations) == 0 {
+		return
+	}
+
+	lockoutSecs := make([]int64, len(s.LockoutDurations))
+	for i, d := range s.LockoutDurations {
+		lockoutSecs[i] = int64(d.Seconds())
+	}
+
+	updated, err := s.UserRepository.RecordFailedSignin(ctx, u.UID, s.LockoutThreshold, lockoutSecs)
+	if err != nil {
+		log.Printf("无法记录用户 %v 的密码错误次数：%v\n", u.UID, err)
+		return
+	}
+
+	if updated.LockoutCount <= u.LockoutCount {
+		return
+	}
+
+	log.Printf("用户 %v 连续 %v 次密码错误，账号锁定至 %v\n", u.UID, s.LockoutThreshold, updated.LockedUntil)
+
+	if err := s.sendAccountUnlock(ctx, updated); err != nil {
+		log.Printf("无法向 %v 发送解除锁定邮件：%v\n", updated.Email, err)
+	}
+}
+
+// sendAccountUnlock 签发一次性的解除锁定令牌，并将包含令牌的链接发送至用户的邮箱
+func (s *userService) sendAccountUnlock(ctx context.Context, u *model.User) error {
+	token, tokenHash, err := generateOneTimeToken()
+	if err != nil {
+		return err
+	}
+
+	expiresIn := time.Duration(s.UnlockExpirationSecs) * time.Second
+	if err := s.OneTimeTokenRepository.SetOneTimeToken(ctx, accountUnlockPurpose, tokenHash, u.UID.String(), expiresIn); err != nil {
+		return err
+	}
+
+	link, err := linkWithToken(s.UnlockURL, token)
+	if err != nil {
+		return err
+	}
+
+	return s.Mailer.SendAccountUnlock(ctx, u, link)
+}
+
+// UnlockAccount 使用解除锁定邮件中的令牌解除账号锁定，令牌只能使用一次
+func (s *userService) UnlockAccount(ctx context.Context, token string) error {
+	userID, err := s.OneTimeTokenRepository.ConsumeOneTimeToken(ctx, accountUnlockPurpose, hashOneTimeToken(token))
+	if err != nil {
+		return err
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		log.Printf("解除锁定令牌对应的 uid 无效：%v\n", userID)
+		return apperrors.NewInternal()
+	}
+
+	return s.UserRepository.ResetSigninFailures(ctx, uid)
+}
+
+// ClearLockout 由管理员解除账号锁定并清除密码错误次数
+func (s *userService) ClearLockout(ctx context.Context, uid uuid.UUID) error {
+	return s.UserRepository.ResetSigninFailures(ctx, uid)
+}
+
+// UpdateDetails 更新用户的名称、邮箱与网站，成功后 u 为更新后的完整用户信息。
+// 修改邮箱后需要重新验证，因此会向新邮箱发送验证邮件
+func (s *userService) UpdateDetails(ctx context.Context, u *model.User) error {
+	current, err := s.UserRepository.FindByID(ctx, u.UID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.UserRepository.Update(ctx, u); err != nil {
+		return err
+	}
+
+	if u.Email == current.Email {
+		return nil
+	}
+
+	// 资料已经更新，验证邮件发送失败时用户可以手动重新发送
+	if err := s.SendEmailVerification(ctx, u); err != nil {
+		log.Printf("修改邮箱后无法向 %v 发送验证邮件：%v\n", u.Email, err)
+	}
+
+	return nil
+}
+
+// SendEmailVerification 签发一次性的邮箱验证令牌，并将包含令牌的链接发送至用户的邮箱
+func (s *userService) SendEmailVerification(ctx context.Context, u *model.User) error {
+	if u.EmailVerified {
+		return apperrors.NewConflict("verified email", u.Email)
+	}
+
+	token, err := generateEmailVerificationToken(u, s.VerificationSecret, s.VerificationExpirationSecs)
+	if err != nil {
+		log.Printf("为 uid:%v 生成邮箱验证令牌时出错，错误：%v\n", u.UID, err)
+		return apperrors.NewInternal()
+	}
+
+	if err := s.OneTimeTokenRepository.SetOneTimeToken(ctx, emailVerificationPurpose, token.ID, u.UID.String(), token.ExpiresIn); err != nil {
+		return err
+	}
+
+	link, err := linkWithToken(s.VerificationURL, token.SS)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Mailer.SendEmailVerification(ctx, u, link); err != nil {
+		log.Printf("无法向 %v 发送验证邮件：%v\n", u.Email, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// VerifyEmail 使用验证令牌将用户的邮箱标记为已验证。令牌只能使用一次，
+// 签发后用户修改过邮箱时令牌失效
+func (s *userService) VerifyEmail(ctx context.Context, token string) (*model.User, error) {
+	claims, err := validateEmailVerificationToken(token, s.VerificationSecret)
+	if err != nil {
+		log.Printf("无法验证或解析邮箱验证令牌 - 错误：%v\n", err)
+		return nil, apperrors.NewAuthorization("无效的邮箱验证令牌")
+	}
+
+	uid, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		log.Printf("无法解析邮箱验证令牌的 sub：%v - 错误：%v\n", claims.Subject, err)
+		return nil, apperrors.NewAuthorization("无效的邮箱验证令牌")
+	}
+
+	userID, err := s.OneTimeTokenRepository.ConsumeOneTimeToken(ctx, emailVerificationPurpose, claims.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if userID != uid.String() {
+		log.Printf("邮箱验证令牌 %v 属于用户 %v，而非 %v\n", claims.Id, userID, uid)
+		return nil, apperrors.NewAuthorization("无效的邮箱验证令牌")
+	}
+
+	u, err := s.UserRepository.SetEmailVerified(ctx, uid, claims.Email)
+	if err != nil {
+		if apperrors.Status(err) == http.StatusNotFound {
+			return nil, apperrors.NewAuthorization("邮箱已变更，请重新验证")
+		}
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// RequestPasswordReset 向 email 对应的用户发送重置密码邮件。为避免被用于探测邮箱是否已注册，
+// 用户不存在时同样返回 nil
+func (s *userService) RequestPasswordReset(ctx context.Context, email string) error {
+	u, err := s.UserRepository.FindByEmail(ctx, email)
+	if err != nil {
+		log.Printf("请求重置密码的邮箱 %v 未注册：%v\n", email, err)
+		return nil
+	}
+
+	// Redis 中只保存令牌的摘要，即使数据泄露也无法用于重置密码
+	token, tokenHash, err := generateOneTimeToken()
+	if err != nil {
+		log.Printf("为 uid:%v 生成重置密码令牌时出错，错误：%v\n", u.UID, err)
+		return apperrors.NewInternal()
+	}
+
+	expiresIn := time.Duration(s.ResetExpirationSecs) * time.Second
+	if err := s.OneTimeTokenRepository.SetOneTimeToken(ctx, passwordResetPurpose, tokenHash, u.UID.String(), expiresIn); err != nil {
+		return err
+	}
+
+	link, err := linkWithToken(s.ResetURL, token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Mailer.SendPasswordReset(ctx, u, link); err != nil {
+		log.Printf("无法向 %v 发送重置密码邮件：%v\n", u.Email, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// FindByPasswordResetToken 返回重置密码令牌所属的用户而不使用令牌，
+// 供调用方在重置前按与注册时相同的规则检查新密码，如不能包含邮箱
+func (s *userService) FindByPasswordResetToken(ctx context.Context, token string) (*model.User, error) {
+	userID, err := s.OneTimeTokenRepository.GetOneTimeToken(ctx, passwordResetPurpose, hashOneTimeToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		log.Printf("重置密码令牌对应的 uid 无效：%v\n", userID)
+		return nil, apperrors.NewInternal()
+	}
+
+	return s.UserRepository.FindByID(ctx, uid)
+}
+
+// ResetPassword 使用重置密码令牌设置新密码并解除账号的锁定，返回用户的 uid，令牌只能使用一次。
+// 调用方需要撤销该用户现有的会话
+func (s *userService) ResetPassword(ctx context.Context, token string, password string) (uuid.UUID, error) {
+	userID, err := s.OneTimeTokenRepository.ConsumeOneTimeToken(ctx, passwordResetPurpose, hashOneTimeToken(token))
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		log.Printf("重置密码令牌对应的 uid 无效：%v\n", userID)
+		return uuid.Nil, apperrors.NewInternal()
+	}
+
+	pw, err := hashPassword(password)
+	if err != nil {
+		log.Printf("无法为用户 %v 生成密码哈希：%v\n", uid, err)
+		return uuid.Nil, apperrors.NewInternal()
+	}
+
+	if err := s.UserRepository.UpdatePassword(ctx, uid, pw); err != nil {
+		return uuid.Nil, err
+	}
+
+	// 用户已证明拥有该邮箱，与解除锁定邮件相同，同时清除密码错误次数与锁定状态
+	if err := s.UserRepository.ResetSigninFailures(ctx, uid); err != nil {
+		log.Printf("重置密码后无法解除用户 %v 的锁定：%v\n", uid, err)
+	}
+
+	logAuthEvent(ctx, s.AuditLogger, model.AuthEventPasswordChange, uid, "", "reset")
+
+	return uid, nil
+}
+
+// ChangePassword 验证当前密码后设置新密码。当前密码错误与登录时一样累加错误次数，
+// 否则持有会话的攻击者可以借此无限次猜测密码
+func (s *userService) ChangePassword(ctx context.Context, uid uuid.UUID, currentPassword string, newPassword string) error {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	match, err := comparePasswords(u.Password, currentPassword)
+	if err != nil {
+		return apperrors.NewInternal()
+	}
+
+	// 锁定期间无论密码是否正确都返回相同的错误，也不再累加错误次数
+	if u.Locked(time.Now()) {
+		return apperrors.NewAuthorization("当前密码错误")
+	}
+
+	if !match {
+		s.recordFailedSignin(ctx, u)
+		return apperrors.NewAuthorization("当前密码错误")
+	}
+
+	if currentPassword == newPassword {
+		return apperrors.NewBadRequest("新密码不能与当前密码相同")
+	}
+
+	pw, err := hashPassword(newPassword)
+	if err != nil {
+		log.Printf("无法为用户 %v 生成密码哈希：%v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	if err := s.UserRepository.UpdatePassword(ctx, uid, pw); err != nil {
+		return err
+	}
+
+	logAuthEvent(ctx, s.AuditLogger, model.AuthEventPasswordChange, uid, u.Email, "change")
+
+	return nil
+}
+
+// EnrollTOTP 为用户生成新的 TOTP 密钥，ConfirmTOTP 校验通过后才会启用。
+// 已启用时需要先停用，未确认的密钥会被覆盖
+func (s *userService) EnrollTOTP(ctx context.Context, uid uuid.UUID) (*model.TOTPEnrollment, error) {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.TOTPEnabled {
+		return nil, apperrors.NewConflict("totp", u.Email)
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		log.Printf("为 uid:%v 生成 TOTP 密钥时出错，错误：%v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	if _, err := s.UserRepository.UpdateTOTP(ctx, uid, secret, false); err != nil {
+		return nil, err
+	}
+
+	return &model.TOTPEnrollment{
+		Secret: secret,
+		URI:    totpURI(s.TOTPIssuer, u.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP 使用验证器生成的验证码确认绑定，之后登录需要验证码。
+// 同时生成恢复码，恢复码只在此时返回一次
+func (s *userService) ConfirmTOTP(ctx context.Context, uid uuid.UUID, code string) (*model.User, []string, error) {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if u.TOTPEnabled {
+		return nil, nil, apperrors.NewConflict("totp", u.Email)
+	}
+
+	if u.TOTPSecret == "" {
+		return nil, nil, apperrors.NewBadRequest("请先绑定 TOTP 验证器")
+	}
+
+	if err := s.checkTOTP(ctx, u, code); err != nil {
+		return nil, nil, err
+	}
+
+	recoveryCodes, err := s.newRecoveryCodes(ctx, uid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	u, err = s.UserRepository.UpdateTOTP(ctx, uid, u.TOTPSecret, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return u, recoveryCodes, nil
+}
+
+// DisableTOTP 校验验证码或恢复码后停用 TOTP，并删除密钥与恢复码。
+// 接受恢复码使丢失验证器的用户也能停用 TOTP
+func (s *userService) DisableTOTP(ctx context.Context, uid uuid.UUID, code string) (*model.User, error) {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !u.TOTPEnabled {
+		return nil, apperrors.NewBadRequest("未启用 TOTP")
+	}
+
+	if _, err := s.checkMFACode(ctx, u, code); err != nil {
+		return nil, err
+	}
+
+	u, err = s.UserRepository.UpdateTOTP(ctx, uid, "", false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.RecoveryCodeRepository.DeleteRecoveryCodes(ctx, uid); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// NewMFAChallenge 在密码校验通过后签发 MFA 令牌，用于随后提交验证码。
+// 与重置密码令牌相同，Redis 中只保存令牌的摘要
+func (s *userService) NewMFAChallenge(ctx context.Context, u *model.User) (string, error) {
+	token, tokenHash, err := generateOneTimeToken()
+	if err != nil {
+		log.Printf("为 uid:%v 生成 MFA 令牌时出错，错误：%v\n", u.UID, err)
+		return "", apperrors.NewInternal()
+	}
+
+	expiresIn := time.Duration(s.MFAChallengeExpirationSecs) * time.Second
+	if err := s.OneTimeTokenRepository.SetOneTimeToken(ctx, mfaChallengePurpose, tokenHash, u.UID.String(), expiresIn); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// VerifyMFAChallenge 校验 MFA 令牌与验证码，code 也可以是恢复码，成功时返回用户。
+// 令牌无论验证码是否正确都只能使用一次，每次猜测验证码都需要重新输入密码
+func (s *userService) VerifyMFAChallenge(ctx context.Context, mfaToken string, code string) (*model.User, error) {
+	userID, err := s.OneTimeTokenRepository.ConsumeOneTimeToken(ctx, mfaChallengePurpose, hashOneTimeToken(mfaToken))
+	if err != nil {
+		return nil, err
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		log.Printf("MFA 令牌对应的 uid 无效：%v\n", userID)
+		return nil, apperrors.NewInternal()
+	}
+
+	u, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	// 签发令牌后用户停用了 TOTP
+	if !u.TOTPEnabled {
+		return nil, apperrors.NewAuthorization("MFA 令牌无效，请重新登录")
+	}
+
+	method, err := s.checkMFACode(ctx, u, code)
+	if err != nil {
+		logAuthEvent(ctx, s.AuditLogger, model.AuthEventMFAFailure, u.UID, u.Email, method)
+		return nil, err
+	}
+
+	logAuthEvent(ctx, s.AuditLogger, model.AuthEventMFASuccess, u.UID, u.Email, method)
+
+	return u, nil
+}
+
+// RegenerateRecoveryCodes 校验 TOTP 验证码或恢复码后生成新的恢复码，已有的恢复码全部失效
+func (s *userService) RegenerateRecoveryCodes(ctx context.Context, uid uuid.UUID, code string) ([]string, error) {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !u.TOTPEnabled {
+		return nil, apperrors.NewBadRequest("未启用 TOTP")
+	}
+
+	if _, err := s.checkMFACode(ctx, u, code); err != nil {
+		return nil, err
+	}
+
+	return s.newRecoveryCodes(ctx, uid)
+}
+
+// CountRecoveryCodes 返回用户剩余未使用的恢复码数量
+func (s *userService) CountRecoveryCodes(ctx context.Context, uid uuid.UUID) (int, error) {
+	return s.RecoveryCodeRepository.CountUnusedRecoveryCodes(ctx, uid)
+}
+
+// newRecoveryCodes 生成并保存新的恢复码，返回明文。恢复码是高熵的随机值，只保存 HMAC 摘要
+func (s *userService) newRecoveryCodes(ctx context.Context, uid uuid.UUID) ([]string, error) {
+	codes, err := generateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		log.Printf("为 uid:%v 生成恢复码时出错，错误：%v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	codeHashes := make([]string, len(codes))
+	for i, code := range codes {
+		codeHashes[i] = hashRecoveryCode(s.RecoveryCodeSecret, uid, code)
+	}
+
+	if err := s.RecoveryCodeRepository.ReplaceRecoveryCodes(ctx, uid, codeHashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// checkMFACode 校验 TOTP 验证码或恢复码，返回所用的方式
+func (s *userService) checkMFACode(ctx context.Context, u *model.User, code string) (string, error) {
+	if isRecoveryCode(code) {
+		return "recovery_code", s.useRecoveryCode(ctx, u, code)
+	}
+
+	return "totp", s.checkTOTP(ctx, u, code)
+}
+
+// useRecoveryCode 按摘要查找恢复码并将其标记为已使用
+func (s *userService) useRecoveryCode(ctx context.Context, u *model.User, code string) error {
+	if err := s.RecoveryCodeRepository.UseRecoveryCode(ctx, u.UID, hashRecoveryCode(s.RecoveryCodeSecret, u.UID, code)); err != nil {
+		if apperrors.Status(err) == http.StatusNotFound {
+			return apperrors.NewAuthorization("恢复码错误")
+		}
+		return err
+	}
+
+	log.Printf("用户 %v 使用了恢复码\n", u.UID)
+	return nil
+}
+
+// checkTOTP 校验验证码并记录其时间步，已使用过的验证码视为错误
+func (s *userService) checkTOTP(ctx context.Context, u *model.User, code string) error {
+	step, ok := validateTOTP(u.TOTPSecret, code, time.Now())
+	if !ok || step <= u.TOTPLastUsedStep {
+		return apperrors.NewAuthorization("验证码错误")
+	}
+
+	if err := s.UserRepository.UseTOTPStep(ctx, u.UID, step); err != nil {
+		if apperrors.Status(err) == http.StatusNotFound {
+			return apperrors.NewAuthorization("验证码错误")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// linkWithToken 将令牌以 token 参数附加在 base 之后
+func linkWithToken(base string, token string) (string, error) {
+	link, err := url.Parse(base)
+	if err != nil {
+		log.Printf("无法解析链接 %v：%v\n", base, err)
+		return "", apperrors.NewInternal()
+	}
+
+	q := link.Query()
+	q.Set("token", token)
+	link.RawQuery = q.Encode()
+
+	return link.String(), nil
+}
+
+// profileImageTypes 允许上传的头像类型
+var profileImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// SetProfileImage 处理并上传用户头像，更新 image_url。头像类型以文件内容为准而非客户端声明的 Content-Type
+func (s *userService) SetProfileImage(ctx context.Context, uid uuid.UUID, imageFileHeader *multipart.FileHeader) (*model.User, error) {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	imageFile, err := imageFileHeader.Open()
+	if err != nil {
+		log.Printf("无法打开上传的图片：%v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+	defer imageFile.Close()
+
+	// DetectContentType 最多只读取前 512 个字节
+	buf := make([]byte, 512)
+	n, err := imageFile.Read(buf)
+	if err != nil && n == 0 {
+		log.Printf("无法读取上传的图片：%v\n", err)
+		return nil, apperrors.NewBadRequest("无法读取上传的图片")
+	}
+
+	contentType := http.DetectContentType(buf[:n])
+	if !profileImageTypes[contentType] {
+		log.Printf("不支持的图片类型：%v\n", contentType)
+		return nil, apperrors.NewUnsupportedMediaType(fmt.Sprintf("不支持的图片类型：%v", contentType))
+	}
+
+	if _, err := imageFile.Seek(0, 0); err != nil {
+		log.Printf("无法读取上传的图片：%v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	variants, err := processProfileImage(imageFile)
+	if err != nil {
+		log.Printf("无法处理上传的图片：%v\n", err)
+		return nil, apperrors.NewBadRequest("无法处理上传的图片")
+	}
+
+	// 每次上传使用新的对象名，避免客户端与 CDN 缓存旧头像
+	objID, err := uuid.NewRandom()
+	if err != nil {
+		log.Printf("无法生成图片对象名：%v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	var imageURL string
+	for _, v := range variants {
+		objName := profileImageObjName(objID.String(), v.Size)
+
+		url, err := s.ImageRepository.UpdateProfile(ctx, objName, bytes.NewReader(v.Data), int64(len(v.Data)), "image/jpeg")
+		if err != nil {
+			s.deleteProfileImage(ctx, profileImageObjName(objID.String(), defaultProfileImageSize))
+			return nil, err
+		}
+
+		if v.Size == defaultProfileImageSize {
+			imageURL = url
+		}
+	}
+
+	updatedUser, err := s.UserRepository.UpdateImage(ctx, uid, imageURL)
+	if err != nil {
+		s.deleteProfileImage(ctx, objNameFromURL(imageURL))
+		return nil, err
+	}
+
+	if u.ImageURL != "" {
+		s.deleteProfileImage(ctx, objNameFromURL(u.ImageURL))
+	}
+
+	return updatedUser, nil
+}
+
+// ClearProfileImage 清空用户的 image_url 并删除对应的图片
+func (s *userService) ClearProfileImage(ctx context.Context, uid uuid.UUID) (*model.User, error) {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.ImageURL == "" {
+		return u, nil
+	}
+
+	updatedUser, err := s.UserRepository.UpdateImage(ctx, uid, "")
+	if err != nil {
+		return nil, err
+	}
+
+	s.deleteProfileImage(ctx, objNameFromURL(u.ImageURL))
+
+	return updatedUser, nil
+}
+
+// deleteProfileImage 删除 objName 所属头像的所有尺寸。此时 image_url 已不再引用该头像，
+// 删除失败只会留下无用的对象，因此只记录日志
+func (s *userService) deleteProfileImage(ctx context.Context, objName string) {
+	if objName == "" {
+		return
+	}
+
+	for _, name := range profileImageObjNames(objName) {
+		if err := s.ImageRepository.DeleteProfile(ctx, name); err != nil {
+			log.Printf("无法删除图片 %v：%v\n", name, err)
+		}
+	}
+}
+
+func objNameFromURL(imageURL string) string {
+	u, err := url.Parse(imageURL)
+	if err != nil {
+		return ""
+	}
+
+	objName := path.Base(u.Path)
+	if objName == "." || objName == "/" {
+		return ""
+	}
+
+	return objName
+}
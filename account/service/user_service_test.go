@@ -1,9 +1,15 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/FuZhouJohn/memrizr/account/model"
 	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
@@ -66,8 +72,15 @@ func TestSignup(t *testing.T) {
 		}
 
 		mockUserRepository := new(mocks.MockUserRepository)
+		mockOneTimeTokenRepository := new(mocks.MockOneTimeTokenRepository)
+		mockMailer := new(mocks.MockMailer)
 		us := NewUserService(&USConfig{
-			UserRepository: mockUserRepository,
+			UserRepository:             mockUserRepository,
+			OneTimeTokenRepository:     mockOneTimeTokenRepository,
+			Mailer:                     mockMailer,
+			VerificationSecret:         "averysecretsecret",
+			VerificationExpirationSecs: 24 * 60 * 60,
+			VerificationURL:            "http://malcorp.test/verify-email",
 		})
 		mockUserRepository.On("Create", mock.AnythingOfType("*context.emptyCtx"), mockUser).
 			Run(func(args mock.Arguments) {
@@ -75,6 +88,14 @@ func TestSignup(t *testing.T) {
 				userArg.UID = uid
 			}).Return(nil)
 
+		// 注册后发送验证邮件
+		mockOneTimeTokenRepository.
+			On("SetOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "verify_email", mock.AnythingOfType("string"), uid.String(), mock.AnythingOfType("time.Duration")).
+			Return(nil)
+		mockMailer.
+			On("SendEmailVerification", mock.AnythingOfType("*context.emptyCtx"), mockUser, mock.AnythingOfType("string")).
+			Return(nil)
+
 		ctx := context.TODO()
 		err := us.Signup(ctx, mockUser)
 		assert.NoError(t, err)
@@ -82,6 +103,8 @@ func TestSignup(t *testing.T) {
 		assert.Equal(t, uid, mockUser.UID)
 
 		mockUserRepository.AssertExpectations(t)
+		mockOneTimeTokenRepository.AssertExpectations(t)
+		mockMailer.AssertExpectations(t)
 	})
 
 	t.Run("Error", func(t *testing.T) {
@@ -177,4 +200,1479 @@ func TestSignin(t *testing.T) {
 		assert.EqualError(t, err, "用户名或密码错误")
 		mockeUserRepository.AssertCalled(t, "FindByEmail", mockArgs...)
 	})
+
+	t.Run("旧格式的哈希登录成功后升级", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		legacyEmail := "legacy@world2.com"
+
+		mockUser := &model.User{
+			Email:    legacyEmail,
+			Password: vaildPW,
+		}
+
+		oldHash := legacyHash(t, vaildPW)
+		mockUserResp := &model.User{
+			UID:      uid,
+			Email:    legacyEmail,
+			Password: oldHash,
+		}
+
+		var rehashed string
+
+		mockeUserRepository.
+			On("FindByEmail", mock.AnythingOfType("*context.emptyCtx"), legacyEmail).
+			Return(mockUserResp, nil)
+		// 只替换校验过的旧哈希
+		mockeUserRepository.
+			On("RehashPassword", mock.AnythingOfType("*context.emptyCtx"), uid, oldHash, mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) {
+				rehashed = args.Get(3).(string)
+			}).
+			Return(nil)
+
+		err := us.Signin(context.TODO(), mockUser)
+
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(rehashed, "$argon2id$"))
+		assert.Equal(t, rehashed, mockUser.Password)
+
+		match, _ := comparePasswords(rehashed, vaildPW)
+		assert.True(t, match)
+
+		// 此前使用当前格式哈希的登录没有重新生成
+		mockeUserRepository.AssertNumberOfCalls(t, "RehashPassword", 1)
+		mockeUserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("升级哈希时密码已被修改", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		legacyEmail := "changed@world2.com"
+		oldHash := legacyHash(t, vaildPW)
+
+		mockUser := &model.User{
+			Email:    legacyEmail,
+			Password: vaildPW,
+		}
+
+		mockeUserRepository.
+			On("FindByEmail", mock.AnythingOfType("*context.emptyCtx"), legacyEmail).
+			Return(&model.User{
+				UID:      uid,
+				Email:    legacyEmail,
+				Password: oldHash,
+			}, nil)
+		mockeUserRepository.
+			On("RehashPassword", mock.AnythingOfType("*context.emptyCtx"), uid, oldHash, mock.AnythingOfType("string")).
+			Return(apperrors.NewNotFound("password", uid.String()))
+
+		err := us.Signin(context.TODO(), mockUser)
+
+		// 登录仍然成功，保留校验过的旧哈希
+		assert.NoError(t, err)
+		assert.Equal(t, oldHash, mockUser.Password)
+	})
+}
+
+func TestSigninAuthEvents(t *testing.T) {
+	email := "audit@world.com"
+	validPW := "avalidpassword"
+	hashedValidPW, _ := hashPassword(validPW)
+
+	newService := func() (model.UserService, *mocks.MockUserRepository, *mocks.MockAuditService) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockAuditService := new(mocks.MockAuditService)
+		mockAuditService.On("Log", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.AuthEvent"))
+
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+			AuditLogger:    mockAuditService,
+		})
+
+		return us, mockUserRepository, mockAuditService
+	}
+
+	t.Run("登录成功", func(t *testing.T) {
+		us, mockUserRepository, mockAuditService := newService()
+
+		uid, _ := uuid.NewRandom()
+		mockUserRepository.
+			On("FindByEmail", mock.AnythingOfType("*context.emptyCtx"), email).
+			Return(&model.User{UID: uid, Email: email, Password: hashedValidPW}, nil)
+
+		err := us.Signin(context.TODO(), &model.User{Email: email, Password: validPW})
+
+		assert.NoError(t, err)
+		mockAuditService.AssertCalled(t, "Log", mock.AnythingOfType("*context.emptyCtx"), &model.AuthEvent{
+			UID:    uid,
+			Type:   model.AuthEventSigninSuccess,
+			Detail: "password",
+			Email:  email,
+		})
+	})
+
+	t.Run("密码错误", func(t *testing.T) {
+		us, mockUserRepository, mockAuditService := newService()
+
+		uid, _ := uuid.NewRandom()
+		mockUserRepository.
+			On("FindByEmail", mock.AnythingOfType("*context.emptyCtx"), email).
+			Return(&model.User{UID: uid, Email: email, Password: hashedValidPW}, nil)
+
+		err := us.Signin(context.TODO(), &model.User{Email: email, Password: "wrongpassword"})
+
+		assert.EqualError(t, err, "用户名或密码错误")
+		mockAuditService.AssertCalled(t, "Log", mock.AnythingOfType("*context.emptyCtx"), &model.AuthEvent{
+			UID:    uid,
+			Type:   model.AuthEventSigninFailure,
+			Detail: "wrong_password",
+			Email:  email,
+		})
+	})
+
+	t.Run("邮箱不存在", func(t *testing.T) {
+		us, mockUserRepository, mockAuditService := newService()
+
+		mockUserRepository.
+			On("FindByEmail", mock.AnythingOfType("*context.emptyCtx"), email).
+			Return(nil, apperrors.NewNotFound("email", email))
+
+		err := us.Signin(context.TODO(), &model.User{Email: email, Password: validPW})
+
+		assert.EqualError(t, err, "用户名或密码错误")
+		mockAuditService.AssertCalled(t, "Log", mock.AnythingOfType("*context.emptyCtx"), &model.AuthEvent{
+			UID:    uuid.Nil,
+			Type:   model.AuthEventSigninFailure,
+			Detail: "unknown_email",
+			Email:  email,
+		})
+	})
+}
+
+func TestAccountLockout(t *testing.T) {
+	email := "bob@bob.com"
+	validPW := "avalidpassword"
+	hashedValidPW, _ := hashPassword(validPW)
+	lockoutSecs := []int64{60, 300}
+
+	newService := func() (model.UserService, *mocks.MockUserRepository, *mocks.MockOneTimeTokenRepository, *mocks.MockMailer) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockOneTimeTokenRepository := new(mocks.MockOneTimeTokenRepository)
+		mockMailer := new(mocks.MockMailer)
+
+		us := NewUserService(&USConfig{
+			UserRepository:         mockUserRepository,
+			OneTimeTokenRepository: mockOneTimeTokenRepository,
+			Mailer:                 mockMailer,
+			LockoutThreshold:       5,
+			LockoutDurations:       []time.Duration{time.Minute, 5 * time.Minute},
+			UnlockExpirationSecs:   3600,
+			UnlockURL:              "http://malcorp.test/unlock",
+		})
+
+		return us, mockUserRepository, mockOneTimeTokenRepository, mockMailer
+	}
+
+	t.Run("密码错误时累加次数", func(t *testing.T) {
+		us, mockUserRepository, mockOneTimeTokenRepository, mockMailer := newService()
+
+		uid, _ := uuid.NewRandom()
+		mockUserResp := &model.User{
+			UID:      uid,
+			Email:    email,
+			Password: hashedValidPW,
+		}
+
+		mockUserRepository.
+			On("FindByEmail", mock.AnythingOfType("*context.emptyCtx"), email).
+			Return(mockUserResp, nil)
+		mockUserRepository.
+			On("RecordFailedSignin", mock.AnythingOfType("*context.emptyCtx"), uid, 5, lockoutSecs).
+			Return(&model.User{UID: uid, Email: email, FailedSigninCount: 1}, nil)
+
+		err := us.Signin(context.TODO(), &model.User{Email: email, Password: "wrongpassword"})
+
+		assert.EqualError(t, err, "用户名或密码错误")
+		mockUserRepository.AssertExpectations(t)
+		mockOneTimeTokenRepository.AssertNotCalled(t, "SetOneTimeToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockMailer.AssertNotCalled(t, "SendAccountUnlock", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("达到次数后锁定并发送解除锁定邮件", func(t *testing.T) {
+		us, mockUserRepository, mockOneTimeTokenRepository, mockMailer := newService()
+
+		uid, _ := uuid.NewRandom()
+		mockUserResp := &model.User{
+			UID:               uid,
+			Email:             email,
+			Password:          hashedValidPW,
+			FailedSigninCount: 4,
+		}
+		lockedUntil := time.Now().Add(time.Minute)
+		lockedUser := &model.User{
+			UID:          uid,
+			Email:        email,
+			LockoutCount: 1,
+			LockedUntil:  &lockedUntil,
+		}
+
+		var tokenHash, link string
+
+		mockUserRepository.
+			On("FindByEmail", mock.AnythingOfType("*context.emptyCtx"), email).
+			Return(mockUserResp, nil)
+		mockUserRepository.
+			On("RecordFailedSignin", mock.AnythingOfType("*context.emptyCtx"), uid, 5, lockoutSecs).
+			Return(lockedUser, nil)
+		mockOneTimeTokenRepository.
+			On("SetOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "unlock_account", mock.AnythingOfType("string"), uid.String(), time.Hour).
+			Run(func(args mock.Arguments) {
+				tokenHash = args.Get(2).(string)
+			}).
+			Return(nil)
+		mockMailer.
+			On("SendAccountUnlock", mock.AnythingOfType("*context.emptyCtx"), lockedUser, mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) {
+				link = args.Get(2).(string)
+			}).
+			Return(nil)
+
+		err := us.Signin(context.TODO(), &model.User{Email: email, Password: "wrongpassword"})
+		assert.EqualError(t, err, "用户名或密码错误")
+
+		parsed, _ := url.Parse(link)
+		token := parsed.Query().Get("token")
+		assert.Equal(t, hashOneTimeToken(token), tokenHash)
+
+		// 使用邮件中的链接解除锁定
+		mockOneTimeTokenRepository.
+			On("ConsumeOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "unlock_account", tokenHash).
+			Return(uid.String(), nil)
+		mockUserRepository.
+			On("ResetSigninFailures", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(nil)
+
+		err = us.UnlockAccount(context.TODO(), token)
+		assert.NoError(t, err)
+
+		mockUserRepository.AssertExpectations(t)
+		mockMailer.AssertExpectations(t)
+	})
+
+	t.Run("锁定期间密码正确也返回相同的错误", func(t *testing.T) {
+		us, mockUserRepository, _, _ := newService()
+
+		uid, _ := uuid.NewRandom()
+		lockedUntil := time.Now().Add(time.Minute)
+		mockUserResp := &model.User{
+			UID:          uid,
+			Email:        email,
+			Password:     hashedValidPW,
+			LockoutCount: 1,
+			LockedUntil:  &lockedUntil,
+		}
+
+		mockUserRepository.
+			On("FindByEmail", mock.AnythingOfType("*context.emptyCtx"), email).
+			Return(mockUserResp, nil)
+
+		err := us.Signin(context.TODO(), &model.User{Email: email, Password: validPW})
+		assert.EqualError(t, err, "用户名或密码错误")
+
+		err = us.Signin(context.TODO(), &model.User{Email: email, Password: "wrongpassword"})
+		assert.EqualError(t, err, "用户名或密码错误")
+
+		// 锁定期间不再累加错误次数
+		mockUserRepository.AssertNotCalled(t, "RecordFailedSignin", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("锁定到期后登录成功并清除次数", func(t *testing.T) {
+		us, mockUserRepository, _, _ := newService()
+
+		uid, _ := uuid.NewRandom()
+		lockedUntil := time.Now().Add(-time.Second)
+		mockUserResp := &model.User{
+			UID:               uid,
+			Email:             email,
+			Password:          hashedValidPW,
+			FailedSigninCount: 2,
+			LockoutCount:      1,
+			LockedUntil:       &lockedUntil,
+		}
+
+		mockUserRepository.
+			On("FindByEmail", mock.AnythingOfType("*context.emptyCtx"), email).
+			Return(mockUserResp, nil)
+		mockUserRepository.
+			On("ResetSigninFailures", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(nil)
+
+		u := &model.User{Email: email, Password: validPW}
+		err := us.Signin(context.TODO(), u)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uid, u.UID)
+		assert.Equal(t, 0, u.FailedSigninCount)
+		assert.Nil(t, u.LockedUntil)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("解除锁定令牌无效", func(t *testing.T) {
+		us, mockUserRepository, mockOneTimeTokenRepository, _ := newService()
+
+		mockErr := apperrors.NewAuthorization("令牌无效、已过期或已被使用")
+		mockOneTimeTokenRepository.
+			On("ConsumeOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "unlock_account", hashOneTimeToken("aToken")).
+			Return("", mockErr)
+
+		err := us.UnlockAccount(context.TODO(), "aToken")
+
+		assert.Equal(t, mockErr, err)
+		mockUserRepository.AssertNotCalled(t, "ResetSigninFailures", mock.Anything, mock.Anything)
+	})
+
+	t.Run("管理员解除锁定", func(t *testing.T) {
+		us, mockUserRepository, _, _ := newService()
+
+		uid, _ := uuid.NewRandom()
+		mockUserRepository.
+			On("ResetSigninFailures", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(nil)
+
+		err := us.ClearLockout(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+	})
+}
+
+func TestUpdateDetails(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	currentUser := &model.User{
+		UID:           uid,
+		Email:         "bob@bob.com",
+		EmailVerified: true,
+	}
+
+	newService := func() (model.UserService, *mocks.MockUserRepository, *mocks.MockOneTimeTokenRepository, *mocks.MockMailer) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockOneTimeTokenRepository := new(mocks.MockOneTimeTokenRepository)
+		mockMailer := new(mocks.MockMailer)
+
+		us := NewUserService(&USConfig{
+			UserRepository:             mockUserRepository,
+			OneTimeTokenRepository:     mockOneTimeTokenRepository,
+			Mailer:                     mockMailer,
+			VerificationSecret:         "averysecretsecret",
+			VerificationExpirationSecs: 24 * 60 * 60,
+			VerificationURL:            "http://malcorp.test/verify-email",
+		})
+
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(currentUser, nil)
+
+		return us, mockUserRepository, mockOneTimeTokenRepository, mockMailer
+	}
+
+	t.Run("成功", func(t *testing.T) {
+		us, mockUserRepository, mockOneTimeTokenRepository, mockMailer := newService()
+
+		mockUser := &model.User{
+			UID:     uid,
+			Email:   "bob@bob.com",
+			Website: "https://jacobgoodwin.me",
+			Name:    "A New Bob!",
+		}
+
+		mockArgs := mock.Arguments{
+			mock.AnythingOfType("*context.emptyCtx"),
+			mockUser,
+		}
+
+		mockUserRepository.
+			On("Update", mockArgs...).
+			Run(func(args mock.Arguments) {
+				args.Get(1).(*model.User).EmailVerified = true
+			}).
+			Return(nil)
+
+		ctx := context.TODO()
+		err := us.UpdateDetails(ctx, mockUser)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertCalled(t, "Update", mockArgs...)
+		// 邮箱未修改时不发送验证邮件
+		mockOneTimeTokenRepository.AssertNotCalled(t, "SetOneTimeToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockMailer.AssertNotCalled(t, "SendEmailVerification", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("修改邮箱后发送验证邮件", func(t *testing.T) {
+		us, mockUserRepository, mockOneTimeTokenRepository, mockMailer := newService()
+
+		mockUser := &model.User{
+			UID:   uid,
+			Email: "new@bob.com",
+		}
+
+		mockUserRepository.
+			On("Update", mock.AnythingOfType("*context.emptyCtx"), mockUser).
+			Return(nil)
+		mockOneTimeTokenRepository.
+			On("SetOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "verify_email", mock.AnythingOfType("string"), uid.String(), 24*time.Hour).
+			Return(nil)
+		mockMailer.
+			On("SendEmailVerification", mock.AnythingOfType("*context.emptyCtx"), mockUser, mock.AnythingOfType("string")).
+			Return(nil)
+
+		err := us.UpdateDetails(context.TODO(), mockUser)
+
+		assert.NoError(t, err)
+		mockOneTimeTokenRepository.AssertExpectations(t)
+		mockMailer.AssertExpectations(t)
+	})
+
+	t.Run("验证邮件发送失败时仍然更新成功", func(t *testing.T) {
+		us, mockUserRepository, mockOneTimeTokenRepository, mockMailer := newService()
+
+		mockUser := &model.User{
+			UID:   uid,
+			Email: "new@bob.com",
+		}
+
+		mockUserRepository.
+			On("Update", mock.AnythingOfType("*context.emptyCtx"), mockUser).
+			Return(nil)
+		mockOneTimeTokenRepository.
+			On("SetOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "verify_email", mock.AnythingOfType("string"), uid.String(), 24*time.Hour).
+			Return(nil)
+		mockMailer.
+			On("SendEmailVerification", mock.AnythingOfType("*context.emptyCtx"), mockUser, mock.AnythingOfType("string")).
+			Return(fmt.Errorf("smtp unavailable"))
+
+		err := us.UpdateDetails(context.TODO(), mockUser)
+
+		assert.NoError(t, err)
+		mockMailer.AssertExpectations(t)
+	})
+
+	t.Run("失败", func(t *testing.T) {
+		us, mockUserRepository, _, mockMailer := newService()
+
+		mockUser := &model.User{
+			UID:   uid,
+			Email: "new@bob.com",
+		}
+
+		mockArgs := mock.Arguments{
+			mock.AnythingOfType("*context.emptyCtx"),
+			mockUser,
+		}
+
+		mockError := apperrors.NewInternal()
+
+		mockUserRepository.
+			On("Update", mockArgs...).Return(mockError)
+
+		ctx := context.TODO()
+		err := us.UpdateDetails(ctx, mockUser)
+		assert.Error(t, err)
+
+		apperror, ok := err.(*apperrors.Error)
+		assert.True(t, ok)
+		assert.Equal(t, apperrors.Internal, apperror.Type)
+
+		mockUserRepository.AssertCalled(t, "Update", mockArgs...)
+		mockMailer.AssertNotCalled(t, "SendEmailVerification", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+// imageFileHeader 通过解析 multipart 请求得到 *multipart.FileHeader
+func imageFileHeader(t *testing.T, content []byte) *multipart.FileHeader {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	part, _ := writer.CreateFormFile("imageFile", "avatar")
+	part.Write(content)
+	writer.Close()
+
+	request, _ := http.NewRequest(http.MethodPost, "/image", body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+
+	_, fileHeader, err := request.FormFile("imageFile")
+	assert.NoError(t, err)
+
+	return fileHeader
+}
+
+func TestSetProfileImage(t *testing.T) {
+	pngContent := encodeTestPNG(t, 40, 20)
+
+	t.Run("成功并删除旧头像", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		mockUser := &model.User{
+			UID:      uid,
+			ImageURL: "http://malcorp.test/images/old_256.jpg",
+		}
+
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(mockUser, nil)
+
+		// 每个尺寸上传一次
+		var objNames []string
+		for _, size := range []int{64, 256, 512} {
+			suffix := fmt.Sprintf("_%d.jpg", size)
+
+			mockImageRepository.
+				On("UpdateProfile", mock.AnythingOfType("*context.emptyCtx"), mock.MatchedBy(func(objName string) bool {
+					return strings.HasSuffix(objName, suffix)
+				}), mock.Anything, mock.AnythingOfType("int64"), "image/jpeg").
+				Run(func(args mock.Arguments) {
+					objNames = append(objNames, args.Get(1).(string))
+				}).
+				Return("http://malcorp.test/images/new"+suffix, nil)
+		}
+
+		updatedUser := &model.User{
+			UID:      uid,
+			ImageURL: "http://malcorp.test/images/new_256.jpg",
+		}
+
+		mockUserRepository.
+			On("UpdateImage", mock.AnythingOfType("*context.emptyCtx"), uid, updatedUser.ImageURL).
+			Return(updatedUser, nil)
+
+		mockImageRepository.
+			On("DeleteProfile", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("string")).
+			Return(nil)
+
+		ctx := context.TODO()
+		u, err := us.SetProfileImage(ctx, uid, imageFileHeader(t, pngContent))
+
+		assert.NoError(t, err)
+		assert.Equal(t, updatedUser, u)
+
+		// 所有尺寸使用相同的 {id}
+		assert.Len(t, objNames, 3)
+		objID := strings.TrimSuffix(objNames[0], "_512.jpg")
+		assert.Len(t, objID, 36)
+		assert.ElementsMatch(t, []string{objID + "_64.jpg", objID + "_256.jpg", objID + "_512.jpg"}, objNames)
+
+		for _, size := range []int{64, 256, 512} {
+			oldObjName := fmt.Sprintf("old_%d.jpg", size)
+			mockImageRepository.AssertCalled(t, "DeleteProfile", mock.AnythingOfType("*context.emptyCtx"), oldObjName)
+		}
+		mockImageRepository.AssertNumberOfCalls(t, "DeleteProfile", 3)
+	})
+
+	t.Run("不支持的图片类型", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(&model.User{UID: uid}, nil)
+
+		ctx := context.TODO()
+		u, err := us.SetProfileImage(ctx, uid, imageFileHeader(t, []byte("<html><body></body></html>")))
+
+		assert.Nil(t, u)
+		assert.Equal(t, apperrors.UnsupportedMediaType, err.(*apperrors.Error).Type)
+		mockImageRepository.AssertNotCalled(t, "UpdateProfile", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("图片已损坏", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(&model.User{UID: uid}, nil)
+
+		ctx := context.TODO()
+		u, err := us.SetProfileImage(ctx, uid, imageFileHeader(t, pngContent[:len(pngContent)/2]))
+
+		assert.Nil(t, u)
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockImageRepository.AssertNotCalled(t, "UpdateProfile", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("更新 image_url 失败时删除新上传的图片", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		mockError := apperrors.NewInternal()
+
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(&model.User{UID: uid}, nil)
+
+		mockImageRepository.
+			On("UpdateProfile", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("int64"), "image/jpeg").
+			Return("http://malcorp.test/images/new_256.jpg", nil)
+
+		mockUserRepository.
+			On("UpdateImage", mock.AnythingOfType("*context.emptyCtx"), uid, "http://malcorp.test/images/new_256.jpg").
+			Return(nil, mockError)
+
+		mockImageRepository.
+			On("DeleteProfile", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("string")).
+			Return(nil)
+
+		ctx := context.TODO()
+		u, err := us.SetProfileImage(ctx, uid, imageFileHeader(t, pngContent))
+
+		assert.Nil(t, u)
+		assert.Equal(t, mockError, err)
+		mockImageRepository.AssertNumberOfCalls(t, "UpdateProfile", 3)
+		for _, size := range []int{64, 256, 512} {
+			newObjName := fmt.Sprintf("new_%d.jpg", size)
+			mockImageRepository.AssertCalled(t, "DeleteProfile", mock.AnythingOfType("*context.emptyCtx"), newObjName)
+		}
+	})
+}
+
+func TestClearProfileImage(t *testing.T) {
+	t.Run("成功", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		// 处理图片之前上传的头像只有一个对象
+		mockUser := &model.User{
+			UID:      uid,
+			ImageURL: "http://malcorp.test/images/old.png",
+		}
+		updatedUser := &model.User{
+			UID: uid,
+		}
+
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(mockUser, nil)
+		mockUserRepository.
+			On("UpdateImage", mock.AnythingOfType("*context.emptyCtx"), uid, "").
+			Return(updatedUser, nil)
+		mockImageRepository.
+			On("DeleteProfile", mock.AnythingOfType("*context.emptyCtx"), "old.png").
+			Return(nil)
+
+		ctx := context.TODO()
+		u, err := us.ClearProfileImage(ctx, uid)
+
+		assert.NoError(t, err)
+		assert.Equal(t, updatedUser, u)
+		mockImageRepository.AssertCalled(t, "DeleteProfile", mock.AnythingOfType("*context.emptyCtx"), "old.png")
+	})
+
+	t.Run("用户没有头像", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockImageRepository := new(mocks.MockImageRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			ImageRepository: mockImageRepository,
+		})
+
+		mockUser := &model.User{
+			UID: uid,
+		}
+
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(mockUser, nil)
+
+		ctx := context.TODO()
+		u, err := us.ClearProfileImage(ctx, uid)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockUser, u)
+		mockUserRepository.AssertNotCalled(t, "UpdateImage", mock.Anything, mock.Anything, mock.Anything)
+		mockImageRepository.AssertNotCalled(t, "DeleteProfile", mock.Anything, mock.Anything)
+	})
+}
+
+func TestEmailVerification(t *testing.T) {
+	secret := "averysecretsecret"
+	uid, _ := uuid.NewRandom()
+	mockUser := &model.User{
+		UID:   uid,
+		Email: "bob@bob.com",
+	}
+
+	newService := func() (model.UserService, *mocks.MockUserRepository, *mocks.MockOneTimeTokenRepository, *mocks.MockMailer) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockOneTimeTokenRepository := new(mocks.MockOneTimeTokenRepository)
+		mockMailer := new(mocks.MockMailer)
+
+		us := NewUserService(&USConfig{
+			UserRepository:             mockUserRepository,
+			OneTimeTokenRepository:     mockOneTimeTokenRepository,
+			Mailer:                     mockMailer,
+			VerificationSecret:         secret,
+			VerificationExpirationSecs: 24 * 60 * 60,
+			VerificationURL:            "http://malcorp.test/verify-email?lang=zh",
+		})
+
+		return us, mockUserRepository, mockOneTimeTokenRepository, mockMailer
+	}
+
+	// sendAndCapture 发送验证邮件，返回邮件中链接所带的令牌及其 ID
+	sendAndCapture := func(t *testing.T, us model.UserService, mockOneTimeTokenRepository *mocks.MockOneTimeTokenRepository, mockMailer *mocks.MockMailer) (string, string) {
+		var tokenID, link string
+
+		mockOneTimeTokenRepository.
+			On("SetOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "verify_email", mock.AnythingOfType("string"), uid.String(), 24*time.Hour).
+			Run(func(args mock.Arguments) {
+				tokenID = args.Get(2).(string)
+			}).
+			Return(nil)
+		mockMailer.
+			On("SendEmailVerification", mock.AnythingOfType("*context.emptyCtx"), mockUser, mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) {
+				link = args.Get(2).(string)
+			}).
+			Return(nil)
+
+		err := us.SendEmailVerification(context.TODO(), mockUser)
+		assert.NoError(t, err)
+
+		parsed, err := url.Parse(link)
+		assert.NoError(t, err)
+		assert.Equal(t, "zh", parsed.Query().Get("lang"))
+
+		return parsed.Query().Get("token"), tokenID
+	}
+
+	t.Run("发送验证邮件并验证成功", func(t *testing.T) {
+		us, mockUserRepository, mockOneTimeTokenRepository, mockMailer := newService()
+		token, tokenID := sendAndCapture(t, us, mockOneTimeTokenRepository, mockMailer)
+
+		verifiedUser := &model.User{
+			UID:           uid,
+			Email:         mockUser.Email,
+			EmailVerified: true,
+		}
+
+		mockOneTimeTokenRepository.
+			On("ConsumeOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "verify_email", tokenID).
+			Return(uid.String(), nil)
+		mockUserRepository.
+			On("SetEmailVerified", mock.AnythingOfType("*context.emptyCtx"), uid, mockUser.Email).
+			Return(verifiedUser, nil)
+
+		u, err := us.VerifyEmail(context.TODO(), token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, verifiedUser, u)
+	})
+
+	t.Run("邮箱已验证", func(t *testing.T) {
+		us, _, mockOneTimeTokenRepository, _ := newService()
+
+		err := us.SendEmailVerification(context.TODO(), &model.User{UID: uid, EmailVerified: true})
+
+		assert.Equal(t, apperrors.Conflict, err.(*apperrors.Error).Type)
+		mockOneTimeTokenRepository.AssertNotCalled(t, "SetOneTimeToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("令牌已被使用", func(t *testing.T) {
+		us, mockUserRepository, mockOneTimeTokenRepository, mockMailer := newService()
+		token, tokenID := sendAndCapture(t, us, mockOneTimeTokenRepository, mockMailer)
+
+		mockError := apperrors.NewAuthorization("令牌无效、已过期或已被使用")
+		mockOneTimeTokenRepository.
+			On("ConsumeOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "verify_email", tokenID).
+			Return("", mockError)
+
+		u, err := us.VerifyEmail(context.TODO(), token)
+
+		assert.Nil(t, u)
+		assert.Equal(t, mockError, err)
+		mockUserRepository.AssertNotCalled(t, "SetEmailVerified", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("签发后邮箱已变更", func(t *testing.T) {
+		us, mockUserRepository, mockOneTimeTokenRepository, mockMailer := newService()
+		token, tokenID := sendAndCapture(t, us, mockOneTimeTokenRepository, mockMailer)
+
+		mockOneTimeTokenRepository.
+			On("ConsumeOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "verify_email", tokenID).
+			Return(uid.String(), nil)
+		mockUserRepository.
+			On("SetEmailVerified", mock.AnythingOfType("*context.emptyCtx"), uid, mockUser.Email).
+			Return(nil, apperrors.NewNotFound("email", mockUser.Email))
+
+		u, err := us.VerifyEmail(context.TODO(), token)
+
+		assert.Nil(t, u)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+	})
+
+	t.Run("签名密钥不正确", func(t *testing.T) {
+		us, _, mockOneTimeTokenRepository, _ := newService()
+
+		token, _ := generateEmailVerificationToken(mockUser, "notthesecret", 60)
+
+		u, err := us.VerifyEmail(context.TODO(), token.SS)
+
+		assert.Nil(t, u)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockOneTimeTokenRepository.AssertNotCalled(t, "ConsumeOneTimeToken", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("不能使用刷新令牌", func(t *testing.T) {
+		us, _, mockOneTimeTokenRepository, _ := newService()
+
+		refreshToken, _ := generateRefreshToken(uid, secret, 60)
+
+		u, err := us.VerifyEmail(context.TODO(), refreshToken.SS)
+
+		assert.Nil(t, u)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockOneTimeTokenRepository.AssertNotCalled(t, "ConsumeOneTimeToken", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestPasswordReset(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	mockUser := &model.User{
+		UID:   uid,
+		Email: "bob@bob.com",
+	}
+
+	newService := func() (model.UserService, *mocks.MockUserRepository, *mocks.MockOneTimeTokenRepository, *mocks.MockMailer) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockOneTimeTokenRepository := new(mocks.MockOneTimeTokenRepository)
+		mockMailer := new(mocks.MockMailer)
+
+		us := NewUserService(&USConfig{
+			UserRepository:         mockUserRepository,
+			OneTimeTokenRepository: mockOneTimeTokenRepository,
+			Mailer:                 mockMailer,
+			ResetExpirationSecs:    15 * 60,
+			ResetURL:               "http://malcorp.test/reset-password",
+		})
+
+		return us, mockUserRepository, mockOneTimeTokenRepository, mockMailer
+	}
+
+	t.Run("发送重置密码邮件并重置成功", func(t *testing.T) {
+		us, mockUserRepository, mockOneTimeTokenRepository, mockMailer := newService()
+
+		var tokenHash, link string
+
+		mockUserRepository.
+			On("FindByEmail", mock.AnythingOfType("*context.emptyCtx"), mockUser.Email).
+			Return(mockUser, nil)
+		mockOneTimeTokenRepository.
+			On("SetOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "reset_password", mock.AnythingOfType("string"), uid.String(), 15*time.Minute).
+			Run(func(args mock.Arguments) {
+				tokenHash = args.Get(2).(string)
+			}).
+			Return(nil)
+		mockMailer.
+			On("SendPasswordReset", mock.AnythingOfType("*context.emptyCtx"), mockUser, mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) {
+				link = args.Get(2).(string)
+			}).
+			Return(nil)
+
+		err := us.RequestPasswordReset(context.TODO(), mockUser.Email)
+		assert.NoError(t, err)
+
+		parsed, _ := url.Parse(link)
+		token := parsed.Query().Get("token")
+
+		// 只保存令牌的摘要
+		assert.NotEmpty(t, token)
+		assert.NotEqual(t, token, tokenHash)
+		assert.Equal(t, hashOneTimeToken(token), tokenHash)
+
+		// 查询令牌所属的用户不会使用令牌
+		mockOneTimeTokenRepository.
+			On("GetOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "reset_password", tokenHash).
+			Return(uid.String(), nil)
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(mockUser, nil)
+
+		u, err := us.FindByPasswordResetToken(context.TODO(), token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockUser, u)
+		mockOneTimeTokenRepository.AssertNotCalled(t, "ConsumeOneTimeToken", mock.Anything, mock.Anything, mock.Anything)
+
+		newPassword := "anewpassword"
+
+		mockOneTimeTokenRepository.
+			On("ConsumeOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "reset_password", tokenHash).
+			Return(uid.String(), nil)
+		mockUserRepository.
+			On("UpdatePassword", mock.AnythingOfType("*context.emptyCtx"), uid, mock.MatchedBy(func(pw string) bool {
+				match, err := comparePasswords(pw, newPassword)
+				return err == nil && match
+			})).
+			Return(nil)
+		// 重置密码同时解除锁定
+		mockUserRepository.
+			On("ResetSigninFailures", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(nil)
+
+		resetUID, err := us.ResetPassword(context.TODO(), token, newPassword)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uid, resetUID)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("邮箱未注册", func(t *testing.T) {
+		us, mockUserRepository, mockOneTimeTokenRepository, mockMailer := newService()
+
+		mockUserRepository.
+			On("FindByEmail", mock.AnythingOfType("*context.emptyCtx"), "nobody@bob.com").
+			Return(nil, apperrors.NewNotFound("email", "nobody@bob.com"))
+
+		err := us.RequestPasswordReset(context.TODO(), "nobody@bob.com")
+
+		assert.NoError(t, err)
+		mockOneTimeTokenRepository.AssertNotCalled(t, "SetOneTimeToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockMailer.AssertNotCalled(t, "SendPasswordReset", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("令牌无效或已被使用", func(t *testing.T) {
+		us, mockUserRepository, mockOneTimeTokenRepository, _ := newService()
+
+		mockError := apperrors.NewAuthorization("令牌无效、已过期或已被使用")
+		mockOneTimeTokenRepository.
+			On("GetOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "reset_password", hashOneTimeToken("aUsedToken")).
+			Return("", mockError)
+		mockOneTimeTokenRepository.
+			On("ConsumeOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "reset_password", hashOneTimeToken("aUsedToken")).
+			Return("", mockError)
+
+		u, err := us.FindByPasswordResetToken(context.TODO(), "aUsedToken")
+
+		assert.Nil(t, u)
+		assert.Equal(t, mockError, err)
+		mockUserRepository.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+
+		resetUID, err := us.ResetPassword(context.TODO(), "aUsedToken", "anewpassword")
+
+		assert.Equal(t, uuid.Nil, resetUID)
+		assert.Equal(t, mockError, err)
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+		mockUserRepository.AssertNotCalled(t, "ResetSigninFailures", mock.Anything, mock.Anything)
+	})
+}
+
+func TestChangePassword(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	currentPassword := "currentpassword"
+	hashedPassword, _ := hashPassword(currentPassword)
+
+	mockUser := &model.User{
+		UID:      uid,
+		Email:    "bob@bob.com",
+		Password: hashedPassword,
+	}
+
+	t.Run("成功", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		newPassword := "anewpassword"
+
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(mockUser, nil)
+		mockUserRepository.
+			On("UpdatePassword", mock.AnythingOfType("*context.emptyCtx"), uid, mock.MatchedBy(func(pw string) bool {
+				match, err := comparePasswords(pw, newPassword)
+				return err == nil && match
+			})).
+			Return(nil)
+
+		err := us.ChangePassword(context.TODO(), uid, currentPassword, newPassword)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("当前密码错误时累加错误次数", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:   mockUserRepository,
+			LockoutThreshold: 5,
+			LockoutDurations: []time.Duration{time.Minute, 5 * time.Minute},
+		})
+
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(mockUser, nil)
+		mockUserRepository.
+			On("RecordFailedSignin", mock.AnythingOfType("*context.emptyCtx"), uid, 5, []int64{60, 300}).
+			Return(&model.User{UID: uid, Email: mockUser.Email, FailedSigninCount: 1}, nil)
+
+		err := us.ChangePassword(context.TODO(), uid, "wrongpassword", "anewpassword")
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertExpectations(t)
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("账号锁定期间不校验当前密码", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:   mockUserRepository,
+			LockoutThreshold: 5,
+			LockoutDurations: []time.Duration{time.Minute, 5 * time.Minute},
+		})
+
+		lockedUntil := time.Now().Add(time.Minute)
+		lockedUser := &model.User{
+			UID:          uid,
+			Email:        "bob@bob.com",
+			Password:     hashedPassword,
+			LockoutCount: 1,
+			LockedUntil:  &lockedUntil,
+		}
+
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(lockedUser, nil)
+
+		err := us.ChangePassword(context.TODO(), uid, currentPassword, "anewpassword")
+		assert.EqualError(t, err, "当前密码错误")
+
+		err = us.ChangePassword(context.TODO(), uid, "wrongpassword", "anewpassword")
+		assert.EqualError(t, err, "当前密码错误")
+
+		mockUserRepository.AssertNotCalled(t, "RecordFailedSignin", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("新密码与当前密码相同", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(mockUser, nil)
+
+		err := us.ChangePassword(context.TODO(), uid, currentPassword, currentPassword)
+
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestTOTP(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	newService := func() (model.UserService, *mocks.MockUserRepository, *mocks.MockOneTimeTokenRepository, *mocks.MockRecoveryCodeRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockOneTimeTokenRepository := new(mocks.MockOneTimeTokenRepository)
+		mockRecoveryCodeRepository := new(mocks.MockRecoveryCodeRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository:             mockUserRepository,
+			OneTimeTokenRepository:     mockOneTimeTokenRepository,
+			RecoveryCodeRepository:     mockRecoveryCodeRepository,
+			TOTPIssuer:                 "Memrizr",
+			MFAChallengeExpirationSecs: 5 * 60,
+		})
+
+		return us, mockUserRepository, mockOneTimeTokenRepository, mockRecoveryCodeRepository
+	}
+
+	currentCode := func(secret string) (string, int64) {
+		step := time.Now().Unix() / totpPeriod
+		code, _ := totpCode(secret, step)
+		return code, step
+	}
+
+	t.Run("绑定 TOTP", func(t *testing.T) {
+		us, mockUserRepository, _, _ := newService()
+
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(&model.User{UID: uid, Email: "bob@bob.com"}, nil)
+		mockUserRepository.
+			On("UpdateTOTP", mock.AnythingOfType("*context.emptyCtx"), uid, mock.AnythingOfType("string"), false).
+			Return(&model.User{UID: uid}, nil)
+
+		enrollment, err := us.EnrollTOTP(context.TODO(), uid)
+		assert.NoError(t, err)
+
+		mockUserRepository.AssertCalled(t, "UpdateTOTP", mock.AnythingOfType("*context.emptyCtx"), uid, enrollment.Secret, false)
+		assert.True(t, strings.HasPrefix(enrollment.URI, "otpauth://totp/Memrizr:bob@bob.com?"))
+	})
+
+	t.Run("已启用时不能重新绑定", func(t *testing.T) {
+		us, mockUserRepository, _, _ := newService()
+
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(&model.User{UID: uid, TOTPSecret: rfcTOTPSecret, TOTPEnabled: true}, nil)
+
+		_, err := us.EnrollTOTP(context.TODO(), uid)
+
+		assert.Equal(t, apperrors.Conflict, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "UpdateTOTP", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("确认绑定", func(t *testing.T) {
+		us, mockUserRepository, _, mockRecoveryCodeRepository := newService()
+
+		code, step := currentCode(rfcTOTPSecret)
+		enabledUser := &model.User{UID: uid, TOTPSecret: rfcTOTPSecret, TOTPEnabled: true}
+
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(&model.User{UID: uid, TOTPSecret: rfcTOTPSecret}, nil)
+		mockUserRepository.
+			On("UseTOTPStep", mock.AnythingOfType("*context.emptyCtx"), uid, step).
+			Return(nil)
+		mockUserRepository.
+			On("UpdateTOTP", mock.AnythingOfType("*context.emptyCtx"), uid, rfcTOTPSecret, true).
+			Return(enabledUser, nil)
+
+		var codeHashes []string
+		mockRecoveryCodeRepository.
+			On("ReplaceRecoveryCodes", mock.AnythingOfType("*context.emptyCtx"), uid, mock.AnythingOfType("[]string")).
+			Run(func(args mock.Arguments) {
+				codeHashes = args.Get(2).([]string)
+			}).
+			Return(nil)
+
+		u, recoveryCodes, err := us.ConfirmTOTP(context.TODO(), uid, code)
+
+		assert.NoError(t, err)
+		assert.Equal(t, enabledUser, u)
+		assert.Len(t, recoveryCodes, recoveryCodeCount)
+		assert.Len(t, codeHashes, recoveryCodeCount)
+
+		// 只保存摘要
+		assert.Equal(t, hashRecoveryCode("", uid, recoveryCodes[0]), codeHashes[0])
+		assert.NotContains(t, codeHashes, recoveryCodes[0])
+
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("验证码错误", func(t *testing.T) {
+		us, mockUserRepository, _, _ := newService()
+
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(&model.User{UID: uid, TOTPSecret: rfcTOTPSecret}, nil)
+
+		_, _, err := us.ConfirmTOTP(context.TODO(), uid, "abcdef")
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "UpdateTOTP", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("验证码已被使用", func(t *testing.T) {
+		us, mockUserRepository, _, _ := newService()
+
+		code, step := currentCode(rfcTOTPSecret)
+
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(&model.User{UID: uid, TOTPSecret: rfcTOTPSecret}, nil)
+		mockUserRepository.
+			On("UseTOTPStep", mock.AnythingOfType("*context.emptyCtx"), uid, step).
+			Return(apperrors.NewNotFound("totp step", fmt.Sprint(step)))
+
+		_, _, err := us.ConfirmTOTP(context.TODO(), uid, code)
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "UpdateTOTP", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("停用 TOTP", func(t *testing.T) {
+		us, mockUserRepository, _, mockRecoveryCodeRepository := newService()
+
+		code, step := currentCode(rfcTOTPSecret)
+
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(&model.User{UID: uid, TOTPSecret: rfcTOTPSecret, TOTPEnabled: true}, nil)
+		mockUserRepository.
+			On("UseTOTPStep", mock.AnythingOfType("*context.emptyCtx"), uid, step).
+			Return(nil)
+		mockUserRepository.
+			On("UpdateTOTP", mock.AnythingOfType("*context.emptyCtx"), uid, "", false).
+			Return(&model.User{UID: uid}, nil)
+		mockRecoveryCodeRepository.
+			On("DeleteRecoveryCodes", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(nil)
+
+		u, err := us.DisableTOTP(context.TODO(), uid, code)
+
+		assert.NoError(t, err)
+		assert.False(t, u.TOTPEnabled)
+		mockUserRepository.AssertExpectations(t)
+		mockRecoveryCodeRepository.AssertExpectations(t)
+	})
+
+	t.Run("MFA 令牌与验证码", func(t *testing.T) {
+		us, mockUserRepository, mockOneTimeTokenRepository, _ := newService()
+
+		code, step := currentCode(rfcTOTPSecret)
+		mockUser := &model.User{UID: uid, TOTPSecret: rfcTOTPSecret, TOTPEnabled: true}
+
+		var tokenHash string
+		mockOneTimeTokenRepository.
+			On("SetOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "mfa_challenge", mock.AnythingOfType("string"), uid.String(), 5*time.Minute).
+			Run(func(args mock.Arguments) {
+				tokenHash = args.Get(2).(string)
+			}).
+			Return(nil)
+
+		mfaToken, err := us.NewMFAChallenge(context.TODO(), mockUser)
+		assert.NoError(t, err)
+		assert.Equal(t, hashOneTimeToken(mfaToken), tokenHash)
+
+		mockOneTimeTokenRepository.
+			On("ConsumeOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "mfa_challenge", tokenHash).
+			Return(uid.String(), nil)
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(mockUser, nil)
+		mockUserRepository.
+			On("UseTOTPStep", mock.AnythingOfType("*context.emptyCtx"), uid, step).
+			Return(nil)
+
+		u, err := us.VerifyMFAChallenge(context.TODO(), mfaToken, code)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockUser, u)
+		mockOneTimeTokenRepository.AssertExpectations(t)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("MFA 令牌无效", func(t *testing.T) {
+		us, mockUserRepository, mockOneTimeTokenRepository, _ := newService()
+
+		mockOneTimeTokenRepository.
+			On("ConsumeOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "mfa_challenge", hashOneTimeToken("invalid")).
+			Return("", apperrors.NewAuthorization("令牌无效、已过期或已被使用"))
+
+		_, err := us.VerifyMFAChallenge(context.TODO(), "invalid", "123456")
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	})
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	mockUser := &model.User{UID: uid, TOTPSecret: rfcTOTPSecret, TOTPEnabled: true}
+	recoveryCodeSecret := "anotsorandomrecoverycodesecret"
+
+	newService := func() (model.UserService, *mocks.MockUserRepository, *mocks.MockOneTimeTokenRepository, *mocks.MockRecoveryCodeRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockOneTimeTokenRepository := new(mocks.MockOneTimeTokenRepository)
+		mockRecoveryCodeRepository := new(mocks.MockRecoveryCodeRepository)
+
+		us := NewUserService(&USConfig{
+			UserRepository:         mockUserRepository,
+			OneTimeTokenRepository: mockOneTimeTokenRepository,
+			RecoveryCodeRepository: mockRecoveryCodeRepository,
+			RecoveryCodeSecret:     recoveryCodeSecret,
+		})
+
+		mockUserRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(mockUser, nil)
+		mockOneTimeTokenRepository.
+			On("ConsumeOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "mfa_challenge", hashOneTimeToken("aMFAToken")).
+			Return(uid.String(), nil)
+
+		return us, mockUserRepository, mockOneTimeTokenRepository, mockRecoveryCodeRepository
+	}
+
+	// 有效的恢复码为 abcde-fgh23，其余的恢复码在数据库中找不到对应的摘要
+	codeHash := hashRecoveryCode(recoveryCodeSecret, uid, "abcdefgh23")
+	useOtherCodeArgs := mock.Arguments{
+		mock.AnythingOfType("*context.emptyCtx"),
+		uid,
+		mock.MatchedBy(func(h string) bool { return h != codeHash }),
+	}
+
+	t.Run("使用恢复码登录", func(t *testing.T) {
+		us, mockUserRepository, _, mockRecoveryCodeRepository := newService()
+
+		mockRecoveryCodeRepository.
+			On("UseRecoveryCode", mock.AnythingOfType("*context.emptyCtx"), uid, codeHash).
+			Return(nil)
+
+		// 忽略大小写与连字符
+		u, err := us.VerifyMFAChallenge(context.TODO(), "aMFAToken", "ABCDE-FGH23")
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockUser, u)
+		mockRecoveryCodeRepository.AssertExpectations(t)
+		mockUserRepository.AssertNotCalled(t, "UseTOTPStep", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("恢复码错误", func(t *testing.T) {
+		us, _, _, mockRecoveryCodeRepository := newService()
+
+		mockRecoveryCodeRepository.
+			On("UseRecoveryCode", useOtherCodeArgs...).
+			Return(apperrors.NewNotFound("recovery code", uid.String()))
+
+		_, err := us.VerifyMFAChallenge(context.TODO(), "aMFAToken", "aaaaa-aaaaa")
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockRecoveryCodeRepository.AssertNotCalled(t, "UseRecoveryCode", mock.Anything, uid, codeHash)
+	})
+
+	t.Run("恢复码已被并发使用", func(t *testing.T) {
+		us, _, _, mockRecoveryCodeRepository := newService()
+
+		mockRecoveryCodeRepository.
+			On("UseRecoveryCode", mock.AnythingOfType("*context.emptyCtx"), uid, codeHash).
+			Return(apperrors.NewNotFound("recovery code", uid.String()))
+
+		_, err := us.VerifyMFAChallenge(context.TODO(), "aMFAToken", "abcde-fgh23")
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+	})
+
+	t.Run("重新生成恢复码", func(t *testing.T) {
+		us, mockUserRepository, _, mockRecoveryCodeRepository := newService()
+
+		step := time.Now().Unix() / totpPeriod
+		code, _ := totpCode(rfcTOTPSecret, step)
+
+		mockUserRepository.
+			On("UseTOTPStep", mock.AnythingOfType("*context.emptyCtx"), uid, step).
+			Return(nil)
+		var codeHashes []string
+		mockRecoveryCodeRepository.
+			On("ReplaceRecoveryCodes", mock.AnythingOfType("*context.emptyCtx"), uid, mock.AnythingOfType("[]string")).
+			Run(func(args mock.Arguments) {
+				codeHashes = args.Get(2).([]string)
+			}).
+			Return(nil)
+
+		recoveryCodes, err := us.RegenerateRecoveryCodes(context.TODO(), uid, code)
+
+		assert.NoError(t, err)
+		assert.Len(t, recoveryCodes, recoveryCodeCount)
+		for i, rc := range recoveryCodes {
+			assert.Regexp(t, "^[a-z2-7]{5}-[a-z2-7]{5}$", rc)
+			assert.True(t, isRecoveryCode(rc))
+			// 只保存以密钥计算的摘要
+			assert.Equal(t, hashRecoveryCode(recoveryCodeSecret, uid, rc), codeHashes[i])
+		}
+		mockRecoveryCodeRepository.AssertExpectations(t)
+	})
+
+	t.Run("使用恢复码重新生成恢复码", func(t *testing.T) {
+		us, mockUserRepository, _, mockRecoveryCodeRepository := newService()
+
+		mockRecoveryCodeRepository.
+			On("UseRecoveryCode", mock.AnythingOfType("*context.emptyCtx"), uid, codeHash).
+			Return(nil)
+		mockRecoveryCodeRepository.
+			On("ReplaceRecoveryCodes", mock.AnythingOfType("*context.emptyCtx"), uid, mock.AnythingOfType("[]string")).
+			Return(nil)
+
+		recoveryCodes, err := us.RegenerateRecoveryCodes(context.TODO(), uid, "abcde-fgh23")
+
+		assert.NoError(t, err)
+		assert.Len(t, recoveryCodes, recoveryCodeCount)
+		mockRecoveryCodeRepository.AssertExpectations(t)
+		mockUserRepository.AssertNotCalled(t, "UseTOTPStep", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("使用恢复码停用 TOTP", func(t *testing.T) {
+		us, mockUserRepository, _, mockRecoveryCodeRepository := newService()
+
+		mockRecoveryCodeRepository.
+			On("UseRecoveryCode", mock.AnythingOfType("*context.emptyCtx"), uid, codeHash).
+			Return(nil)
+		mockUserRepository.
+			On("UpdateTOTP", mock.AnythingOfType("*context.emptyCtx"), uid, "", false).
+			Return(&model.User{UID: uid}, nil)
+		mockRecoveryCodeRepository.
+			On("DeleteRecoveryCodes", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(nil)
+
+		u, err := us.DisableTOTP(context.TODO(), uid, "ABCDE-FGH23")
+
+		assert.NoError(t, err)
+		assert.False(t, u.TOTPEnabled)
+		mockRecoveryCodeRepository.AssertExpectations(t)
+		mockUserRepository.AssertNotCalled(t, "UseTOTPStep", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("恢复码错误时不停用 TOTP", func(t *testing.T) {
+		us, mockUserRepository, _, mockRecoveryCodeRepository := newService()
+
+		mockRecoveryCodeRepository.
+			On("UseRecoveryCode", useOtherCodeArgs...).
+			Return(apperrors.NewNotFound("recovery code", uid.String()))
+
+		_, err := us.DisableTOTP(context.TODO(), uid, "aaaaa-aaaaa")
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "UpdateTOTP", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockRecoveryCodeRepository.AssertNotCalled(t, "DeleteRecoveryCodes", mock.Anything, mock.Anything)
+	})
+
+	t.Run("剩余数量", func(t *testing.T) {
+		us, _, _, mockRecoveryCodeRepository := newService()
+
+		mockRecoveryCodeRepository.
+			On("CountUnusedRecoveryCodes", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return(7, nil)
+
+		remaining, err := us.CountRecoveryCodes(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 7, remaining)
+	})
 }
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// 支持的 COSE 签名算法，按优先顺序排列
+const (
+	coseAlgES256 = -7
+	coseAlgEdDSA = -8
+	coseAlgRS256 = -257
+)
+
+var webAuthnAlgs = []int64{coseAlgES256, coseAlgEdDSA, coseAlgRS256}
+
+// 验证器数据中的标志位
+const (
+	authDataFlagUP = 0x01 // 用户在场
+	authDataFlagUV = 0x04 // 用户已验证（PIN、生物识别等）
+	authDataFlagAT = 0x40 // 包含凭据数据
+)
+
+// webAuthnClientData 为 clientDataJSON 中需要校验的字段
+type webAuthnClientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// webAuthnAuthData 为解析后的验证器数据，CredentialID 与 PublicKey 仅在注册时存在
+type webAuthnAuthData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+type webAuthnAttestationObject struct {
+	Fmt      string          `cbor:"fmt"`
+	AttStmt  cbor.RawMessage `cbor:"attStmt"`
+	AuthData []byte          `cbor:"authData"`
+}
+
+// decodeBase64URL 解码 base64url，兼容带填充的输入
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+func encodeBase64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// parseWebAuthnClientData 解析 clientDataJSON 并校验其类型，返回解码后的原始数据用于计算签名
+func parseWebAuthnClientData(encoded string, typ string) (*webAuthnClientData, []byte, error) {
+	raw, err := decodeBase64URL(encoded)
+	if err != nil {
+		return nil, nil, fmt.Errorf("无法解码 clientDataJSON：%w", err)
+	}
+
+	cd := &webAuthnClientData{}
+	if err := json.Unmarshal(raw, cd); err != nil {
+		return nil, nil, fmt.Errorf("无法解析 clientDataJSON：%w", err)
+	}
+
+	if cd.Type != typ {
+		return nil, nil, fmt.Errorf("clientDataJSON 的类型为 %v，而非 %v", cd.Type, typ)
+	}
+
+	return cd, raw, nil
+}
+
+// parseWebAuthnAttestationObject 解码 attestationObject，请求中 attestation 为 none，不校验 attStmt
+func parseWebAuthnAttestationObject(encoded string) (*webAuthnAttestationObject, error) {
+	raw, err := decodeBase64URL(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("无法解码 attestationObject：%w", err)
+	}
+
+	attObj := &webAuthnAttestationObject{}
+	if err := cbor.Unmarshal(raw, attObj); err != nil {
+		return nil, fmt.Errorf("无法解析 attestationObject：%w", err)
+	}
+
+	return attObj, nil
+}
+
+// parseWebAuthnAuthData 按 WebAuthn §6.1 解析验证器数据
+func parseWebAuthnAuthData(data []byte) (*webAuthnAuthData, error) {
+	if len(data) < 37 {
+		return nil, fmt.Errorf("验证器数据长度 %v 不足", len(data))
+	}
+
+	ad := &webAuthnAuthData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+
+	if ad.Flags&authDataFlagAT == 0 {
+		return ad, nil
+	}
+
+	// AAGUID（16 字节）、凭据 ID 长度（2 字节）、凭据 ID、COSE 公钥，之后可能还有扩展数据
+	rest := data[37:]
+	if len(rest) < 18 {
+		return nil, fmt.Errorf("凭据数据长度不足")
+	}
+
+	idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < idLen {
+		return nil, fmt.Errorf("凭据 ID 长度不足")
+	}
+
+	ad.CredentialID = rest[:idLen]
+	rest = rest[idLen:]
+
+	dec := cbor.NewDecoder(bytes.NewReader(rest))
+	var key cbor.RawMessage
+	if err := dec.Decode(&key); err != nil {
+		return nil, fmt.Errorf("无法解析凭据公钥：%w", err)
+	}
+	ad.PublicKey = rest[:dec.NumBytesRead()]
+
+	return ad, nil
+}
+
+// parseCOSEKey 将 COSE_Key 转换为公钥，仅支持 ES256（P-256）、EdDSA（Ed25519）与 RS256
+func parseCOSEKey(data []byte) (crypto.PublicKey, int64, error) {
+	var m map[int]cbor.RawMessage
+	if err := cbor.Unmarshal(data, &m); err != nil {
+		return nil, 0, fmt.Errorf("无法解析 COSE 公钥：%w", err)
+	}
+
+	var kty, alg int64
+	if err := cbor.Unmarshal(m[1], &kty); err != nil {
+		return nil, 0, fmt.Errorf("COSE 公钥缺少 kty：%w", err)
+	}
+	if err := cbor.Unmarshal(m[3], &alg); err != nil {
+		return nil, 0, fmt.Errorf("COSE 公钥缺少 alg：%w", err)
+	}
+
+	switch {
+	case kty == 2 && alg == coseAlgES256:
+		var crv int64
+		var x, y []byte
+		if err := cbor.Unmarshal(m[-1], &crv); err != nil || crv != 1 {
+			return nil, 0, fmt.Errorf("不支持的椭圆曲线：%v", crv)
+		}
+		if err := cbor.Unmarshal(m[-2], &x); err != nil || len(x) != 32 {
+			return nil, 0, fmt.Errorf("无效的 ES256 公钥")
+		}
+		if err := cbor.Unmarshal(m[-3], &y); err != nil || len(y) != 32 {
+			return nil, 0, fmt.Errorf("无效的 ES256 公钥")
+		}
+
+		pub := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, 0, fmt.Errorf("ES256 公钥不在曲线上")
+		}
+
+		return pub, alg, nil
+	case kty == 1 && alg == coseAlgEdDSA:
+		var crv int64
+		var x []byte
+		if err := cbor.Unmarshal(m[-1], &crv); err != nil || crv != 6 {
+			return nil, 0, fmt.Errorf("不支持的曲线：%v", crv)
+		}
+		if err := cbor.Unmarshal(m[-2], &x); err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, 0, fmt.Errorf("无效的 Ed25519 公钥")
+		}
+
+		return ed25519.PublicKey(x), alg, nil
+	case kty == 3 && alg == coseAlgRS256:
+		var n, e []byte
+		if err := cbor.Unmarshal(m[-1], &n); err != nil || len(n) < 256 {
+			return nil, 0, fmt.Errorf("无效的 RS256 公钥")
+		}
+		if err := cbor.Unmarshal(m[-2], &e); err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, 0, fmt.Errorf("无效的 RS256 公钥")
+		}
+
+		exp := 0
+		for _, b := range e {
+			exp = exp<<8 | int(b)
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exp}, alg, nil
+	}
+
+	return nil, 0, fmt.Errorf("不支持的 COSE 公钥，kty：%v，alg：%v", kty, alg)
+}
+
+// verifyWebAuthnSignature 校验验证器对 authData || SHA-256(clientDataJSON) 的签名
+func verifyWebAuthnSignature(pub crypto.PublicKey, authData []byte, clientDataJSON []byte, sig []byte) error {
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, authData...), clientDataHash[:]...)
+	digest := sha256.Sum256(signed)
+
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		if ecdsa.VerifyASN1(key, digest[:], sig) {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if ed25519.Verify(key, signed, sig) {
+			return nil
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err == nil {
+			return nil
+		}
+	default:
+		return fmt.Errorf("不支持的公钥类型 %T", pub)
+	}
+
+	return fmt.Errorf("签名无效")
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/google/uuid"
+)
+
+// 注册与登录挑战在 OneTimeTokenRepository 中的用途，登录挑战不属于任何用户
+const (
+	webAuthnRegisterPurpose = "webauthn_register"
+	webAuthnLoginPurpose    = "webauthn_login"
+)
+
+const defaultWebAuthnCredentialName = "通行密钥"
+
+type webAuthnService struct {
+	UserRepository         model.UserRepository
+	CredentialRepository   model.WebAuthnCredentialRepository
+	OneTimeTokenRepository model.OneTimeTokenRepository
+	RPID                   string
+	RPName                 string
+	RPOrigins              []string
+	Timeout                time.Duration
+	AuditLogger            model.AuditLogger
+}
+
+// WASConfig 中 RPID 为依赖方的域名，RPOrigins 为允许发起仪式的页面来源（如 https://malcorp.test），
+// AuditLogger 为 nil 时不记录认证事件
+type WASConfig struct {
+	UserRepository         model.UserRepository
+	CredentialRepository   model.WebAuthnCredentialRepository
+	OneTimeTokenRepository model.OneTimeTokenRepository
+	RPID                   string
+	RPName                 string
+	RPOrigins              []string
+	Timeout                time.Duration
+	AuditLogger            model.AuditLogger
+}
+
+func NewWebAuthnService(c *WASConfig) model.WebAuthnService {
+	return &webAuthnService{
+		UserRepository:         c.UserRepository,
+		CredentialRepository:   c.CredentialRepository,
+		OneTimeTokenRepository: c.OneTimeTokenRepository,
+		RPID:                   c.RPID,
+		RPName:                 c.RPName,
+		RPOrigins:              c.RPOrigins,
+		Timeout:                c.Timeout,
+		AuditLogger:            c.AuditLogger,
+	}
+}
+
+// BeginRegistration 返回注册通行密钥的参数。要求可发现凭据与用户验证，以便不输入邮箱直接登录；
+// 不要求证明（attestation），因此不会校验验证器的型号
+func (s *webAuthnService) BeginRegistration(ctx context.Context, u *model.User) (*model.WebAuthnCreationOptions, error) {
+	credentials, err := s.CredentialRepository.ListByUID(ctx, u.UID)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := s.newChallenge(ctx, webAuthnRegisterPurpose, u.UID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	// 避免在同一验证器上重复注册
+	exclude := make([]model.WebAuthnCredentialDescriptor, len(credentials))
+	for i, c := range credentials {
+		exclude[i] = model.WebAuthnCredentialDescriptor{Type: "public-key", ID: c.ID}
+	}
+
+	params := make([]model.WebAuthnCredentialParameter, len(webAuthnAlgs))
+	for i, alg := range webAuthnAlgs {
+		params[i] = model.WebAuthnCredentialParameter{Type: "public-key", Alg: alg}
+	}
+
+	displayName := u.Name
+	if displayName == "" {
+		displayName = u.Email
+	}
+
+	return &model.WebAuthnCreationOptions{
+		Challenge: challenge,
+		RP: model.WebAuthnRelyingParty{
+			ID:   s.RPID,
+			Name: s.RPName,
+		},
+		User: model.WebAuthnUserEntity{
+			ID:          encodeBase64URL(u.UID[:]),
+			Name:        u.Email,
+			DisplayName: displayName,
+		},
+		PubKeyCredParams:   params,
+		Timeout:            s.Timeout.Milliseconds(),
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: model.WebAuthnAuthenticatorSelection{
+			ResidentKey:      "required",
+			UserVerification: "required",
+		},
+		Attestation: "none",
+	}, nil
+}
+
+// FinishRegistration 按 WebAuthn §7.1 校验注册结果并保存凭据
+func (s *webAuthnService) FinishRegistration(ctx context.Context, u *model.User, name string, attestation *model.WebAuthnAttestation) (*model.WebAuthnCredential, error) {
+	cd, _, err := parseWebAuthnClientData(attestation.ClientDataJSON, "webauthn.create")
+	if err != nil {
+		log.Printf("用户 %v 注册通行密钥失败：%v\n", u.UID, err)
+		return nil, apperrors.NewBadRequest("无效的通行密钥")
+	}
+
+	if err := s.consumeChallenge(ctx, webAuthnRegisterPurpose, cd, u.UID.String()); err != nil {
+		return nil, err
+	}
+
+	attObj, err := parseWebAuthnAttestationObject(attestation.AttestationObject)
+	if err != nil {
+		log.Printf("用户 %v 注册通行密钥失败：%v\n", u.UID, err)
+		return nil, apperrors.NewBadRequest("无效的通行密钥")
+	}
+
+	ad, err := s.verifyAuthData(attObj.AuthData)
+	if err != nil {
+		log.Printf("用户 %v 注册通行密钥失败：%v\n", u.UID, err)
+		return nil, apperrors.NewBadRequest("无效的通行密钥")
+	}
+
+	if ad.CredentialID == nil {
+		return nil, apperrors.NewBadRequest("无效的通行密钥")
+	}
+
+	if id, err := decodeBase64URL(attestation.ID); err != nil || subtle.ConstantTimeCompare(id, ad.CredentialID) != 1 {
+		return nil, apperrors.NewBadRequest("通行密钥 ID 不一致")
+	}
+
+	if _, _, err := parseCOSEKey(ad.PublicKey); err != nil {
+		log.Printf("用户 %v 的通行密钥公钥无效：%v\n", u.UID, err)
+		return nil, apperrors.NewBadRequest("不支持的通行密钥算法")
+	}
+
+	if name == "" {
+		name = defaultWebAuthnCredentialName
+	}
+
+	credential := &model.WebAuthnCredential{
+		ID:        encodeBase64URL(ad.CredentialID),
+		UID:       u.UID,
+		PublicKey: encodeBase64URL(ad.PublicKey),
+		SignCount: int64(ad.SignCount),
+		Name:      name,
+	}
+
+	if err := s.CredentialRepository.Create(ctx, credential); err != nil {
+		return nil, err
+	}
+
+	return credential, nil
+}
+
+// BeginLogin 返回使用通行密钥登录的参数，不指定 allowCredentials，由验证器列出可发现凭据
+func (s *webAuthnService) BeginLogin(ctx context.Context) (*model.WebAuthnRequestOptions, error) {
+	challenge, err := s.newChallenge(ctx, webAuthnLoginPurpose, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.WebAuthnRequestOptions{
+		Challenge:        challenge,
+		RPID:             s.RPID,
+		Timeout:          s.Timeout.Milliseconds(),
+		UserVerification: "required",
+	}, nil
+}
+
+// FinishLogin 按 WebAuthn §7.2 校验签名，成功时返回凭据所属的用户。
+// 签名计数未递增时视为验证器可能已被克隆，拒绝登录
+func (s *webAuthnService) FinishLogin(ctx context.Context, assertion *model.WebAuthnAssertion) (*model.User, error) {
+	invalidErr := apperrors.NewAuthorization("通行密钥验证失败")
+
+	cd, clientDataJSON, err := parseWebAuthnClientData(assertion.ClientDataJSON, "webauthn.get")
+	if err != nil {
+		log.Printf("通行密钥登录失败：%v\n", err)
+		return nil, invalidErr
+	}
+
+	if err := s.consumeChallenge(ctx, webAuthnLoginPurpose, cd, ""); err != nil {
+		return nil, err
+	}
+
+	rawID, err := decodeBase64URL(assertion.ID)
+	if err != nil {
+		return nil, invalidErr
+	}
+
+	credential, err := s.CredentialRepository.FindByID(ctx, encodeBase64URL(rawID))
+	if err != nil {
+		log.Printf("通行密钥 %v 不存在：%v\n", assertion.ID, err)
+		return nil, invalidErr
+	}
+
+	if assertion.UserHandle != "" {
+		userHandle, err := decodeBase64URL(assertion.UserHandle)
+		if err != nil || subtle.ConstantTimeCompare(userHandle, credential.UID[:]) != 1 {
+			log.Printf("通行密钥 %v 的 userHandle 与用户 %v 不一致\n", credential.ID, credential.UID)
+			return nil, invalidErr
+		}
+	}
+
+	authData, err := decodeBase64URL(assertion.AuthenticatorData)
+	if err != nil {
+		return nil, invalidErr
+	}
+
+	ad, err := s.verifyAuthData(authData)
+	if err != nil {
+		log.Printf("通行密钥 %v 登录失败：%v\n", credential.ID, err)
+		return nil, invalidErr
+	}
+
+	publicKey, err := decodeBase64URL(credential.PublicKey)
+	if err != nil {
+		log.Printf("通行密钥 %v 的公钥无法解码：%v\n", credential.ID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	pub, _, err := parseCOSEKey(publicKey)
+	if err != nil {
+		log.Printf("通行密钥 %v 的公钥无法解析：%v\n", credential.ID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	sig, err := decodeBase64URL(assertion.Signature)
+	if err != nil {
+		return nil, invalidErr
+	}
+
+	if err := verifyWebAuthnSignature(pub, authData, clientDataJSON, sig); err != nil {
+		log.Printf("通行密钥 %v 登录失败：%v\n", credential.ID, err)
+		return nil, invalidErr
+	}
+
+	signCount := int64(ad.SignCount)
+	if (signCount != 0 || credential.SignCount != 0) && signCount <= credential.SignCount {
+		log.Printf("通行密钥 %v 的签名计数 %v 未超过 %v，验证器可能已被克隆\n", credential.ID, signCount, credential.SignCount)
+		return nil, invalidErr
+	}
+
+	if err := s.CredentialRepository.UpdateSignCount(ctx, credential.ID, signCount); err != nil {
+		if apperrors.Status(err) == http.StatusNotFound {
+			log.Printf("通行密钥 %v 的签名计数 %v 已被使用\n", credential.ID, signCount)
+			return nil, invalidErr
+		}
+		return nil, err
+	}
+
+	u, err := s.UserRepository.FindByID(ctx, credential.UID)
+	if err != nil {
+		return nil, err
+	}
+
+	logAuthEvent(ctx, s.AuditLogger, model.AuthEventSigninSuccess, u.UID, u.Email, "webauthn")
+
+	return u, nil
+}
+
+func (s *webAuthnService) ListCredentials(ctx context.Context, uid uuid.UUID) ([]*model.WebAuthnCredential, error) {
+	return s.CredentialRepository.ListByUID(ctx, uid)
+}
+
+func (s *webAuthnService) DeleteCredential(ctx context.Context, uid uuid.UUID, id string) error {
+	return s.CredentialRepository.Delete(ctx, uid, id)
+}
+
+// newChallenge 生成随机挑战并保存，有效期与仪式的超时时间相同
+func (s *webAuthnService) newChallenge(ctx context.Context, purpose string, userID string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log.Printf("无法生成 WebAuthn 挑战：%v\n", err)
+		return "", apperrors.NewInternal()
+	}
+
+	challenge := encodeBase64URL(b)
+	if err := s.OneTimeTokenRepository.SetOneTimeToken(ctx, purpose, challenge, userID, s.Timeout); err != nil {
+		return "", err
+	}
+
+	return challenge, nil
+}
+
+// consumeChallenge 校验来源并使用挑战，挑战只能使用一次且必须属于 userID
+func (s *webAuthnService) consumeChallenge(ctx context.Context, purpose string, cd *webAuthnClientData, userID string) error {
+	if !s.allowedOrigin(cd.Origin) {
+		log.Printf("不允许的 WebAuthn 来源：%v\n", cd.Origin)
+		return apperrors.NewAuthorization("通行密钥验证失败")
+	}
+
+	challengeUserID, err := s.OneTimeTokenRepository.ConsumeOneTimeToken(ctx, purpose, cd.Challenge)
+	if err != nil {
+		return err
+	}
+
+	if challengeUserID != userID {
+		log.Printf("WebAuthn 挑战属于用户 %v，而非 %v\n", challengeUserID, userID)
+		return apperrors.NewAuthorization("通行密钥验证失败")
+	}
+
+	return nil
+}
+
+func (s *webAuthnService) allowedOrigin(origin string) bool {
+	for _, o := range s.RPOrigins {
+		if o == origin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// verifyAuthData 校验 RP ID 的哈希，以及用户在场与用户验证标志
+func (s *webAuthnService) verifyAuthData(data []byte) (*webAuthnAuthData, error) {
+	ad, err := parseWebAuthnAuthData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	rpIDHash := sha256.Sum256([]byte(s.RPID))
+	if subtle.ConstantTimeCompare(ad.RPIDHash, rpIDHash[:]) != 1 {
+		return nil, fmt.Errorf("RP ID 的哈希不一致")
+	}
+
+	if ad.Flags&authDataFlagUP == 0 || ad.Flags&authDataFlagUV == 0 {
+		return nil, fmt.Errorf("验证器未验证用户")
+	}
+
+	return ad, nil
+}
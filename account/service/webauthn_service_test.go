@@ -0,0 +1,384 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/FuZhouJohn/memrizr/account/model"
+	"github.com/FuZhouJohn/memrizr/account/model/apperrors"
+	"github.com/FuZhouJohn/memrizr/account/model/mocks"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	testRPID   = "malcorp.test"
+	testOrigin = "https://malcorp.test"
+)
+
+// softAuthenticator 为测试用的软件验证器，按 WebAuthn 规范生成注册与登录的响应
+type softAuthenticator struct {
+	signer       crypto.Signer
+	credentialID []byte
+	signCount    uint32
+	rpID         string
+	origin       string
+	flags        byte
+}
+
+func newSoftAuthenticator(t *testing.T, signer crypto.Signer) *softAuthenticator {
+	credentialID := make([]byte, 16)
+	_, err := rand.Read(credentialID)
+	assert.NoError(t, err)
+
+	return &softAuthenticator{
+		signer:       signer,
+		credentialID: credentialID,
+		rpID:         testRPID,
+		origin:       testOrigin,
+		flags:        authDataFlagUP | authDataFlagUV,
+	}
+}
+
+func (a *softAuthenticator) coseKey(t *testing.T) []byte {
+	var key map[int]interface{}
+
+	switch pub := a.signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		x := make([]byte, 32)
+		y := make([]byte, 32)
+		pub.X.FillBytes(x)
+		pub.Y.FillBytes(y)
+		key = map[int]interface{}{1: 2, 3: coseAlgES256, -1: 1, -2: x, -3: y}
+	case ed25519.PublicKey:
+		key = map[int]interface{}{1: 1, 3: coseAlgEdDSA, -1: 6, -2: []byte(pub)}
+	}
+
+	data, err := cbor.Marshal(key)
+	assert.NoError(t, err)
+
+	return data
+}
+
+func (a *softAuthenticator) authData(t *testing.T, flags byte, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = append(data, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[33:], a.signCount)
+
+	if attested {
+		data = append(data, make([]byte, 16)...)
+		data = append(data, byte(len(a.credentialID)>>8), byte(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, a.coseKey(t)...)
+	}
+
+	return data
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, typ string, challenge string) []byte {
+	data, err := json.Marshal(map[string]interface{}{
+		"type":        typ,
+		"challenge":   challenge,
+		"origin":      a.origin,
+		"crossOrigin": false,
+	})
+	assert.NoError(t, err)
+
+	return data
+}
+
+// create 模拟 navigator.credentials.create()
+func (a *softAuthenticator) create(t *testing.T, challenge string) *model.WebAuthnAttestation {
+	attObj, err := cbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authData(t, a.flags|authDataFlagAT, true),
+	})
+	assert.NoError(t, err)
+
+	return &model.WebAuthnAttestation{
+		ID:                encodeBase64URL(a.credentialID),
+		ClientDataJSON:    encodeBase64URL(a.clientData(t, "webauthn.create", challenge)),
+		AttestationObject: encodeBase64URL(attObj),
+	}
+}
+
+// get 模拟 navigator.credentials.get()，每次调用签名计数加一
+func (a *softAuthenticator) get(t *testing.T, challenge string, userHandle []byte) *model.WebAuthnAssertion {
+	a.signCount++
+
+	authData := a.authData(t, a.flags, false)
+	clientData := a.clientData(t, "webauthn.get", challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	signed := append(append([]byte{}, authData...), clientDataHash[:]...)
+
+	var sig []byte
+	var err error
+	if _, ok := a.signer.(ed25519.PrivateKey); ok {
+		sig, err = a.signer.Sign(rand.Reader, signed, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(signed)
+		sig, err = a.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	assert.NoError(t, err)
+
+	return &model.WebAuthnAssertion{
+		ID:                encodeBase64URL(a.credentialID),
+		ClientDataJSON:    encodeBase64URL(clientData),
+		AuthenticatorData: encodeBase64URL(authData),
+		Signature:         encodeBase64URL(sig),
+		UserHandle:        encodeBase64URL(userHandle),
+	}
+}
+
+func TestWebAuthnService(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	mockUser := &model.User{
+		UID:   uid,
+		Email: "bob@bob.com",
+		Name:  "Bob",
+	}
+
+	newService := func() (model.WebAuthnService, *mocks.MockUserRepository, *mocks.MockWebAuthnCredentialRepository, *mocks.MockOneTimeTokenRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockCredentialRepository := new(mocks.MockWebAuthnCredentialRepository)
+		mockOneTimeTokenRepository := new(mocks.MockOneTimeTokenRepository)
+
+		ws := NewWebAuthnService(&WASConfig{
+			UserRepository:         mockUserRepository,
+			CredentialRepository:   mockCredentialRepository,
+			OneTimeTokenRepository: mockOneTimeTokenRepository,
+			RPID:                   testRPID,
+			RPName:                 "Memrizr",
+			RPOrigins:              []string{testOrigin},
+			Timeout:                5 * time.Minute,
+		})
+
+		return ws, mockUserRepository, mockCredentialRepository, mockOneTimeTokenRepository
+	}
+
+	// register 使用软件验证器完成注册，返回保存的凭据
+	register := func(t *testing.T, authenticator *softAuthenticator) *model.WebAuthnCredential {
+		ws, _, mockCredentialRepository, mockOneTimeTokenRepository := newService()
+
+		var challenge string
+		mockCredentialRepository.
+			On("ListByUID", mock.AnythingOfType("*context.emptyCtx"), uid).
+			Return([]*model.WebAuthnCredential{{ID: "existing"}}, nil)
+		mockOneTimeTokenRepository.
+			On("SetOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "webauthn_register", mock.AnythingOfType("string"), uid.String(), 5*time.Minute).
+			Run(func(args mock.Arguments) {
+				challenge = args.Get(2).(string)
+			}).
+			Return(nil)
+
+		options, err := ws.BeginRegistration(context.TODO(), mockUser)
+		assert.NoError(t, err)
+		assert.Equal(t, challenge, options.Challenge)
+		assert.Equal(t, testRPID, options.RP.ID)
+		assert.Equal(t, encodeBase64URL(uid[:]), options.User.ID)
+		assert.Equal(t, "existing", options.ExcludeCredentials[0].ID)
+		assert.Equal(t, int64(5*60*1000), options.Timeout)
+
+		mockOneTimeTokenRepository.
+			On("ConsumeOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "webauthn_register", challenge).
+			Return(uid.String(), nil)
+		mockCredentialRepository.
+			On("Create", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.WebAuthnCredential")).
+			Return(nil)
+
+		credential, err := ws.FinishRegistration(context.TODO(), mockUser, "", authenticator.create(t, challenge))
+		assert.NoError(t, err)
+
+		mockCredentialRepository.AssertExpectations(t)
+		return credential
+	}
+
+	// beginLogin 开始登录并返回挑战
+	beginLogin := func(t *testing.T, ws model.WebAuthnService, mockOneTimeTokenRepository *mocks.MockOneTimeTokenRepository) string {
+		var challenge string
+		mockOneTimeTokenRepository.
+			On("SetOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "webauthn_login", mock.AnythingOfType("string"), "", 5*time.Minute).
+			Run(func(args mock.Arguments) {
+				challenge = args.Get(2).(string)
+			}).
+			Return(nil)
+
+		options, err := ws.BeginLogin(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, challenge, options.Challenge)
+		assert.Equal(t, "required", options.UserVerification)
+
+		mockOneTimeTokenRepository.
+			On("ConsumeOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "webauthn_login", challenge).
+			Return("", nil)
+
+		return challenge
+	}
+
+	for name, signer := range map[string]func() crypto.Signer{
+		"ES256": func() crypto.Signer {
+			key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			return key
+		},
+		"EdDSA": func() crypto.Signer {
+			_, key, _ := ed25519.GenerateKey(rand.Reader)
+			return key
+		},
+	} {
+		signer := signer
+
+		t.Run(name+" 注册并登录", func(t *testing.T) {
+			authenticator := newSoftAuthenticator(t, signer())
+
+			credential := register(t, authenticator)
+			assert.Equal(t, encodeBase64URL(authenticator.credentialID), credential.ID)
+			assert.Equal(t, uid, credential.UID)
+			assert.Equal(t, "通行密钥", credential.Name)
+
+			ws, mockUserRepository, mockCredentialRepository, mockOneTimeTokenRepository := newService()
+			challenge := beginLogin(t, ws, mockOneTimeTokenRepository)
+
+			mockCredentialRepository.
+				On("FindByID", mock.AnythingOfType("*context.emptyCtx"), credential.ID).
+				Return(credential, nil)
+			mockCredentialRepository.
+				On("UpdateSignCount", mock.AnythingOfType("*context.emptyCtx"), credential.ID, int64(1)).
+				Return(nil)
+			mockUserRepository.
+				On("FindByID", mock.AnythingOfType("*context.emptyCtx"), uid).
+				Return(mockUser, nil)
+
+			u, err := ws.FinishLogin(context.TODO(), authenticator.get(t, challenge, uid[:]))
+
+			assert.NoError(t, err)
+			assert.Equal(t, mockUser, u)
+			mockCredentialRepository.AssertExpectations(t)
+		})
+	}
+
+	t.Run("签名计数未递增", func(t *testing.T) {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		authenticator := newSoftAuthenticator(t, key)
+		credential := register(t, authenticator)
+
+		// 服务端记录的计数已超过验证器，可能存在克隆的验证器
+		credential.SignCount = 5
+
+		ws, mockUserRepository, mockCredentialRepository, mockOneTimeTokenRepository := newService()
+		challenge := beginLogin(t, ws, mockOneTimeTokenRepository)
+
+		mockCredentialRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), credential.ID).
+			Return(credential, nil)
+
+		_, err := ws.FinishLogin(context.TODO(), authenticator.get(t, challenge, nil))
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockCredentialRepository.AssertNotCalled(t, "UpdateSignCount", mock.Anything, mock.Anything, mock.Anything)
+		mockUserRepository.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("签名无效", func(t *testing.T) {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		authenticator := newSoftAuthenticator(t, key)
+		credential := register(t, authenticator)
+
+		ws, _, mockCredentialRepository, mockOneTimeTokenRepository := newService()
+		challenge := beginLogin(t, ws, mockOneTimeTokenRepository)
+
+		mockCredentialRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), credential.ID).
+			Return(credential, nil)
+
+		// 其他验证器使用相同的凭据 ID 签名
+		otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		other := newSoftAuthenticator(t, otherKey)
+		other.credentialID = authenticator.credentialID
+
+		_, err := ws.FinishLogin(context.TODO(), other.get(t, challenge, nil))
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockCredentialRepository.AssertNotCalled(t, "UpdateSignCount", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("userHandle 与凭据不一致", func(t *testing.T) {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		authenticator := newSoftAuthenticator(t, key)
+		credential := register(t, authenticator)
+
+		ws, _, mockCredentialRepository, mockOneTimeTokenRepository := newService()
+		challenge := beginLogin(t, ws, mockOneTimeTokenRepository)
+
+		mockCredentialRepository.
+			On("FindByID", mock.AnythingOfType("*context.emptyCtx"), credential.ID).
+			Return(credential, nil)
+
+		otherUID, _ := uuid.NewRandom()
+		_, err := ws.FinishLogin(context.TODO(), authenticator.get(t, challenge, otherUID[:]))
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+	})
+
+	t.Run("来源不允许", func(t *testing.T) {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		authenticator := newSoftAuthenticator(t, key)
+		authenticator.origin = "https://evil.test"
+
+		ws, _, mockCredentialRepository, mockOneTimeTokenRepository := newService()
+
+		_, err := ws.FinishRegistration(context.TODO(), mockUser, "", authenticator.create(t, "aChallenge"))
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockOneTimeTokenRepository.AssertNotCalled(t, "ConsumeOneTimeToken", mock.Anything, mock.Anything, mock.Anything)
+		mockCredentialRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("验证器未验证用户", func(t *testing.T) {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		authenticator := newSoftAuthenticator(t, key)
+		authenticator.flags = authDataFlagUP
+
+		ws, _, mockCredentialRepository, mockOneTimeTokenRepository := newService()
+
+		mockOneTimeTokenRepository.
+			On("ConsumeOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "webauthn_register", "aChallenge").
+			Return(uid.String(), nil)
+
+		_, err := ws.FinishRegistration(context.TODO(), mockUser, "", authenticator.create(t, "aChallenge"))
+
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockCredentialRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("挑战属于其他用户", func(t *testing.T) {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		authenticator := newSoftAuthenticator(t, key)
+
+		ws, _, mockCredentialRepository, mockOneTimeTokenRepository := newService()
+
+		otherUID, _ := uuid.NewRandom()
+		mockOneTimeTokenRepository.
+			On("ConsumeOneTimeToken", mock.AnythingOfType("*context.emptyCtx"), "webauthn_register", "aChallenge").
+			Return(otherUID.String(), nil)
+
+		_, err := ws.FinishRegistration(context.TODO(), mockUser, "", authenticator.create(t, "aChallenge"))
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockCredentialRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}